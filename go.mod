@@ -13,6 +13,7 @@ require (
 	github.com/swaggo/swag v1.8.1
 	github.com/vielendanke/go-db-lb v0.0.0-20210909065144-1b492d5aafb1
 	github.com/xuri/excelize/v2 v2.5.0
+	golang.org/x/text v0.3.7
 )
 
 require (
@@ -58,7 +59,6 @@ require (
 	golang.org/x/crypto v0.0.0-20220131195533-30dcbda58838 // indirect
 	golang.org/x/net v0.0.0-20220407224826-aac1ed45d8e3 // indirect
 	golang.org/x/sys v0.0.0-20220406163625-3f8b81556e12 // indirect
-	golang.org/x/text v0.3.7 // indirect
 	golang.org/x/tools v0.1.10 // indirect
 	gopkg.in/ini.v1 v1.57.0 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
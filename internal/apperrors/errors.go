@@ -0,0 +1,170 @@
+// Package apperrors holds the errors the API reports to its clients. Every
+// error has a stable code the frontend can switch on and a message in
+// Russian and English.
+package apperrors
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+type Code string
+
+const (
+	BadRequest            Code = "BAD_REQUEST"
+	Unauthorized          Code = "UNAUTHORIZED"
+	Forbidden             Code = "FORBIDDEN"
+	NotFound              Code = "NOT_FOUND"
+	Conflict              Code = "CONFLICT"
+	Timeout               Code = "TIMEOUT"
+	ValidationFailed      Code = "VALIDATION_FAILED"
+	FileTooLarge          Code = "FILE_TOO_LARGE"
+	FileUnreadable        Code = "FILE_UNREADABLE"
+	LimitExceeded         Code = "LIMIT_EXCEEDED"
+	TemplateNotRecognized Code = "TEMPLATE_NOT_RECOGNIZED"
+	SheetNotFound         Code = "SHEET_NOT_FOUND"
+	RowInvalid            Code = "ROW_INVALID"
+	StorageUnavailable    Code = "STORAGE_UNAVAILABLE"
+	Internal              Code = "INTERNAL"
+)
+
+type definition struct {
+	status int
+	ru, en string
+}
+
+var definitions = map[Code]definition{
+	BadRequest:            {http.StatusBadRequest, "Некорректный запрос", "Bad request"},
+	Unauthorized:          {http.StatusUnauthorized, "Требуется авторизация", "Authentication required"},
+	Forbidden:             {http.StatusForbidden, "Недостаточно прав", "Access denied"},
+	NotFound:              {http.StatusNotFound, "Не найдено", "Not found"},
+	Conflict:              {http.StatusConflict, "Конфликт с текущим состоянием", "Conflict with the current state"},
+	Timeout:               {http.StatusRequestTimeout, "Превышено время ожидания", "Request timed out"},
+	ValidationFailed:      {http.StatusUnprocessableEntity, "Данные не прошли проверку", "Validation failed"},
+	FileTooLarge:          {http.StatusRequestEntityTooLarge, "Файл слишком большой", "File is too large"},
+	FileUnreadable:        {http.StatusBadRequest, "Не удалось прочитать файл", "File could not be read"},
+	LimitExceeded:         {http.StatusUnprocessableEntity, "Файл превышает допустимые ограничения", "File exceeds upload limits"},
+	TemplateNotRecognized: {http.StatusBadRequest, "Неправильный шаблон документа, обратитесь к нам", "Document template is not recognized, please contact us"},
+	SheetNotFound:         {http.StatusBadRequest, "В файле нет нужного листа", "Required sheet is missing"},
+	RowInvalid:            {http.StatusUnprocessableEntity, "Строка файла содержит ошибки", "File row is invalid"},
+	StorageUnavailable:    {http.StatusServiceUnavailable, "Файловое хранилище недоступно", "File storage is unavailable"},
+	Internal:              {http.StatusInternalServerError, "Внутренняя ошибка", "Internal error"},
+}
+
+// statusCodes map the HTTP errors the code base returns to their codes.
+var statusCodes = map[int]Code{
+	http.StatusBadRequest:            BadRequest,
+	http.StatusUnauthorized:          Unauthorized,
+	http.StatusForbidden:             Forbidden,
+	http.StatusNotFound:              NotFound,
+	http.StatusMethodNotAllowed:      NotFound,
+	http.StatusConflict:              Conflict,
+	http.StatusRequestTimeout:        Timeout,
+	http.StatusRequestEntityTooLarge: FileTooLarge,
+	http.StatusUnprocessableEntity:   ValidationFailed,
+	http.StatusServiceUnavailable:    StorageUnavailable,
+}
+
+// Error is an API error. Details are shown to the client, the cause is only
+// logged.
+type Error struct {
+	Code    Code
+	Status  int
+	Details interface{}
+	Err     error
+}
+
+// New returns the error with the code, details may be nil.
+func New(code Code, details interface{}) *Error {
+	return &Error{Code: code, Status: status(code), Details: details}
+}
+
+// Wrap returns the error with the code caused by err.
+func Wrap(code Code, err error) *Error {
+	return &Error{Code: code, Status: status(code), Err: err}
+}
+
+func (e *Error) Error() string {
+	switch {
+	case e.Err != nil:
+		return fmt.Sprintf("%s: %v", e.Code, e.Err)
+	case e.Details != nil:
+		return fmt.Sprintf("%s: %v", e.Code, e.Details)
+	}
+	return string(e.Code)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Message returns the message of the error in the language, "ru" or "en".
+func (e *Error) Message(lang string) string {
+	return Message(e.Code, lang)
+}
+
+// Message returns the message of the code in the language, Russian unless
+// lang is "en".
+func Message(code Code, lang string) string {
+	d, ok := definitions[code]
+	if !ok {
+		d = definitions[Internal]
+	}
+	if lang == "en" {
+		return d.en
+	}
+	return d.ru
+}
+
+// From turns any error into an API error. Echo errors keep their status
+// and a string message becomes the details, except for server errors whose
+// text may hold internals.
+func From(err error) *Error {
+	switch e := err.(type) {
+	case *Error:
+		return e
+	case *echo.HTTPError:
+		code, ok := statusCodes[e.Code]
+		if !ok {
+			code = Internal
+			if e.Code < http.StatusInternalServerError {
+				code = BadRequest
+			}
+		}
+		res := &Error{Code: code, Status: e.Code, Err: e}
+		if e.Code < http.StatusInternalServerError {
+			switch msg := e.Message.(type) {
+			case string:
+				res.Details = msg
+			case error:
+				res.Details = msg.Error()
+			}
+		}
+		return res
+	}
+	return Wrap(Internal, err)
+}
+
+// Lang picks the message language from an Accept-Language header.
+func Lang(acceptLanguage string) string {
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag := strings.ToLower(strings.TrimSpace(strings.SplitN(part, ";", 2)[0]))
+		switch {
+		case strings.HasPrefix(tag, "ru"):
+			return "ru"
+		case strings.HasPrefix(tag, "en"):
+			return "en"
+		}
+	}
+	return "ru"
+}
+
+func status(code Code) int {
+	if d, ok := definitions[code]; ok {
+		return d.status
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,19 @@
+package auth
+
+import (
+	"context"
+	"excel-service/internal/models"
+)
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, p *models.Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the caller put into the context by the auth
+// middleware, nil for unauthenticated calls.
+func PrincipalFromContext(ctx context.Context) *models.Principal {
+	p, _ := ctx.Value(principalKey{}).(*models.Principal)
+	return p
+}
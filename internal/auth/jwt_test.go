@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+func signToken(alg, payload string, secret []byte) string {
+	unsigned := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"`+alg+`","typ":"JWT"}`)) +
+		"." + base64.RawURLEncoding.EncodeToString([]byte(payload))
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestParseHS256(t *testing.T) {
+	secret := []byte("secret")
+	now := time.Unix(1700000000, 0)
+	valid := `{"id":"user-1","role":"role-1","exp":1700000600}`
+
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{"valid", signToken("HS256", valid, secret), nil},
+		{"bad signature", signToken("HS256", valid, []byte("other")), ErrSignature},
+		{"alg none", signToken("none", valid, secret), ErrAlgorithm},
+		{"alg HS512", signToken("HS512", valid, secret), ErrAlgorithm},
+		{"expired", signToken("HS256", `{"id":"user-1","exp":1699999999}`, secret), ErrExpired},
+		{"expires now", signToken("HS256", `{"id":"user-1","exp":1700000000}`, secret), ErrExpired},
+		{"no exp", signToken("HS256", `{"id":"user-1"}`, secret), ErrExpired},
+		{"not yet valid", signToken("HS256", `{"id":"user-1","exp":1700000600,"nbf":1700000300}`, secret), ErrExpired},
+		{"no user id", signToken("HS256", `{"exp":1700000600}`, secret), ErrMalformedToken},
+		{"two segments", "a.b", ErrMalformedToken},
+		{"bad header", "!!." + base64.RawURLEncoding.EncodeToString([]byte(valid)) + ".sig", ErrMalformedToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := ParseHS256(tt.token, secret, now)
+			if err != tt.want {
+				t.Fatalf("ParseHS256() error = %v, want %v", err, tt.want)
+			}
+			if tt.want == nil && (claims.Id != "user-1" || claims.Role != "role-1") {
+				t.Errorf("ParseHS256() claims = %+v", claims)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrMissingSignature = errors.New("missing signature or timestamp")
+	ErrStaleTimestamp   = errors.New("timestamp is outside the allowed window")
+	ErrReplayed         = errors.New("signature was already used")
+)
+
+// Sign returns the hook signature of the body: hex HMAC-SHA256 of
+// "<timestamp>.<body>" prefixed with "sha256=".
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature checks the signature of the body and that the unix
+// timestamp it covers is not further than tolerance from now.
+func VerifySignature(secret []byte, timestamp, signature string, body []byte, now time.Time, tolerance time.Duration) error {
+	if timestamp == "" || signature == "" {
+		return ErrMissingSignature
+	}
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrStaleTimestamp
+	}
+	if skew := now.Sub(time.Unix(ts, 0)); skew > tolerance || skew < -tolerance {
+		return ErrStaleTimestamp
+	}
+	if !hmac.Equal([]byte(strings.ToLower(signature)), []byte(Sign(secret, timestamp, body))) {
+		return ErrSignature
+	}
+	return nil
+}
+
+// ReplayGuard remembers signatures for as long as their timestamps are
+// accepted, so a captured request can't be sent again inside the window.
+type ReplayGuard struct {
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+func NewReplayGuard() *ReplayGuard {
+	return &ReplayGuard{seen: map[string]time.Time{}}
+}
+
+// Check records the signature and fails if it was recorded before and has
+// not expired yet.
+func (g *ReplayGuard) Check(signature string, now time.Time, ttl time.Duration) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for sig, expires := range g.seen {
+		if now.After(expires) {
+			delete(g.seen, sig)
+		}
+	}
+	if _, ok := g.seen[signature]; ok {
+		return ErrReplayed
+	}
+	g.seen[signature] = now.Add(ttl)
+	return nil
+}
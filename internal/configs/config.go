@@ -1,9 +1,13 @@
 package configs
 
 type Configs struct {
-	Port string     `json:"port"`
-	DB   *DBCfg     `json:"db"`
-	Aws  *AwsConfig `json:"aws"`
+	Port   string        `json:"port"`
+	DB     *DBCfg        `json:"db"`
+	Aws    *AwsConfig    `json:"aws"`
+	Cbr    *CbrConfig    `json:"cbr"`
+	Auth   *AuthConfig   `json:"auth"`
+	Limits *LimitsConfig `json:"limits"`
+	Export *ExportConfig `json:"export"`
 }
 
 type DBCfg struct {
@@ -22,9 +26,46 @@ type AwsConfig struct {
 	Bucket    string
 }
 
+type CbrConfig struct {
+	RatesUrl string `json:"rates_url"`
+}
+
+// AuthConfig holds the Directus token secret, static tokens of machine
+// clients mapped to client names and the roles allowed to change reference
+// data besides Directus admins. HookSecret signs Directus hook calls.
+type AuthConfig struct {
+	JwtSecret     string            `json:"jwt_secret"`
+	ServiceTokens map[string]string `json:"service_tokens"`
+	AdminRoles    []string          `json:"admin_roles"`
+	HookSecret    string            `json:"hook_secret"`
+}
+
+// LimitsConfig bounds what an uploaded spreadsheet may contain. Sizes are
+// in bytes, MaxUnzipRatio is unpacked size to file size of xlsx and ods
+// archives.
+type LimitsConfig struct {
+	MaxFileSize   int64   `json:"max_file_size"`
+	MaxUnzipSize  int64   `json:"max_unzip_size"`
+	MaxUnzipRatio float64 `json:"max_unzip_ratio"`
+	MaxSheets     int     `json:"max_sheets"`
+	MaxRows       int     `json:"max_rows"`
+	MaxColumns    int     `json:"max_columns"`
+	MaxCellLength int     `json:"max_cell_length"`
+}
+
+// ExportConfig sets how generated workbooks and CSV files write values that
+// would run as formulas, "quote" or "string".
+type ExportConfig struct {
+	FormulaPolicy string `json:"formula_policy"`
+}
+
 func NewConfig() *Configs {
 	return &Configs{
-		DB:  &DBCfg{},
-		Aws: &AwsConfig{},
+		DB:     &DBCfg{},
+		Aws:    &AwsConfig{},
+		Cbr:    &CbrConfig{},
+		Auth:   &AuthConfig{},
+		Limits: &LimitsConfig{},
+		Export: &ExportConfig{},
 	}
 }
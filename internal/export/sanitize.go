@@ -0,0 +1,53 @@
+package export
+
+import (
+	"strconv"
+	"strings"
+)
+
+// FormulaPolicy says how cells that a spreadsheet program would run as a
+// formula are written.
+type FormulaPolicy string
+
+const (
+	// PolicyQuote prefixes the value with an apostrophe, so it is shown as
+	// text and the original is still readable.
+	PolicyQuote FormulaPolicy = "quote"
+	// PolicyString keeps the value as is and writes the cell with the
+	// string type. CSV has no cell types, so CSV output is quoted anyway.
+	PolicyString FormulaPolicy = "string"
+)
+
+// formulaPrefixes start a formula in Excel, LibreOffice and Google Sheets.
+// Tab and carriage return are stripped by some of them before parsing.
+const formulaPrefixes = "=+-@\t\r"
+
+// ParsePolicy returns the policy by name, unknown names give PolicyQuote.
+func ParsePolicy(name string) FormulaPolicy {
+	if FormulaPolicy(strings.ToLower(strings.TrimSpace(name))) == PolicyString {
+		return PolicyString
+	}
+	return PolicyQuote
+}
+
+// IsFormula reports whether the value would be run as a formula when the
+// file is opened. Plain numbers such as "-5" or "+7" are data.
+func IsFormula(value string) bool {
+	trimmed := strings.TrimLeft(value, " ")
+	if trimmed == "" || !strings.ContainsRune(formulaPrefixes, rune(trimmed[0])) {
+		return false
+	}
+	if _, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+		return false
+	}
+	return true
+}
+
+// Quote makes a formula-like value inert by prefixing it with an
+// apostrophe, other values are returned unchanged.
+func Quote(value string) string {
+	if IsFormula(value) {
+		return "'" + value
+	}
+	return value
+}
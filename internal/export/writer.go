@@ -0,0 +1,63 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// SetCell writes a supplier controlled string into a generated workbook.
+// Every string cell of an export or annotated workbook goes through it.
+func SetCell(f *excelize.File, sheet, cell, value string, policy FormulaPolicy) error {
+	if policy == PolicyString {
+		return f.SetCellStr(sheet, cell, value)
+	}
+	return f.SetCellStr(sheet, cell, Quote(value))
+}
+
+// SetRow writes the values into the row starting at the cell, one column
+// per value.
+func SetRow(f *excelize.File, sheet, cell string, values []string, policy FormulaPolicy) error {
+	col, row, err := excelize.CellNameToCoordinates(cell)
+	if err != nil {
+		return err
+	}
+	for i, value := range values {
+		name, nameErr := excelize.CoordinatesToCellName(col+i, row)
+		if nameErr != nil {
+			return nameErr
+		}
+		if setErr := SetCell(f, sheet, name, value, policy); setErr != nil {
+			return setErr
+		}
+	}
+	return nil
+}
+
+// CSVWriter is csv.Writer that quotes formula-like fields.
+type CSVWriter struct {
+	*csv.Writer
+}
+
+func NewCSVWriter(w io.Writer) *CSVWriter {
+	return &CSVWriter{Writer: csv.NewWriter(w)}
+}
+
+func (w *CSVWriter) Write(record []string) error {
+	safe := make([]string, len(record))
+	for i, field := range record {
+		safe[i] = Quote(field)
+	}
+	return w.Writer.Write(safe)
+}
+
+func (w *CSVWriter) WriteAll(records [][]string) error {
+	for _, record := range records {
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
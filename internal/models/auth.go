@@ -0,0 +1,11 @@
+package models
+
+// Principal is the authenticated caller. Directus users act on their own
+// company, Service is set for machine clients with a static token.
+type Principal struct {
+	UserId    string `json:"user_id,omitempty"`
+	CompanyId string `json:"company_id,omitempty"`
+	Role      string `json:"role,omitempty"`
+	Admin     bool   `json:"admin"`
+	Service   string `json:"service,omitempty"`
+}
@@ -8,6 +8,9 @@ type GetExcelFromAwsByFileIdReq struct {
 type DirectusModel struct {
 	Key        string `json:"key"`
 	Collection string `json:"collection"`
+	SheetSelector
+	// Accounting is what the caller claims about itself and is never used
+	// for access decisions, company and user are read from the upload.
 	Accounting struct {
 		User    string `json:"user"`
 		Company string `json:"company"`
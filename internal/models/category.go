@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// CategorySample is already categorized nomenclature the category model is
+// trained on.
+type CategorySample struct {
+	NomenclatureId string
+	Name           string
+	Okpd2          string
+	Manufacturer   string
+	CategoryId     string
+	Category       string
+}
+
+type CategorySuggestion struct {
+	Id             string    `json:"id"`
+	NomenclatureId string    `json:"nomenclature_id"`
+	Name           string    `json:"name"`
+	CategoryId     string    `json:"category_id"`
+	Category       string    `json:"category"`
+	Confidence     float64   `json:"confidence"`
+	Status         string    `json:"status"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type CategorySuggestionRun struct {
+	Checked   int `json:"checked"`
+	Suggested int `json:"suggested"`
+}
+
+type CategoryReviewReq struct {
+	Accept []string `json:"accept"`
+	Reject []string `json:"reject"`
+}
+
+type CategoryReview struct {
+	Accepted int64 `json:"accepted"`
+	Rejected int64 `json:"rejected"`
+}
+
+// CategoryNode is a category with its children. ParentId is empty for the
+// roots of the tree.
+type CategoryNode struct {
+	Id       string          `json:"id"`
+	Name     string          `json:"name"`
+	Code     string          `json:"code,omitempty"`
+	ParentId string          `json:"parent_id,omitempty"`
+	Children []*CategoryNode `json:"children,omitempty"`
+}
+
+type CategoryTreeImport struct {
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+}
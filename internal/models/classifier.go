@@ -0,0 +1,46 @@
+package models
+
+// ClassifierCode is an entry of OKPD2 or TN VED EAEU. Sections have Level 0,
+// other entries have the number of digits in the code as Level.
+type ClassifierCode struct {
+	Code       string `json:"code"`
+	Name       string `json:"name"`
+	ParentCode string `json:"parent_code,omitempty"`
+	Level      int    `json:"level"`
+}
+
+type ClassifierImport struct {
+	Kind    string `json:"kind"`
+	Codes   int    `json:"codes"`
+	Skipped int    `json:"skipped"`
+}
+
+// ClassifierLookup holds the code with its parents, Path goes from the
+// section down to the code itself.
+type ClassifierLookup struct {
+	Kind string            `json:"kind"`
+	Code string            `json:"code"`
+	Name string            `json:"name"`
+	Path []*ClassifierCode `json:"path"`
+}
+
+// UploadRowError is a problem found in one row of an uploaded file. The row
+// is still imported, the field is left empty or saved as written. Row is
+// numbered the way Excel shows it.
+type UploadRowError struct {
+	UploadId string `json:"upload_id,omitempty"`
+	FileName string `json:"file_name"`
+	Sheet    string `json:"sheet,omitempty"`
+	Row      int    `json:"row"`
+	Field    string `json:"field"`
+	Value    string `json:"value"`
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+}
+
+// ExportFile is a generated file the service returns for download.
+type ExportFile struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
@@ -0,0 +1,79 @@
+package models
+
+import "time"
+
+// ColumnDraft is what the service makes of an upload sheet before the user
+// maps its columns. Template is the built-in template the sheet fits, if
+// any, ColumnTemplateId the saved template of the company the suggestions
+// came from. HeaderRow and DataRow are row numbers.
+type ColumnDraft struct {
+	UploadId         string          `json:"upload_id"`
+	FileName         string          `json:"file_name"`
+	Sheet            string          `json:"sheet"`
+	Sheets           []string        `json:"sheets"`
+	Template         string          `json:"template,omitempty"`
+	ColumnTemplateId string          `json:"column_template_id,omitempty"`
+	HeaderRow        int             `json:"header_row"`
+	DataRow          int             `json:"data_row"`
+	Columns          []*FileColumn   `json:"columns"`
+	Fields           []*MappingField `json:"fields"`
+}
+
+// FileColumn is a sheet column with the first values under the header and
+// the target field suggested for it. Index counts from 0, Confidence is 1
+// for a field taken from a saved template.
+type FileColumn struct {
+	Index      int      `json:"index"`
+	Name       string   `json:"name"`
+	Samples    []string `json:"samples"`
+	Field      string   `json:"field,omitempty"`
+	Confidence float64  `json:"confidence,omitempty"`
+}
+
+// MappingField is a target field a column can be mapped to.
+type MappingField struct {
+	Key      string `json:"key"`
+	Title    string `json:"title"`
+	Required bool   `json:"required"`
+}
+
+// ColumnMappingReq is the mapping the user confirmed, Columns maps target
+// fields to 0-based column indexes. FileName is the file of the draft, the
+// first file with the sheet when empty. With SaveAs the mapping is kept as a
+// company template under that name.
+type ColumnMappingReq struct {
+	FileName string         `json:"file_name,omitempty"`
+	Sheet    string         `json:"sheet"`
+	DataRow  int            `json:"data_row"`
+	Columns  map[string]int `json:"columns"`
+	SaveAs   string         `json:"save_as,omitempty"`
+}
+
+// ColumnMapping is the mapping an upload is imported with, it applies to
+// the sheet of the file it was checked against.
+type ColumnMapping struct {
+	UploadId   string         `json:"upload_id"`
+	FileName   string         `json:"file_name"`
+	Sheet      string         `json:"sheet"`
+	DataRow    int            `json:"data_row"`
+	Columns    map[string]int `json:"columns"`
+	TemplateId string         `json:"template_id,omitempty"`
+}
+
+// ColumnTemplate is a mapping a company saved for reuse, Columns maps
+// target fields to header names.
+type ColumnTemplate struct {
+	Id        string            `json:"id"`
+	CompanyId string            `json:"company_id"`
+	Name      string            `json:"name"`
+	Columns   map[string]string `json:"columns"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// ColumnMappingRun is the mapping saved for the upload and the outcome of
+// the import with it.
+type ColumnMappingRun struct {
+	Mapping  *ColumnMapping  `json:"mapping"`
+	Template *ColumnTemplate `json:"template,omitempty"`
+	Result   *ResponseMsg    `json:"result"`
+}
@@ -0,0 +1,25 @@
+package models
+
+// Country is an OKSM entry, Code is the three digit OKSM code.
+type Country struct {
+	Code    string   `json:"code"`
+	Alpha2  string   `json:"alpha2"`
+	Alpha3  string   `json:"alpha3"`
+	NameRu  string   `json:"name_ru"`
+	NameEn  string   `json:"name_en,omitempty"`
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+type CountryImport struct {
+	Countries int `json:"countries"`
+	Skipped   int `json:"skipped"`
+}
+
+// CountrySummary counts nomenclature by country of origin, Code is empty
+// for items without a recognized country.
+type CountrySummary struct {
+	Code         string `json:"code"`
+	Name         string `json:"name"`
+	Domestic     bool   `json:"domestic"`
+	Nomenclature int    `json:"nomenclature"`
+}
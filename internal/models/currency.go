@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// CurrencyRate is the Central Bank of Russia rate: Value rubles for Nominal
+// units of the currency.
+type CurrencyRate struct {
+	Code    string    `json:"code"`
+	Date    time.Time `json:"date"`
+	Nominal int       `json:"nominal"`
+	Value   float64   `json:"value"`
+}
+
+type CurrencyRates struct {
+	Date  time.Time       `json:"date"`
+	Rates []*CurrencyRate `json:"rates"`
+}
@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// MatchItem holds the fields nomenclature is matched by.
+type MatchItem struct {
+	Id           string `json:"id"`
+	Name         string `json:"name"`
+	CodeSkmtr    string `json:"code_skmtr"`
+	CodeKsNsi    string `json:"code_ks_nsi"`
+	CodeAmto     string `json:"code_amto"`
+	Manufacturer string `json:"manufacturer"`
+	TmcMark      string `json:"tmc_mark"`
+	GostTu       string `json:"gost_tu"`
+	DrawingName  string `json:"drawing_name"`
+}
+
+// DuplicateCandidate pairs new nomenclature with a catalogue item it
+// probably duplicates. Reasons name the fields that matched.
+type DuplicateCandidate struct {
+	Id             string    `json:"id"`
+	NomenclatureId string    `json:"nomenclature_id"`
+	Name           string    `json:"name"`
+	DuplicateId    string    `json:"duplicate_id"`
+	DuplicateName  string    `json:"duplicate_name"`
+	Score          float64   `json:"score"`
+	Reasons        []string  `json:"reasons"`
+	Status         string    `json:"status"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type DuplicateRun struct {
+	Checked int `json:"checked"`
+	Found   int `json:"found"`
+}
+
+type DuplicateReviewReq struct {
+	Confirm []string `json:"confirm"`
+	Reject  []string `json:"reject"`
+}
+
+type DuplicateReview struct {
+	Confirmed int64 `json:"confirmed"`
+	Rejected  int64 `json:"rejected"`
+}
+
+// DuplicateMerge reports a merge: price list links of the merged item are
+// moved to the master item, the merged item points to the master.
+type DuplicateMerge struct {
+	CandidateId string `json:"candidate_id"`
+	MasterId    string `json:"master_id"`
+	MergedId    string `json:"merged_id"`
+	PriceLinks  int64  `json:"price_links"`
+}
@@ -0,0 +1,34 @@
+package models
+
+// ExtractionRule is one step of the attribute extraction from product names.
+// Rules run in Position order, every rule removes its match from the name.
+type ExtractionRule struct {
+	Id       string `json:"id"`
+	Position int    `json:"position"`
+	Pattern  string `json:"pattern"`
+	Field    string `json:"field"`
+	Unit     string `json:"unit"`
+	Active   bool   `json:"active"`
+}
+
+// ExtractedAttribute holds numeric values already converted to the base unit
+// of the field: millimetres, kilograms or litres.
+type ExtractedAttribute struct {
+	Field  string  `json:"field"`
+	Value  string  `json:"value"`
+	Number float64 `json:"number,omitempty"`
+	Unit   string  `json:"unit,omitempty"`
+	Match  string  `json:"match"`
+	RuleId string  `json:"rule_id,omitempty"`
+}
+
+type ExtractionResult struct {
+	Source     string                `json:"source"`
+	Name       string                `json:"name"`
+	Attributes []*ExtractedAttribute `json:"attributes"`
+}
+
+type ExtractionTestReq struct {
+	Names []string          `json:"names"`
+	Rules []*ExtractionRule `json:"rules"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+type HookRun struct {
+	Key        string       `json:"key"`
+	UploadId   string       `json:"upload_id"`
+	Status     string       `json:"status"`
+	Attempts   int          `json:"attempts"`
+	Response   *ResponseMsg `json:"response,omitempty"`
+	Error      string       `json:"error,omitempty"`
+	ErrorCode  int          `json:"error_code,omitempty"`
+	ErrorType  string       `json:"error_type,omitempty"`
+	StartedAt  time.Time    `json:"started_at"`
+	FinishedAt *time.Time   `json:"finished_at,omitempty"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+type Manufacturer struct {
+	Id      string   `json:"id"`
+	Name    string   `json:"name"`
+	Inn     string   `json:"inn,omitempty"`
+	Country string   `json:"country,omitempty"`
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+type ManufacturerImport struct {
+	Manufacturers int   `json:"manufacturers"`
+	Aliases       int   `json:"aliases"`
+	Resolved      int64 `json:"resolved"`
+}
+
+// UnresolvedManufacturer is a manufacturer string no alias matched. Values
+// are the spellings met in uploads, all reduced to the same Key.
+type UnresolvedManufacturer struct {
+	Key         string    `json:"key"`
+	Values      []string  `json:"values"`
+	Occurrences int       `json:"occurrences"`
+	LastSeen    time.Time `json:"last_seen"`
+}
+
+// ManufacturerResolveReq maps an unresolved key to an existing manufacturer
+// or to a new one when ManufacturerId is empty.
+type ManufacturerResolveReq struct {
+	Key            string        `json:"key"`
+	ManufacturerId string        `json:"manufacturer_id"`
+	Manufacturer   *Manufacturer `json:"manufacturer"`
+}
+
+type ManufacturerResolve struct {
+	ManufacturerId string `json:"manufacturer_id"`
+	Nomenclature   int64  `json:"nomenclature"`
+}
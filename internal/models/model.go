@@ -9,11 +9,29 @@ type Category struct {
 
 type ResponseMsg struct {
 	Message string `json:"message"`
+	// UploadId is the upload a direct import recorded, it is rolled back
+	// with DELETE api/v1/uploads/:id/data.
+	UploadId string `json:"upload_id,omitempty"`
+	// Sheets are the workbook sheets the import read.
+	Sheets []*SheetReport `json:"sheets,omitempty"`
 }
 
-type FileColumns struct {
-	RowId int8 `json:"row_id"`
-	RowName string `json:"row_name"`
+// ErrorResponse is the body of every failed request.
+type ErrorResponse struct {
+	Code      string      `json:"code"`
+	Message   string      `json:"message"`
+	Details   interface{} `json:"details,omitempty"`
+	RequestId string      `json:"request_id"`
+}
+
+type UploadRollback struct {
+	UploadId      string `json:"upload_id"`
+	Nomenclature  int64  `json:"nomenclature"`
+	Packages      int64  `json:"packages"`
+	PriceLinks    int64  `json:"price_links"`
+	PriceVersions int64  `json:"price_versions"`
+	Restored      int64  `json:"restored"`
+	Unmerged      int64  `json:"unmerged"`
 }
 
 type Nomenclature struct {
@@ -33,9 +51,15 @@ type Nomenclature struct {
 	IsTax                 bool                   `json:"is_tax"`
 	TaxPercentage         float32                `json:"tax_percentage"`
 	PricePerUnit          float32                `json:"price_per_unit"`
+	PriceIncludesVat      bool                   `json:"price_includes_vat"`
+	PriceNet              float32                `json:"price_net"`
+	VatAmount             float32                `json:"vat_amount"`
+	PriceGross            float32                `json:"price_gross"`
+	Currency              string                 `json:"currency"`
 	Measurement           string                 `json:"measurement"`
 	PriceValidThrough     string                 `json:"price_valid_through"`
 	WholesaleItems        *WholesaleItems        `json:"wholesale_items"`
+	PriceTiers            []*PriceTier           `json:"price_tiers"`
 	Quantity              int                    `json:"quantity"`
 	ProductAvailability   bool                   `json:"product_availability"`
 	HazardClass           string                 `json:"hazard_class"`
@@ -53,7 +77,7 @@ type Nomenclature struct {
 	Height                float32                `json:"height"`
 	Width                 float32                `json:"width"`
 	Volume                float32                `json:"volume"`
-	AmountInPackage       int8                   `json:"amount_in_package"`
+	AmountInPackage       int                    `json:"amount_in_package"`
 	Class                 string                 `json:"class"`
 	Representation        string                 `json:"representation"`
 	DeliveryAddress       string                 `json:"delivery_address"`
@@ -62,11 +86,18 @@ type Nomenclature struct {
 	Payload               *Mtr                   `json:"payload,omitempty"`
 	DrawingName           string                 `json:"drawing_name"`
 	CategoryName          string                 `json:"category_name"`
-	OrganizerNomenclature *OrganizerNomenclature `json:"payload"`
+	CategoryId            string                 `json:"category_id,omitempty"`
+	OrganizerNomenclature *OrganizerNomenclature `json:"organizer_payload,omitempty"`
 	CompanyInn            string                 `json:"company_inn"`
 	UserId                string                 `json:"user_id"`
 	CargoCatalogue        *CargoCatalogue        `json:"cargo_catalogue"`
 	PriceLists            []string               `json:"price"`
+	UploadId              string                 `json:"upload_id"`
+	Attributes            []*ExtractedAttribute  `json:"attributes"`
+	Standards             []*Standard            `json:"standards"`
+	Source                string                 `json:"source"`
+	ManufacturerId        string                 `json:"manufacturer_id,omitempty"`
+	CountryCode           string                 `json:"country_code,omitempty"`
 }
 
 type Mtr struct {
@@ -0,0 +1,20 @@
+package models
+
+// MtrLink connects a supplier item to the organizer MTR reference it
+// supplies, MtrId is the id of the organizer_catalogue row. Method is the
+// code that matched, "name" for fuzzy matches or "manual".
+type MtrLink struct {
+	NomenclatureId string  `json:"nomenclature_id"`
+	MtrId          string  `json:"mtr_id"`
+	Confidence     float64 `json:"confidence"`
+	Method         string  `json:"method"`
+}
+
+type MtrLinkRun struct {
+	Checked int `json:"checked"`
+	Linked  int `json:"linked"`
+}
+
+type MtrLinkReq struct {
+	MtrId string `json:"mtr_id"`
+}
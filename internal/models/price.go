@@ -0,0 +1,68 @@
+package models
+
+import "time"
+
+type PriceListVersion struct {
+	Id        string    `json:"id"`
+	PriceId   string    `json:"price_id"`
+	UploadId  string    `json:"upload_id"`
+	Version   int       `json:"version"`
+	Items     int       `json:"items"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type PriceListItem struct {
+	ItemKey        string          `json:"item_key"`
+	NomenclatureId string          `json:"nomenclature_id"`
+	Name           string          `json:"name"`
+	PricePerUnit   float32         `json:"price_per_unit"`
+	Currency       string          `json:"currency"`
+	WholesaleItems *WholesaleItems `json:"wholesale_items"`
+	PriceTiers     []*PriceTier    `json:"price_tiers"`
+}
+
+type PriceChange struct {
+	ItemKey         string          `json:"item_key"`
+	Name            string          `json:"name"`
+	OldPricePerUnit float32         `json:"old_price_per_unit"`
+	NewPricePerUnit float32         `json:"new_price_per_unit"`
+	OldCurrency     string          `json:"old_currency"`
+	NewCurrency     string          `json:"new_currency"`
+	OldWholesale    *WholesaleItems `json:"old_wholesale_items"`
+	NewWholesale    *WholesaleItems `json:"new_wholesale_items"`
+	OldPriceTiers   []*PriceTier    `json:"old_price_tiers"`
+	NewPriceTiers   []*PriceTier    `json:"new_price_tiers"`
+}
+
+type PriceListDiff struct {
+	PriceId     string           `json:"price_id"`
+	FromVersion int              `json:"from_version"`
+	ToVersion   int              `json:"to_version"`
+	Added       []*PriceListItem `json:"added"`
+	Removed     []*PriceListItem `json:"removed"`
+	Repriced    []*PriceChange   `json:"repriced"`
+}
+
+// PriceTier is a quantity break of the wholesale price. QuantityTo is nil
+// when the tier has no upper bound.
+type PriceTier struct {
+	QuantityFrom float64  `json:"quantity_from"`
+	QuantityTo   *float64 `json:"quantity_to"`
+	Price        float64  `json:"price"`
+}
+
+// PriceQuote holds prices in the original currency of the nomenclature and
+// converted to rubles at the exchange rate of Date.
+type PriceQuote struct {
+	NomenclatureId  string     `json:"nomenclature_id"`
+	Quantity        float64    `json:"quantity"`
+	Currency        string     `json:"currency"`
+	PricePerUnit    float64    `json:"price_per_unit"`
+	Price           float64    `json:"price"`
+	Tier            *PriceTier `json:"tier"`
+	Date            time.Time  `json:"date"`
+	Rate            float64    `json:"rate"`
+	RateDate        time.Time  `json:"rate_date"`
+	PricePerUnitRub float64    `json:"price_per_unit_rub"`
+	PriceRub        float64    `json:"price_rub"`
+}
@@ -0,0 +1,24 @@
+package models
+
+// SheetSelector picks the workbook sheet to import. Name wins over Index,
+// Index counts from 1. When both are empty the sheet is found by its
+// header.
+type SheetSelector struct {
+	Name  string `json:"sheet,omitempty" form:"sheet" query:"sheet"`
+	Index int    `json:"sheet_index,omitempty" form:"sheet_index" query:"sheet_index"`
+}
+
+// SheetReport is what an import made of one sheet. HeaderRow and DataRow
+// are the row numbers the header and the data start at, Rows counts the
+// data rows read, Errors the rows with problems in the upload report.
+type SheetReport struct {
+	FileName     string `json:"file_name,omitempty"`
+	Sheet        string `json:"sheet"`
+	Template     string `json:"template,omitempty"`
+	HeaderRow    int    `json:"header_row,omitempty"`
+	DataRow      int    `json:"data_row,omitempty"`
+	CategoryHint string `json:"category_hint,omitempty"`
+	Rows         int    `json:"rows"`
+	Saved        int    `json:"saved"`
+	Errors       int    `json:"errors"`
+}
@@ -0,0 +1,16 @@
+package models
+
+// Standard is a normative document reference in canonical form, Code is
+// Kind and Number joined by a space: "ГОСТ Р 52857.1-2007", "ТУ 14-3-1128-2000".
+type Standard struct {
+	Id     string `json:"id,omitempty"`
+	Kind   string `json:"kind"`
+	Number string `json:"number"`
+	Code   string `json:"code"`
+}
+
+type StandardNomenclature struct {
+	NomenclatureId string `json:"nomenclature_id"`
+	Name           string `json:"name"`
+	Standard       string `json:"standard"`
+}
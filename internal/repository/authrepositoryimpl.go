@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"excel-service/internal/models"
+	"net/http"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+// SelectUserPrincipal returns the company and role of an active Directus
+// user, Admin is set for roles with admin access.
+func (e ExcelRepositoryImpl) SelectUserPrincipal(ctx context.Context, userId string) (*models.Principal, error) {
+	p := &models.Principal{UserId: userId}
+	err := e.lb.CallPrimaryPreferred().PGxPool().QueryRow(
+		ctx,
+		"select coalesce(du.company::text, ''), coalesce(du.role::text, ''), coalesce(dr.admin_access, false) "+
+			"from directus_users du left join directus_roles dr on dr.id = du.role "+
+			"where du.id::text = $1 and du.status = 'active'",
+		userId,
+	).Scan(&p.CompanyId, &p.Role, &p.Admin)
+	if err == pgx.ErrNoRows {
+		log.Warnf("user %s not found or not active", userId)
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, "user not found or not active")
+	}
+	if err != nil {
+		log.Errorf("failed to select user principal: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	return p, nil
+}
+
+func (e ExcelRepositoryImpl) SelectUploadCompany(ctx context.Context, uploadId string) (string, error) {
+	var companyId string
+	err := e.lb.CallPrimaryPreferred().PGxPool().QueryRow(
+		ctx,
+		"select coalesce(company::text, '') from uploads where id::text = $1",
+		uploadId,
+	).Scan(&companyId)
+	if err == pgx.ErrNoRows {
+		log.Warnf("upload %s not found", uploadId)
+		return "", echo.NewHTTPError(http.StatusNotFound, "upload not found")
+	}
+	if err != nil {
+		log.Errorf("failed to select upload company: %v", err)
+		return "", echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	return companyId, nil
+}
+
+func (e ExcelRepositoryImpl) SelectCompanyIdByName(ctx context.Context, name string) (string, error) {
+	var companyId string
+	err := e.lb.CallPrimaryPreferred().PGxPool().QueryRow(ctx, "select id::text from company where name = $1", name).Scan(&companyId)
+	if err == pgx.ErrNoRows {
+		log.Warnf("company %s not found", name)
+		return "", echo.NewHTTPError(http.StatusNotFound, "company not found")
+	}
+	if err != nil {
+		log.Errorf("failed to select company by name: %v", err)
+		return "", echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	return companyId, nil
+}
+
+// SelectPriceListCompanies returns the companies whose uploads fill the
+// price list.
+func (e ExcelRepositoryImpl) SelectPriceListCompanies(ctx context.Context, priceId string) ([]string, error) {
+	rows, err := e.lb.CallPrimaryPreferred().PGxPool().Query(
+		ctx,
+		"select distinct coalesce(u.company::text, '') from uploads_price up join uploads u on u.id = up.uploads_id where up.price_id::text = $1",
+		priceId,
+	)
+	if err != nil {
+		log.Errorf("failed to select price list companies: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	defer rows.Close()
+
+	var companies []string
+	for rows.Next() {
+		var companyId string
+		if scanErr := rows.Scan(&companyId); scanErr != nil {
+			log.Errorf("failed to scan price list company: %v", scanErr)
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, scanErr)
+		}
+		companies = append(companies, companyId)
+	}
+	if rows.Err() != nil {
+		log.Errorf("failed to read price list companies: %v", rows.Err())
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, rows.Err())
+	}
+	if len(companies) == 0 {
+		log.Warnf("price list %s not found", priceId)
+		return nil, echo.NewHTTPError(http.StatusNotFound, "price list not found")
+	}
+	return companies, nil
+}
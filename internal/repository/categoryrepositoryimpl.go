@@ -0,0 +1,216 @@
+package repository
+
+import (
+	"context"
+	"excel-service/internal/models"
+	"net/http"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+func (e ExcelRepositoryImpl) SelectCategorizedNomenclature(ctx context.Context, unclassified string, limit int) ([]*models.CategorySample, error) {
+	return e.selectCategorySamples(
+		ctx,
+		"select n.id::text, n.name, coalesce(o.code, ''), coalesce(n.manufacturer, ''), c.id::text, c.name from nomenclature n "+
+			"join category c on c.id = n.category left join okpd2 o on o.id = n.okpd2 "+
+			"where c.name <> $1 and n.name is not null limit $2",
+		unclassified, limit,
+	)
+}
+
+// SelectUnclassifiedNomenclature skips items that already wait for a
+// manager decision.
+func (e ExcelRepositoryImpl) SelectUnclassifiedNomenclature(ctx context.Context, unclassified string, limit int) ([]*models.CategorySample, error) {
+	return e.selectCategorySamples(
+		ctx,
+		"select n.id::text, n.name, coalesce(o.code, ''), coalesce(n.manufacturer, ''), coalesce(c.id::text, ''), coalesce(c.name, '') from nomenclature n "+
+			"left join category c on c.id = n.category left join okpd2 o on o.id = n.okpd2 "+
+			"where (n.category is null or c.name = $1) and n.name is not null "+
+			"and not exists (select 1 from category_suggestion s where s.nomenclature_id = n.id and s.status = 'pending') limit $2",
+		unclassified, limit,
+	)
+}
+
+func (e ExcelRepositoryImpl) selectCategorySamples(ctx context.Context, query string, args ...interface{}) ([]*models.CategorySample, error) {
+	rows, err := e.lb.CallPrimaryPreferred().PGxPool().Query(ctx, query, args...)
+	if err != nil {
+		log.Errorf("failed to select nomenclature for category model: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	defer rows.Close()
+
+	var samples []*models.CategorySample
+	for rows.Next() {
+		sample := &models.CategorySample{}
+		if scanErr := rows.Scan(&sample.NomenclatureId, &sample.Name, &sample.Okpd2, &sample.Manufacturer, &sample.CategoryId, &sample.Category); scanErr != nil {
+			log.Errorf("failed to scan nomenclature for category model: %v", scanErr)
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, scanErr)
+		}
+		samples = append(samples, sample)
+	}
+	if rows.Err() != nil {
+		log.Errorf("failed to read nomenclature for category model: %v", rows.Err())
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, rows.Err())
+	}
+	return samples, nil
+}
+
+func (e ExcelRepositoryImpl) SaveCategorySuggestions(ctx context.Context, suggestions []*models.CategorySuggestion) error {
+	batch := &pgx.Batch{}
+	for _, suggestion := range suggestions {
+		batch.Queue(
+			"insert into category_suggestion (nomenclature_id, category_id, category_name, confidence) values ($1, $2, $3, $4)",
+			suggestion.NomenclatureId, suggestion.CategoryId, suggestion.Category, suggestion.Confidence,
+		)
+	}
+
+	if bErr := e.lb.CallPrimaryPreferred().PGxPool().SendBatch(ctx, batch).Close(); bErr != nil {
+		log.Errorf("failed to save category suggestions: %v", bErr)
+		return echo.NewHTTPError(http.StatusInternalServerError, bErr)
+	}
+	return nil
+}
+
+func (e ExcelRepositoryImpl) SelectCategorySuggestions(ctx context.Context, status string) ([]*models.CategorySuggestion, error) {
+	rows, err := e.lb.CallPrimaryPreferred().PGxPool().Query(
+		ctx,
+		"select s.id::text, s.nomenclature_id::text, coalesce(n.name, ''), coalesce(s.category_id::text, ''), coalesce(c.name, s.category_name), s.confidence, s.status, s.created_at "+
+			"from category_suggestion s join nomenclature n on n.id = s.nomenclature_id left join category c on c.id = s.category_id "+
+			"where s.status = $1 order by s.confidence desc, s.created_at",
+		status,
+	)
+	if err != nil {
+		log.Errorf("failed to select category suggestions: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	defer rows.Close()
+
+	res := []*models.CategorySuggestion{}
+	for rows.Next() {
+		s := &models.CategorySuggestion{}
+		if scanErr := rows.Scan(&s.Id, &s.NomenclatureId, &s.Name, &s.CategoryId, &s.Category, &s.Confidence, &s.Status, &s.CreatedAt); scanErr != nil {
+			log.Errorf("failed to scan category suggestion: %v", scanErr)
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, scanErr)
+		}
+		res = append(res, s)
+	}
+	if rows.Err() != nil {
+		log.Errorf("failed to read category suggestions: %v", rows.Err())
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, rows.Err())
+	}
+	return res, nil
+}
+
+// ReviewCategorySuggestions moves accepted items to the suggested category.
+// Only pending suggestions are touched, a second review of the same ids
+// changes nothing. Suggestions without a category id, made before ids were
+// kept for an ambiguous name, can only be rejected.
+func (e ExcelRepositoryImpl) ReviewCategorySuggestions(ctx context.Context, accept, reject []string, tx pgx.Tx) (*models.CategoryReview, error) {
+	res := &models.CategoryReview{}
+	steps := []struct {
+		query    string
+		ids      []string
+		affected *int64
+	}{
+		{"update nomenclature n set category = s.category_id " +
+			"from category_suggestion s where s.id::text = any($1) and s.status = 'pending' and s.category_id is not null and n.id = s.nomenclature_id", accept, nil},
+		{"update category_suggestion set status = 'accepted', reviewed_at = now() where id::text = any($1) and status = 'pending' and category_id is not null", accept, &res.Accepted},
+		{"update category_suggestion set status = 'rejected', reviewed_at = now() where id::text = any($1) and status = 'pending'", reject, &res.Rejected},
+	}
+
+	for _, step := range steps {
+		if len(step.ids) == 0 {
+			continue
+		}
+		tag, err := tx.Exec(ctx, step.query, step.ids)
+		if err != nil {
+			rbErr := tx.Rollback(ctx)
+			if rbErr != nil {
+				log.Errorf("failed to roll back tx in ReviewCategorySuggestions: %v", rbErr)
+				return nil, echo.NewHTTPError(http.StatusInternalServerError, rbErr)
+			}
+			log.Errorf("failed to review category suggestions: %v", err)
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, err)
+		}
+		if step.affected != nil {
+			*step.affected = tag.RowsAffected()
+		}
+	}
+	return res, nil
+}
+
+func (e ExcelRepositoryImpl) SelectCategories(ctx context.Context) ([]*models.CategoryNode, error) {
+	rows, err := e.lb.CallPrimaryPreferred().PGxPool().Query(
+		ctx,
+		"select id::text, coalesce(name, ''), coalesce(code, ''), coalesce(parent::text, '') from category order by code, name",
+	)
+	if err != nil {
+		log.Errorf("failed to select categories: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	defer rows.Close()
+
+	var res []*models.CategoryNode
+	for rows.Next() {
+		node := &models.CategoryNode{}
+		if scanErr := rows.Scan(&node.Id, &node.Name, &node.Code, &node.ParentId); scanErr != nil {
+			log.Errorf("failed to scan category: %v", scanErr)
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, scanErr)
+		}
+		res = append(res, node)
+	}
+	if rows.Err() != nil {
+		log.Errorf("failed to read categories: %v", rows.Err())
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, rows.Err())
+	}
+	return res, nil
+}
+
+// UpsertCategory finds the category by code, or by name under the same
+// parent when it has no code, and points it to the parent. It returns the id
+// and whether the category was created.
+func (e ExcelRepositoryImpl) UpsertCategory(ctx context.Context, node *models.CategoryNode, tx pgx.Tx) (string, bool, error) {
+	var id string
+	var err error
+	if node.Code != "" {
+		err = tx.QueryRow(
+			ctx,
+			"update category set name = $1, parent = (select id from category where id::text = $3) where code = $2 returning id::text",
+			node.Name, node.Code, newNullString(node.ParentId),
+		).Scan(&id)
+	} else {
+		err = tx.QueryRow(
+			ctx,
+			"select id::text from category where name = $1 and code is null and parent is not distinct from (select id from category where id::text = $2) limit 1",
+			node.Name, newNullString(node.ParentId),
+		).Scan(&id)
+	}
+	if err == nil {
+		return id, false, nil
+	}
+	if err != pgx.ErrNoRows {
+		return "", false, e.rollbackCategory(ctx, tx, err)
+	}
+
+	err = tx.QueryRow(
+		ctx,
+		"insert into category (name, code, type, parent) values ($1, $2, $3, (select id from category where id::text = $4)) returning id::text",
+		node.Name, newNullString(node.Code), "mdm", newNullString(node.ParentId),
+	).Scan(&id)
+	if err != nil {
+		return "", false, e.rollbackCategory(ctx, tx, err)
+	}
+	return id, true, nil
+}
+
+func (e ExcelRepositoryImpl) rollbackCategory(ctx context.Context, tx pgx.Tx, err error) error {
+	rbErr := tx.Rollback(ctx)
+	if rbErr != nil {
+		log.Errorf("failed to roll back tx in UpsertCategory: %v", rbErr)
+		return echo.NewHTTPError(http.StatusInternalServerError, rbErr)
+	}
+	log.Errorf("failed to upsert category: %v", err)
+	return echo.NewHTTPError(http.StatusInternalServerError, err)
+}
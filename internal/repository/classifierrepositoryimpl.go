@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"excel-service/internal/models"
+	"net/http"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+// classifierTables maps classifier kinds to their tables, kinds never come
+// into queries from the request directly.
+var classifierTables = map[string]string{
+	"okpd2": "okpd2",
+	"tnved": "tnved",
+}
+
+func classifierTable(kind string) (string, error) {
+	table, ok := classifierTables[kind]
+	if !ok {
+		log.Errorf("unknown classifier %s", kind)
+		return "", echo.NewHTTPError(http.StatusBadRequest, "unknown classifier "+kind)
+	}
+	return table, nil
+}
+
+func (e ExcelRepositoryImpl) SaveClassifierCodes(ctx context.Context, kind string, codes []*models.ClassifierCode) error {
+	table, tErr := classifierTable(kind)
+	if tErr != nil {
+		return tErr
+	}
+
+	batch := &pgx.Batch{}
+	for _, code := range codes {
+		batch.Queue(
+			"insert into "+table+" (code, name, parent_code, level) values ($1, $2, $3, $4) "+
+				"on conflict (code) do update set name = excluded.name, parent_code = excluded.parent_code, level = excluded.level",
+			code.Code, code.Name, newNullString(code.ParentCode), code.Level,
+		)
+	}
+
+	if bErr := e.lb.CallPrimaryPreferred().PGxPool().SendBatch(ctx, batch).Close(); bErr != nil {
+		log.Errorf("failed to save %s codes: %v", kind, bErr)
+		return echo.NewHTTPError(http.StatusInternalServerError, bErr)
+	}
+	return nil
+}
+
+// HasClassifierCodes tells whether the classifier was imported at all,
+// codes are only checked against a filled dictionary.
+func (e ExcelRepositoryImpl) HasClassifierCodes(ctx context.Context, kind string) (bool, error) {
+	table, tErr := classifierTable(kind)
+	if tErr != nil {
+		return false, tErr
+	}
+
+	var exists bool
+	err := e.lb.CallPrimaryPreferred().PGxPool().QueryRow(ctx, "select exists (select 1 from "+table+")").Scan(&exists)
+	if err != nil {
+		log.Errorf("failed to check %s codes: %v", kind, err)
+		return false, echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	return exists, nil
+}
+
+func (e ExcelRepositoryImpl) ClassifierCodeExists(ctx context.Context, kind, code string) (bool, error) {
+	table, tErr := classifierTable(kind)
+	if tErr != nil {
+		return false, tErr
+	}
+
+	var exists bool
+	err := e.lb.CallPrimaryPreferred().PGxPool().QueryRow(ctx, "select exists (select 1 from "+table+" where code = $1)", code).Scan(&exists)
+	if err != nil {
+		log.Errorf("failed to check %s code %s: %v", kind, code, err)
+		return false, echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	return exists, nil
+}
+
+// SelectClassifierPath returns the code and all its parents, the section
+// goes first.
+func (e ExcelRepositoryImpl) SelectClassifierPath(ctx context.Context, kind, code string) ([]*models.ClassifierCode, error) {
+	table, tErr := classifierTable(kind)
+	if tErr != nil {
+		return nil, tErr
+	}
+
+	rows, err := e.lb.CallPrimaryPreferred().PGxPool().Query(
+		ctx,
+		"with recursive path as ("+
+			"select code, name, parent_code, level, 0 as depth from "+table+" where code = $1 "+
+			"union all "+
+			"select c.code, c.name, c.parent_code, c.level, p.depth + 1 from "+table+" c join path p on c.code = p.parent_code where p.depth < 16"+
+			") select code, coalesce(name, ''), coalesce(parent_code, ''), coalesce(level, 0) from path order by depth desc",
+		code,
+	)
+	if err != nil {
+		log.Errorf("failed to select %s path: %v", kind, err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	defer rows.Close()
+
+	var path []*models.ClassifierCode
+	for rows.Next() {
+		item := &models.ClassifierCode{}
+		if scanErr := rows.Scan(&item.Code, &item.Name, &item.ParentCode, &item.Level); scanErr != nil {
+			log.Errorf("failed to scan %s path: %v", kind, scanErr)
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, scanErr)
+		}
+		path = append(path, item)
+	}
+	if rows.Err() != nil {
+		log.Errorf("failed to read %s path: %v", kind, rows.Err())
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, rows.Err())
+	}
+	if len(path) == 0 {
+		log.Warnf("%s code %s not found", kind, code)
+		return nil, echo.NewHTTPError(http.StatusNotFound, "code not found")
+	}
+	return path, nil
+}
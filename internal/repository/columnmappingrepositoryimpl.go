@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"excel-service/internal/models"
+	"net/http"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+// SelectUploadColumnMapping returns the mapping confirmed for the upload, nil
+// when there is none.
+func (e ExcelRepositoryImpl) SelectUploadColumnMapping(ctx context.Context, uploadId string) (*models.ColumnMapping, error) {
+	m := &models.ColumnMapping{}
+	err := e.lb.CallPrimaryPreferred().PGxPool().QueryRow(
+		ctx,
+		"select upload::text, coalesce(file, ''), sheet, data_row, columns, coalesce(template::text, '') from upload_column_mapping where upload = $1",
+		uploadId,
+	).Scan(&m.UploadId, &m.FileName, &m.Sheet, &m.DataRow, &m.Columns, &m.TemplateId)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		log.Errorf("failed to select upload column mapping: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	return m, nil
+}
+
+// SaveUploadColumnMapping replaces the mapping of the upload.
+func (e ExcelRepositoryImpl) SaveUploadColumnMapping(ctx context.Context, m *models.ColumnMapping) error {
+	_, err := e.lb.CallPrimaryPreferred().PGxPool().Exec(
+		ctx,
+		"insert into upload_column_mapping (upload, file, sheet, data_row, columns, template) values ($1, $2, $3, $4, $5, $6) "+
+			"on conflict (upload) do update set file = excluded.file, sheet = excluded.sheet, data_row = excluded.data_row, "+
+			"columns = excluded.columns, template = excluded.template, updated_at = now()",
+		m.UploadId, m.FileName, m.Sheet, m.DataRow, m.Columns, newNullString(m.TemplateId),
+	)
+	if err != nil {
+		log.Errorf("failed to save upload column mapping: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	return nil
+}
+
+// SelectColumnTemplates returns the templates of the company, the latest
+// saved first.
+func (e ExcelRepositoryImpl) SelectColumnTemplates(ctx context.Context, companyId string) ([]*models.ColumnTemplate, error) {
+	rows, err := e.lb.CallPrimaryPreferred().PGxPool().Query(
+		ctx,
+		"select id::text, company::text, name, columns, updated_at from column_template where company = $1 order by updated_at desc, name",
+		companyId,
+	)
+	if err != nil {
+		log.Errorf("failed to select column templates: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	defer rows.Close()
+
+	templates := []*models.ColumnTemplate{}
+	for rows.Next() {
+		t := &models.ColumnTemplate{}
+		if scanErr := rows.Scan(&t.Id, &t.CompanyId, &t.Name, &t.Columns, &t.UpdatedAt); scanErr != nil {
+			log.Errorf("failed to scan column template: %v", scanErr)
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, scanErr)
+		}
+		templates = append(templates, t)
+	}
+	if rows.Err() != nil {
+		log.Errorf("failed to read column templates: %v", rows.Err())
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, rows.Err())
+	}
+	return templates, nil
+}
+
+// SaveColumnTemplate upserts the template by company and name and fills in
+// its id.
+func (e ExcelRepositoryImpl) SaveColumnTemplate(ctx context.Context, t *models.ColumnTemplate) error {
+	err := e.lb.CallPrimaryPreferred().PGxPool().QueryRow(
+		ctx,
+		"insert into column_template (company, name, columns) values ($1, $2, $3) "+
+			"on conflict (company, name) do update set columns = excluded.columns, updated_at = now() "+
+			"returning id::text, updated_at",
+		t.CompanyId, t.Name, t.Columns,
+	).Scan(&t.Id, &t.UpdatedAt)
+	if err != nil {
+		log.Errorf("failed to save column template: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"excel-service/internal/models"
+	"net/http"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+func (e ExcelRepositoryImpl) SelectCountries(ctx context.Context) ([]*models.Country, error) {
+	rows, err := e.lb.CallPrimaryPreferred().PGxPool().Query(
+		ctx,
+		"select c.code, c.alpha2, c.alpha3, c.name_ru, coalesce(c.name_en, ''), "+
+			"coalesce(array_agg(a.alias order by a.alias) filter (where a.alias is not null), '{}') "+
+			"from country c left join country_alias a on a.country_code = c.code "+
+			"group by c.code order by c.name_ru",
+	)
+	if err != nil {
+		log.Errorf("failed to select countries: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	defer rows.Close()
+
+	countries := []*models.Country{}
+	for rows.Next() {
+		c := &models.Country{}
+		if scanErr := rows.Scan(&c.Code, &c.Alpha2, &c.Alpha3, &c.NameRu, &c.NameEn, &c.Aliases); scanErr != nil {
+			log.Errorf("failed to scan country: %v", scanErr)
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, scanErr)
+		}
+		countries = append(countries, c)
+	}
+	if rows.Err() != nil {
+		log.Errorf("failed to read countries: %v", rows.Err())
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, rows.Err())
+	}
+	return countries, nil
+}
+
+// SaveCountries upserts OKSM entries by code, English names already known
+// are kept when the file has none.
+func (e ExcelRepositoryImpl) SaveCountries(ctx context.Context, countries []*models.Country) error {
+	batch := &pgx.Batch{}
+	for _, c := range countries {
+		batch.Queue(
+			"insert into country (code, alpha2, alpha3, name_ru, name_en) values ($1, $2, $3, $4, $5) "+
+				"on conflict (code) do update set alpha2 = excluded.alpha2, alpha3 = excluded.alpha3, name_ru = excluded.name_ru, "+
+				"name_en = coalesce(excluded.name_en, country.name_en)",
+			c.Code, c.Alpha2, c.Alpha3, c.NameRu, newNullString(c.NameEn),
+		)
+		for _, alias := range c.Aliases {
+			batch.Queue(
+				"insert into country_alias (alias, country_code) values ($1, $2) on conflict (alias) do update set country_code = excluded.country_code",
+				alias, c.Code,
+			)
+		}
+	}
+
+	if bErr := e.lb.CallPrimaryPreferred().PGxPool().SendBatch(ctx, batch).Close(); bErr != nil {
+		log.Errorf("failed to save countries: %v", bErr)
+		return echo.NewHTTPError(http.StatusInternalServerError, bErr)
+	}
+	return nil
+}
+
+// SelectCountrySummary counts active nomenclature by country of origin,
+// items without a country come last with an empty code.
+func (e ExcelRepositoryImpl) SelectCountrySummary(ctx context.Context) ([]*models.CountrySummary, error) {
+	rows, err := e.lb.CallPrimaryPreferred().PGxPool().Query(
+		ctx,
+		"select coalesce(n.country_code, ''), coalesce(c.name_ru, ''), count(*) "+
+			"from nomenclature n left join country c on c.code = n.country_code "+
+			"where n.merged_into is null "+
+			"group by n.country_code, c.name_ru order by n.country_code is null, count(*) desc",
+	)
+	if err != nil {
+		log.Errorf("failed to select country summary: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	defer rows.Close()
+
+	summary := []*models.CountrySummary{}
+	for rows.Next() {
+		s := &models.CountrySummary{}
+		if scanErr := rows.Scan(&s.Code, &s.Name, &s.Nomenclature); scanErr != nil {
+			log.Errorf("failed to scan country summary: %v", scanErr)
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, scanErr)
+		}
+		summary = append(summary, s)
+	}
+	if rows.Err() != nil {
+		log.Errorf("failed to read country summary: %v", rows.Err())
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, rows.Err())
+	}
+	return summary, nil
+}
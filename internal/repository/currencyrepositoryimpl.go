@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"excel-service/internal/models"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+func (e ExcelRepositoryImpl) SaveCurrencyRates(ctx context.Context, rates []*models.CurrencyRate) error {
+	batch := &pgx.Batch{}
+	for _, rate := range rates {
+		batch.Queue(
+			"insert into currency_rate (currency_code, rate_date, nominal, value) values ($1, $2, $3, $4) "+
+				"on conflict (currency_code, rate_date) do update set nominal = excluded.nominal, value = excluded.value",
+			rate.Code, rate.Date, rate.Nominal, rate.Value,
+		)
+	}
+
+	if bErr := e.lb.CallPrimaryPreferred().PGxPool().SendBatch(ctx, batch).Close(); bErr != nil {
+		log.Errorf("failed to save currency rates: %v", bErr)
+		return echo.NewHTTPError(http.StatusInternalServerError, bErr)
+	}
+	return nil
+}
+
+// SelectCurrencyRate returns the latest rate set on or before the date, the
+// CBR does not publish rates for weekends and holidays.
+func (e ExcelRepositoryImpl) SelectCurrencyRate(ctx context.Context, code string, date time.Time) (*models.CurrencyRate, error) {
+	rate := &models.CurrencyRate{Code: code}
+	err := e.lb.CallPrimaryPreferred().PGxPool().QueryRow(
+		ctx,
+		"select rate_date, nominal, value::float8 from currency_rate where currency_code = $1 and rate_date <= $2 order by rate_date desc limit 1",
+		code, date,
+	).Scan(&rate.Date, &rate.Nominal, &rate.Value)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			log.Warnf("no %s rate on %s", code, date.Format("2006-01-02"))
+			return nil, echo.NewHTTPError(http.StatusNotFound, "no exchange rate for "+code)
+		}
+		log.Errorf("failed to select currency rate: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	return rate, nil
+}
+
+// SelectCurrencyCodes returns the codes of the currency dictionary.
+func (e ExcelRepositoryImpl) SelectCurrencyCodes(ctx context.Context) (map[string]bool, error) {
+	rows, err := e.lb.CallPrimaryPreferred().PGxPool().Query(ctx, "select code from currency where code is not null")
+	if err != nil {
+		log.Errorf("failed to select currency codes: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	defer rows.Close()
+
+	codes := map[string]bool{}
+	for rows.Next() {
+		var code string
+		if scanErr := rows.Scan(&code); scanErr != nil {
+			log.Errorf("failed to scan currency code: %v", scanErr)
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, scanErr)
+		}
+		codes[strings.ToUpper(strings.TrimSpace(code))] = true
+	}
+	if rows.Err() != nil {
+		log.Errorf("failed to read currency codes: %v", rows.Err())
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, rows.Err())
+	}
+	return codes, nil
+}
@@ -0,0 +1,203 @@
+package repository
+
+import (
+	"context"
+	"excel-service/internal/models"
+	"net/http"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+const matchItemColumns = "id::text, coalesce(name, ''), coalesce(code_skmtr, ''), coalesce(code_ks_nsi, ''), coalesce(code_amto, ''), coalesce(manufacturer, ''), coalesce(tmc_mark, ''), coalesce(gost_tu, ''), coalesce(drawing_name, '')"
+
+func (e ExcelRepositoryImpl) SelectUploadMatchItems(ctx context.Context, uploadId string) ([]*models.MatchItem, error) {
+	return e.selectMatchItems(ctx, "select "+matchItemColumns+" from nomenclature where upload = $1 and merged_into is null", uploadId)
+}
+
+// SelectDuplicateCandidates picks catalogue items sharing a code, the
+// manufacturer and mark or a similar name with the item. Exact matches come
+// before similar names, so the limit never drops them for trigram matches.
+// Items of the same upload are not compared with each other.
+func (e ExcelRepositoryImpl) SelectDuplicateCandidates(ctx context.Context, item *models.MatchItem, uploadId string, limit int) ([]*models.MatchItem, error) {
+	const exact = "($3 <> '' and code_skmtr = $3) or ($4 <> '' and code_ks_nsi = $4) or ($5 <> '' and code_amto = $5) " +
+		"or ($6 <> '' and $7 <> '' and lower(manufacturer) = lower($6) and lower(tmc_mark) = lower($7))"
+	return e.selectMatchItems(
+		ctx,
+		"select "+matchItemColumns+" from nomenclature "+
+			"where id <> $1 and upload is distinct from $2::uuid and merged_into is null and ("+exact+" or name % $8) "+
+			"order by coalesce("+exact+", false) desc, similarity(name, $8) desc limit $9",
+		item.Id, newNullString(uploadId), item.CodeSkmtr, item.CodeKsNsi, item.CodeAmto, item.Manufacturer, item.TmcMark, item.Name, limit,
+	)
+}
+
+func (e ExcelRepositoryImpl) selectMatchItems(ctx context.Context, query string, args ...interface{}) ([]*models.MatchItem, error) {
+	rows, err := e.lb.CallPrimaryPreferred().PGxPool().Query(ctx, query, args...)
+	if err != nil {
+		log.Errorf("failed to select nomenclature for matching: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	defer rows.Close()
+
+	var items []*models.MatchItem
+	for rows.Next() {
+		item := &models.MatchItem{}
+		if scanErr := rows.Scan(&item.Id, &item.Name, &item.CodeSkmtr, &item.CodeKsNsi, &item.CodeAmto, &item.Manufacturer, &item.TmcMark, &item.GostTu, &item.DrawingName); scanErr != nil {
+			log.Errorf("failed to scan nomenclature for matching: %v", scanErr)
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, scanErr)
+		}
+		items = append(items, item)
+	}
+	if rows.Err() != nil {
+		log.Errorf("failed to read nomenclature for matching: %v", rows.Err())
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, rows.Err())
+	}
+	return items, nil
+}
+
+// SaveDuplicateCandidates skips pairs already in the queue in either order.
+func (e ExcelRepositoryImpl) SaveDuplicateCandidates(ctx context.Context, candidates []*models.DuplicateCandidate) error {
+	batch := &pgx.Batch{}
+	for _, c := range candidates {
+		batch.Queue(
+			"insert into duplicate_candidate (nomenclature_id, duplicate_id, score, reasons) select $1, $2, $3, $4 "+
+				"where not exists (select 1 from duplicate_candidate where nomenclature_id = $2 and duplicate_id = $1) "+
+				"on conflict (nomenclature_id, duplicate_id) do nothing",
+			c.NomenclatureId, c.DuplicateId, c.Score, c.Reasons,
+		)
+	}
+
+	if bErr := e.lb.CallPrimaryPreferred().PGxPool().SendBatch(ctx, batch).Close(); bErr != nil {
+		log.Errorf("failed to save duplicate candidates: %v", bErr)
+		return echo.NewHTTPError(http.StatusInternalServerError, bErr)
+	}
+	return nil
+}
+
+func (e ExcelRepositoryImpl) SelectDuplicateQueue(ctx context.Context, status string) ([]*models.DuplicateCandidate, error) {
+	rows, err := e.lb.CallPrimaryPreferred().PGxPool().Query(
+		ctx,
+		"select d.id::text, d.nomenclature_id::text, coalesce(n.name, ''), d.duplicate_id::text, coalesce(m.name, ''), d.score, d.reasons, d.status, d.created_at "+
+			"from duplicate_candidate d join nomenclature n on n.id = d.nomenclature_id join nomenclature m on m.id = d.duplicate_id "+
+			"where d.status = $1 order by d.score desc, d.created_at",
+		status,
+	)
+	if err != nil {
+		log.Errorf("failed to select duplicate candidates: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	defer rows.Close()
+
+	res := []*models.DuplicateCandidate{}
+	for rows.Next() {
+		c := &models.DuplicateCandidate{}
+		if scanErr := rows.Scan(&c.Id, &c.NomenclatureId, &c.Name, &c.DuplicateId, &c.DuplicateName, &c.Score, &c.Reasons, &c.Status, &c.CreatedAt); scanErr != nil {
+			log.Errorf("failed to scan duplicate candidate: %v", scanErr)
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, scanErr)
+		}
+		res = append(res, c)
+	}
+	if rows.Err() != nil {
+		log.Errorf("failed to read duplicate candidates: %v", rows.Err())
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, rows.Err())
+	}
+	return res, nil
+}
+
+func (e ExcelRepositoryImpl) ReviewDuplicates(ctx context.Context, confirm, reject []string) (*models.DuplicateReview, error) {
+	res := &models.DuplicateReview{}
+	steps := []struct {
+		status   string
+		ids      []string
+		affected *int64
+	}{
+		{"confirmed", confirm, &res.Confirmed},
+		{"rejected", reject, &res.Rejected},
+	}
+
+	for _, step := range steps {
+		if len(step.ids) == 0 {
+			continue
+		}
+		tag, err := e.lb.CallPrimaryPreferred().PGxPool().Exec(
+			ctx,
+			"update duplicate_candidate set status = $1, reviewed_at = now() where id::text = any($2) and status = 'pending'",
+			step.status, step.ids,
+		)
+		if err != nil {
+			log.Errorf("failed to review duplicate candidates: %v", err)
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, err)
+		}
+		*step.affected = tag.RowsAffected()
+	}
+	return res, nil
+}
+
+// MergeDuplicate keeps the catalogue item and merges the new one into it.
+// Pending and confirmed candidates can be merged. The price list links
+// moved to the kept item are recorded in nomenclature_merge.
+func (e ExcelRepositoryImpl) MergeDuplicate(ctx context.Context, candidateId string, tx pgx.Tx) (*models.DuplicateMerge, error) {
+	res := &models.DuplicateMerge{CandidateId: candidateId}
+	err := tx.QueryRow(
+		ctx,
+		"select duplicate_id::text, nomenclature_id::text from duplicate_candidate where id::text = $1 and status in ('pending', 'confirmed') for update",
+		candidateId,
+	).Scan(&res.MasterId, &res.MergedId)
+	if err != nil {
+		rbErr := tx.Rollback(ctx)
+		if rbErr != nil {
+			log.Errorf("failed to roll back tx in MergeDuplicate: %v", rbErr)
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, rbErr)
+		}
+		if err == pgx.ErrNoRows {
+			log.Warnf("duplicate candidate %s not found or already reviewed", candidateId)
+			return nil, echo.NewHTTPError(http.StatusNotFound, "duplicate candidate not found or already reviewed")
+		}
+		log.Errorf("failed to select duplicate candidate: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	// the moved links are recorded, a rollback of either upload moves them back
+	err = tx.QueryRow(
+		ctx,
+		"with moved as (update price_nomenclature set nomenclature_id = $1 where nomenclature_id = $2 returning price_id::text) "+
+			"insert into nomenclature_merge (merged_id, master_id, price_ids) select $2, $1, coalesce(array_agg(price_id), '{}') from moved "+
+			"returning cardinality(price_ids)",
+		res.MasterId, res.MergedId,
+	).Scan(&res.PriceLinks)
+	if err != nil {
+		rbErr := tx.Rollback(ctx)
+		if rbErr != nil {
+			log.Errorf("failed to roll back tx in MergeDuplicate: %v", rbErr)
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, rbErr)
+		}
+		log.Errorf("failed to move price links: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	steps := []struct {
+		query    string
+		args     []interface{}
+		affected *int64
+	}{
+		{"update nomenclature set merged_into = $1 where id = $2", []interface{}{res.MasterId, res.MergedId}, nil},
+		{"update duplicate_candidate set status = 'merged', reviewed_at = now() where id::text = $1", []interface{}{candidateId}, nil},
+	}
+	for _, step := range steps {
+		tag, execErr := tx.Exec(ctx, step.query, step.args...)
+		if execErr != nil {
+			rbErr := tx.Rollback(ctx)
+			if rbErr != nil {
+				log.Errorf("failed to roll back tx in MergeDuplicate: %v", rbErr)
+				return nil, echo.NewHTTPError(http.StatusInternalServerError, rbErr)
+			}
+			log.Errorf("failed to merge duplicate: %v", execErr)
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, execErr)
+		}
+		if step.affected != nil {
+			*step.affected = tag.RowsAffected()
+		}
+	}
+	return res, nil
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"excel-service/internal/models"
+	"time"
 
 	"github.com/jackc/pgx/v4"
 )
@@ -23,6 +24,70 @@ type ExcelRepository interface {
 	SetUploadStatus(ctx context.Context, uploadId string, status string) error
 	SaveBanks(ctx context.Context, bik, name, cor_account, address string, tx pgx.Tx) error
 	NewErrorNomenclatureId(ctx context.Context, row_id int, fileName string) error
-	NewUploadCatalogue(ctx context.Context, fileNameDisc, fileNameDl, uploadedBy, companyId string, fileSize int64) error
+	NewUploadCatalogue(ctx context.Context, fileNameDisc, fileNameDl, uploadedBy, companyId string, fileSize int64) (string, error)
 	GetFromUploadCatalogue(ctx context.Context, id string) ([]*models.UploadsEntity, error)
+	LockUploadStatus(ctx context.Context, uploadId string, tx pgx.Tx) (string, error)
+	NewDirectUpload(ctx context.Context, companyId string) (string, error)
+	SetUploadStatusTx(ctx context.Context, uploadId, status string, tx pgx.Tx) error
+	DeleteUploadData(ctx context.Context, uploadId string, tx pgx.Tx) (*models.UploadRollback, error)
+	NewPriceListVersion(ctx context.Context, priceId, uploadId string, items []*models.PriceListItem) (*models.PriceListVersion, error)
+	SelectPriceListVersions(ctx context.Context, priceId string) ([]*models.PriceListVersion, error)
+	SelectPriceListItems(ctx context.Context, priceId string, version int) ([]*models.PriceListItem, error)
+	SavePriceTiers(ctx context.Context, nomenclatureId string, tiers []*models.PriceTier) error
+	SelectPriceTiers(ctx context.Context, nomenclatureId string) ([]*models.PriceTier, error)
+	SelectNomenclaturePrice(ctx context.Context, nomenclatureId string) (float64, string, error)
+	SaveCurrencyRates(ctx context.Context, rates []*models.CurrencyRate) error
+	SelectCurrencyRate(ctx context.Context, code string, date time.Time) (*models.CurrencyRate, error)
+	SelectCurrencyCodes(ctx context.Context) (map[string]bool, error)
+	SelectExtractionRules(ctx context.Context) ([]*models.ExtractionRule, error)
+	SaveNomenclatureStandards(ctx context.Context, nomenclatureId string, standards []*models.Standard) error
+	SelectNomenclatureByStandard(ctx context.Context, code string) ([]*models.StandardNomenclature, error)
+	SaveClassifierCodes(ctx context.Context, kind string, codes []*models.ClassifierCode) error
+	HasClassifierCodes(ctx context.Context, kind string) (bool, error)
+	ClassifierCodeExists(ctx context.Context, kind, code string) (bool, error)
+	SelectClassifierPath(ctx context.Context, kind, code string) ([]*models.ClassifierCode, error)
+	NewUploadRowErrors(ctx context.Context, rowErrs []*models.UploadRowError) error
+	SelectUploadRowErrors(ctx context.Context, uploadId string) ([]*models.UploadRowError, error)
+	SelectCategorizedNomenclature(ctx context.Context, unclassified string, limit int) ([]*models.CategorySample, error)
+	SelectUnclassifiedNomenclature(ctx context.Context, unclassified string, limit int) ([]*models.CategorySample, error)
+	SaveCategorySuggestions(ctx context.Context, suggestions []*models.CategorySuggestion) error
+	SelectCategorySuggestions(ctx context.Context, status string) ([]*models.CategorySuggestion, error)
+	ReviewCategorySuggestions(ctx context.Context, accept, reject []string, tx pgx.Tx) (*models.CategoryReview, error)
+	SelectCategories(ctx context.Context) ([]*models.CategoryNode, error)
+	UpsertCategory(ctx context.Context, node *models.CategoryNode, tx pgx.Tx) (string, bool, error)
+	SelectUploadMatchItems(ctx context.Context, uploadId string) ([]*models.MatchItem, error)
+	SelectDuplicateCandidates(ctx context.Context, item *models.MatchItem, uploadId string, limit int) ([]*models.MatchItem, error)
+	SaveDuplicateCandidates(ctx context.Context, candidates []*models.DuplicateCandidate) error
+	SelectDuplicateQueue(ctx context.Context, status string) ([]*models.DuplicateCandidate, error)
+	ReviewDuplicates(ctx context.Context, confirm, reject []string) (*models.DuplicateReview, error)
+	MergeDuplicate(ctx context.Context, candidateId string, tx pgx.Tx) (*models.DuplicateMerge, error)
+	SelectUnlinkedSupplierItems(ctx context.Context, uploadId string, limit, offset int) ([]*models.MatchItem, error)
+	SelectMtrCandidates(ctx context.Context, item *models.MatchItem, limit int) ([]*models.MatchItem, error)
+	DeleteStaleMtrLinks(ctx context.Context) (int64, error)
+	SaveMtrLinks(ctx context.Context, links []*models.MtrLink) error
+	SetManualMtrLink(ctx context.Context, nomenclatureId, mtrId string) (*models.MtrLink, error)
+	SelectManufacturerAliases(ctx context.Context) (map[string]string, error)
+	SelectManufacturers(ctx context.Context) ([]*models.Manufacturer, error)
+	UpsertManufacturer(ctx context.Context, m *models.Manufacturer, aliases []string, tx pgx.Tx) (string, error)
+	QueueUnresolvedManufacturers(ctx context.Context, uploadId string, queue []*models.UnresolvedManufacturer) error
+	SelectUnresolvedManufacturers(ctx context.Context) ([]*models.UnresolvedManufacturer, error)
+	LockUnresolvedManufacturers(ctx context.Context, keys []string, tx pgx.Tx) ([]string, error)
+	ResolveManufacturerKey(ctx context.Context, key, manufacturerId string, tx pgx.Tx) (int64, error)
+	SelectCountries(ctx context.Context) ([]*models.Country, error)
+	SaveCountries(ctx context.Context, countries []*models.Country) error
+	SelectCountrySummary(ctx context.Context) ([]*models.CountrySummary, error)
+	SelectUserPrincipal(ctx context.Context, userId string) (*models.Principal, error)
+	SelectUploadCompany(ctx context.Context, uploadId string) (string, error)
+	SelectPriceListCompanies(ctx context.Context, priceId string) ([]string, error)
+	SelectCompanyIdByName(ctx context.Context, name string) (string, error)
+	StartHookRun(ctx context.Context, key, uploadId string, staleAfter time.Duration) (*models.HookRun, bool, error)
+	SelectHookRun(ctx context.Context, key string) (*models.HookRun, error)
+	SelectDoneHookRun(ctx context.Context, uploadId string) (*models.HookRun, error)
+	TouchHookRun(ctx context.Context, key string) error
+	FinishHookRun(ctx context.Context, run *models.HookRun) error
+	DeleteUploadRowErrors(ctx context.Context, uploadId string) error
+	SelectUploadColumnMapping(ctx context.Context, uploadId string) (*models.ColumnMapping, error)
+	SaveUploadColumnMapping(ctx context.Context, m *models.ColumnMapping) error
+	SelectColumnTemplates(ctx context.Context, companyId string) ([]*models.ColumnTemplate, error)
+	SaveColumnTemplate(ctx context.Context, t *models.ColumnTemplate) error
 }
@@ -21,7 +21,8 @@ type ExcelRepositoryImpl struct {
 func (e ExcelRepositoryImpl) GetFromUploadCatalogue(ctx context.Context, id string) ([]*models.UploadsEntity, error) {
 	rows, err := e.lb.CallPrimaryPreferred().PGxPool().Query(
 		ctx,
-		"select u.company, df.filename_disk, du.id from uploads u join uploads_files uf on uf.uploads_id = u.id join directus_files df on df.id = uf.directus_files_id join directus_users du on du.company = u.company where u.id = $1",
+		"select u.company, df.filename_disk, coalesce(df.uploaded_by::text, '') "+
+			"from uploads u join uploads_files uf on uf.uploads_id = u.id join directus_files df on df.id = uf.directus_files_id where u.id = $1",
 		id,
 	)
 	if err != nil {
@@ -34,7 +35,7 @@ func (e ExcelRepositoryImpl) GetFromUploadCatalogue(ctx context.Context, id stri
 		uploadEntity := &models.UploadsEntity{}
 		scErr := rows.Scan(&uploadEntity.CompanyId, &uploadEntity.FileId, &uploadEntity.UserId)
 		if scErr != nil {
-			log.Errorf("failed to scan object in GetFromUploadCatalogue: ", scErr)
+			log.Errorf("failed to scan object in GetFromUploadCatalogue: %v", scErr)
 			return nil, echo.NewHTTPError(http.StatusInternalServerError, scErr)
 		}
 		uploads = append(uploads, uploadEntity)
@@ -42,17 +43,20 @@ func (e ExcelRepositoryImpl) GetFromUploadCatalogue(ctx context.Context, id stri
 	return uploads, nil
 }
 
-func (e ExcelRepositoryImpl) NewUploadCatalogue(ctx context.Context, fileNameDisc, fileNameDl, uploadedBy, companyId string, fileSize int64) error {
-	_, err := e.lb.CallPrimaryPreferred().PGxPool().Exec(
+// NewUploadCatalogue registers the stored file as a new upload of the
+// company, uploaded by the user, and returns the id of the upload.
+func (e ExcelRepositoryImpl) NewUploadCatalogue(ctx context.Context, fileNameDisc, fileNameDl, uploadedBy, companyId string, fileSize int64) (string, error) {
+	var uploadId string
+	err := e.lb.CallPrimaryPreferred().PGxPool().QueryRow(
 		ctx,
-		"with ins as (insert into directus_files (id, storage, filename_disk, filename_download, type, uploaded_by, filesize) values (uuid_generate_v4(), 's3', $1, $2, $3, (select id from directus_users where first_name = $4), $5) returning id), upins as (insert into uploads (id, status, created_at , company) values (uuid_generate_v4(), $6, now(),  (select id from company where name = $7)) returning id) insert into uploads_files (uploads_id, directus_files_id) values ((select id from upins), (select id from ins))",
+		"with ins as (insert into directus_files (id, storage, filename_disk, filename_download, type, uploaded_by, filesize) values (uuid_generate_v4(), 's3', $1, $2, $3, $4, $5) returning id), upins as (insert into uploads (id, status, created_at , company) values (uuid_generate_v4(), $6, now(),  (select id from company where name = $7)) returning id) insert into uploads_files (uploads_id, directus_files_id) values ((select id from upins), (select id from ins)) returning uploads_id::text",
 		fileNameDisc, fileNameDl, "application/vnd.ms-excel", uploadedBy, fileSize, "wait_for_processing", companyId,
-	)
+	).Scan(&uploadId)
 	if err != nil {
 		log.Error("failed to exec in NewUploadCatalogue: ", err)
-		return echo.NewHTTPError(http.StatusInternalServerError, err)
+		return "", echo.NewHTTPError(http.StatusInternalServerError, err)
 	}
-	return nil
+	return uploadId, nil
 }
 func (e ExcelRepositoryImpl) NewErrorNomenclatureId(ctx context.Context, row_id int, fileName string) error {
 	_, err := e.lb.CallPrimaryPreferred().PGxPool().Exec(
@@ -118,18 +122,25 @@ func (e ExcelRepositoryImpl) SelectCompanyInnById(ctx context.Context, companyId
 }
 
 func (e ExcelRepositoryImpl) SelectPriceListsByUploadId(ctx context.Context, uploadId string) ([]string, error) {
-	var ids []string
-	var id string //todo change to multiple ids
-	err := e.lb.CallPrimaryPreferred().PGxPool().QueryRow(
+	rows, err := e.lb.CallPrimaryPreferred().PGxPool().Query(
 		ctx,
 		"select price_id from uploads_price where uploads_id = $1",
 		uploadId,
-	).Scan(&id)
-	ids = append(ids, id)
+	)
 	if err != nil {
-		log.Errorf("failed to find inn by id: %v", err)
-		var res []string
-		return res, echo.NewHTTPError(http.StatusInternalServerError, err)
+		log.Errorf("failed to select price lists by upload id: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if scErr := rows.Scan(&id); scErr != nil {
+			log.Errorf("failed to scan price list id: %v", scErr)
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, scErr)
+		}
+		ids = append(ids, id)
 	}
 
 	return ids, nil
@@ -176,7 +187,7 @@ func (e ExcelRepositoryImpl) CheckCategory(ctx context.Context, catName string,
 
 	tx.QueryRow(
 		ctx,
-		"select count(id) from category where name = $1",
+		"select count(id) from category where name = $1 and parent is null",
 		catName,
 	).Scan(&count)
 	//if execErr != nil {
@@ -222,7 +233,7 @@ func (e ExcelRepositoryImpl) NewParentCategory(ctx context.Context, cat string,
 func (e ExcelRepositoryImpl) NewChildCategory(ctx context.Context, cat *models.Category, tx pgx.Tx) error {
 	_, execErr := tx.Exec(
 		ctx,
-		"insert into category(name, code, type, parent) values ($1, $2, $3, select id from category where name = $4)",
+		"insert into category(name, code, type, parent) values ($1, $2, $3, (select id from category where name = $4 and parent is null))",
 		cat.Name, cat.Code, "amto", cat.ParentName,
 	)
 
@@ -251,11 +262,10 @@ func (e ExcelRepositoryImpl) SaveNomenclature(ctx context.Context, nomenclature
 			ctx,
 			//"insert into nomenclature (id, code_skmtr, code_ks_nsi, code_amto, okpd2, code_tnved, name, tmc_code_vendor, tmc_mark, date_of_manufacture, manufacturer, is_tax, tax_percentage, price_per_unit, measurement, price_valid_through, wholesale_price_per_unit, wholesale_order_from, wholesale_order_to, quantity, product_availability, hazard_class, packaging_type, packing_material, storage_type, weight_netto, weight_brutto, loading_type, warehouse_address, regions, delivery_type) values " +
 			//	"($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, (select id from measurement where name = $15), $16, $17, $18, $19, $20, $21, (select id from hazard_class where name = $22), (select id from packaging_type where name = $23), (select id from packing_material  where name = $24), (select id from storage_type where name = $25), $26, $27, (select id from loading_type  where name = $28), $29,(select id from regions where name = $30), (select id from delivery_type where name = $31)) returning id",
-			"with nom as (insert into nomenclature (id, payload, drawing_name, category, company, currency, owner_role, code_skmtr, code_ks_nsi, code_amto, okpd2, code_tnved, name, tmc_code_vendor, tmc_mark, gost_tu, date_of_manufacture, manufacturer, batch_number, is_tax, tax_percentage, price_per_unit, measurement, price_valid_through, wholesale_items, quantity, product_availability,  loading_type, regions, delivery_type) "+
-				"values ($1, $38, $39, (select id from category where name = $40),  $41, (select id from currency where code = 'RUB'), (select role from directus_users where id = $42), $2, $3, $4, (select id from okpd2 where code = $5), $6, $7, $8, $9, $10,  $11, $12, $13,  $14, $15, $16, (select id from measurement where value = $17), $18, $19, $20, $21, (select id from loading_type  where name = $22), (select id from regions where name = $23), (select id from delivery_type where name = $24)) returning id), "+
-				"with catnom as(insert into catalogue_nomenclature(nomenclature_id, catalog_id) values ("+
-				"package as (insert into package(id, packaging_type, packing_material, name, storage_type, hazard_class, length, height, width, volume,  weight_brutto, weight_netto, amount_in_package, company) "+
-				"values ($25, (select id from packaging_type where name = $26), (select id from packing_material  where name = $27), $28, (select id from storage_type  where name = $29), (select id from hazard_class where name = $30), $31, $32, $33, $34, $35, $36, $37, $41) returning id) insert into nomenclature_package ( nomenclature_id, package_id) values ((select id from nom), (select id from package))",
+			"with nom as (insert into nomenclature (id, payload, drawing_name, category, company, currency, owner_role, code_skmtr, code_ks_nsi, code_amto, okpd2, code_tnved, name, tmc_code_vendor, tmc_mark, gost_tu, date_of_manufacture, manufacturer, batch_number, is_tax, tax_percentage, price_per_unit, measurement, price_valid_through, wholesale_items, quantity, product_availability,  loading_type, regions, delivery_type, upload, price_includes_vat, price_net, vat_amount, price_gross, attributes, source, manufacturer_id, country_code) "+
+				"values ($1, $38, $39, (select id from category where id::text = $40),  $41, (select id from currency where code = $44), (select role from directus_users where id = $42), $2, $3, $4, (select id from okpd2 where code = $5), $6, $7, $8, $9, $10,  $11, $12, $13,  $14, $15, $16, (select id from measurement where value = $17), $18, $19, $20, $21, (select id from loading_type  where name = $22), (select id from regions where name = $23), (select id from delivery_type where name = $24), $43, $45, $46, $47, $48, $49, $50, $51::uuid, $52) returning id), "+
+				"package as (insert into package(id, packaging_type, packing_material, name, storage_type, hazard_class, length, height, width, volume,  weight_brutto, weight_netto, amount_in_package, company, upload) "+
+				"values ($25, (select id from packaging_type where name = $26), (select id from packing_material  where name = $27), $28, (select id from storage_type  where name = $29), (select id from hazard_class where name = $30), $31, $32, $33, $34, $35, $36, $37, $41, $43) returning id) insert into nomenclature_package ( nomenclature_id, package_id) values ((select id from nom), (select id from package))",
 			nomenclature.Id,
 			newNullString(nomenclature.CodeSkmtr),
 			newNullString(nomenclature.CodeKsNsi),
@@ -292,12 +302,22 @@ func (e ExcelRepositoryImpl) SaveNomenclature(ctx context.Context, nomenclature
 			newNullFloat(nomenclature.Volume),
 			newNullFloat(nomenclature.WeightBrutto),
 			newNullFloat(nomenclature.WeightNetto),
-			newNullInt(int(nomenclature.AmountInPackage)),
+			newNullInt(nomenclature.AmountInPackage),
 			nomenclature.Payload,
 			nomenclature.DrawingName,
-			nomenclature.CategoryName,
+			newNullString(nomenclature.CategoryId),
 			companyId,
 			userId,
+			newNullString(nomenclature.UploadId),
+			currencyCode(nomenclature.Currency),
+			nomenclature.PriceIncludesVat,
+			newNullFloat(nomenclature.PriceNet),
+			newNullFloat(nomenclature.VatAmount),
+			newNullFloat(nomenclature.PriceGross),
+			nomenclature.Attributes,
+			newNullString(nomenclature.Source),
+			newNullString(nomenclature.ManufacturerId),
+			newNullString(nomenclature.CountryCode),
 		)
 
 		if execErr != nil {
@@ -344,8 +364,8 @@ func (e ExcelRepositoryImpl) SaveNomenclature(ctx context.Context, nomenclature
 			ctx,
 			//"insert into nomenclature (id, code_skmtr, code_ks_nsi, code_amto, okpd2, code_tnved, name, tmc_code_vendor, tmc_mark, date_of_manufacture, manufacturer, is_tax, tax_percentage, price_per_unit, measurement, price_valid_through, wholesale_price_per_unit, wholesale_order_from, wholesale_order_to, quantity, product_availability, hazard_class, packaging_type, packing_material, storage_type, weight_netto, weight_brutto, loading_type, warehouse_address, regions, delivery_type) values " +
 			//	"($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, (select id from measurement where name = $15), $16, $17, $18, $19, $20, $21, (select id from hazard_class where name = $22), (select id from packaging_type where name = $23), (select id from packing_material  where name = $24), (select id from storage_type where name = $25), $26, $27, (select id from loading_type  where name = $28), $29,(select id from regions where name = $30), (select id from delivery_type where name = $31)) returning id",
-			"insert into nomenclature (id, payload, drawing_name, category, company, currency, owner_role, code_skmtr, code_ks_nsi, code_amto, okpd2, code_tnved, name, tmc_code_vendor, tmc_mark, gost_tu, date_of_manufacture, manufacturer, batch_number, is_tax, tax_percentage, price_per_unit, measurement, price_valid_through, wholesale_items, quantity, product_availability,  loading_type, regions, delivery_type) "+
-				"values ($1, $25, $26, (select id from category where name = $27),  $28, (select id from currency where code = 'RUB'), (select role from directus_users where id = $29), $2, $3, $4, (select id from okpd2 where code = $5), $6, $7, $8, $9, $10,  $11, $12, $13,  $14, $15, $16, (select id from measurement where value = $17), $18, $19, $20, $21, (select id from loading_type  where name = $22), (select id from regions where name = $23), (select id from delivery_type where name = $24))",
+			"insert into nomenclature (id, payload, drawing_name, category, company, currency, owner_role, code_skmtr, code_ks_nsi, code_amto, okpd2, code_tnved, name, tmc_code_vendor, tmc_mark, gost_tu, date_of_manufacture, manufacturer, batch_number, is_tax, tax_percentage, price_per_unit, measurement, price_valid_through, wholesale_items, quantity, product_availability,  loading_type, regions, delivery_type, upload, price_includes_vat, price_net, vat_amount, price_gross, attributes, source, manufacturer_id, country_code) "+
+				"values ($1, $25, $26, (select id from category where id::text = $27),  $28, (select id from currency where code = $31), (select role from directus_users where id = $29), $2, $3, $4, (select id from okpd2 where code = $5), $6, $7, $8, $9, $10,  $11, $12, $13,  $14, $15, $16, (select id from measurement where value = $17), $18, $19, $20, $21, (select id from loading_type  where name = $22), (select id from regions where name = $23), (select id from delivery_type where name = $24), $30, $32, $33, $34, $35, $36, $37, $38::uuid, $39)",
 			nomenclature.Id,
 			newNullString(nomenclature.CodeSkmtr),
 			newNullString(nomenclature.CodeKsNsi),
@@ -372,9 +392,19 @@ func (e ExcelRepositoryImpl) SaveNomenclature(ctx context.Context, nomenclature
 			nomenclature.DeliveryType,
 			nomenclature.OrganizerNomenclature,
 			nomenclature.DrawingName,
-			nomenclature.CategoryName,
+			newNullString(nomenclature.CategoryId),
 			companyId,
 			userId,
+			newNullString(nomenclature.UploadId),
+			currencyCode(nomenclature.Currency),
+			nomenclature.PriceIncludesVat,
+			newNullFloat(nomenclature.PriceNet),
+			newNullFloat(nomenclature.VatAmount),
+			newNullFloat(nomenclature.PriceGross),
+			nomenclature.Attributes,
+			newNullString(nomenclature.Source),
+			newNullString(nomenclature.ManufacturerId),
+			newNullString(nomenclature.CountryCode),
 		)
 
 		if execErr != nil {
@@ -390,7 +420,7 @@ func (e ExcelRepositoryImpl) SaveNomenclature(ctx context.Context, nomenclature
 		fmt.Println("insert into db success")
 		return nil
 	}
-	_, execErr := e.lb.CallPrimaryPreferred().PGxPool().Query(
+	_, execErr := e.lb.CallPrimaryPreferred().PGxPool().Exec(
 		ctx,
 		//"insert into nomenclature (id, code_skmtr, code_ks_nsi, code_amto, okpd2, code_tnved, name, tmc_code_vendor, tmc_mark, date_of_manufacture, manufacturer, is_tax, tax_percentage, price_per_unit, measurement, price_valid_through, wholesale_price_per_unit, wholesale_order_from, wholesale_order_to, quantity, product_availability, hazard_class, packaging_type, packing_material, storage_type, weight_netto, weight_brutto, loading_type, warehouse_address, regions, delivery_type) values " +
 		//	"($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, (select id from measurement where name = $15), $16, $17, $18, $19, $20, $21, (select id from hazard_class where name = $22), (select id from packaging_type where name = $23), (select id from packing_material  where name = $24), (select id from storage_type where name = $25), $26, $27, (select id from loading_type  where name = $28), $29,(select id from regions where name = $30), (select id from delivery_type where name = $31)) returning id",
@@ -424,14 +454,23 @@ func (e ExcelRepositoryImpl) SaveNomenclature(ctx context.Context, nomenclature
 			"product_availability,  "+
 			"loading_type, "+
 			"regions, "+
-			"delivery_type) "+
+			"delivery_type, "+
+			"upload, "+
+			"price_includes_vat, "+
+			"price_net, "+
+			"vat_amount, "+
+			"price_gross, "+
+			"attributes, "+
+			"source, "+
+			"manufacturer_id, "+
+			"country_code) "+
 			"values ("+
 			"$1, "+ //nomenclature.Id
 			"$25, "+
 			"$26, "+
-			"(select id from category where name = $27),"+
+			"(select id from category where id::text = $27),"+
 			"$28, "+
-			"(select id from currency where code = 'RUB'), "+
+			"(select id from currency where code = $31), "+
 			"(select role from directus_users where id = $29), "+
 			"$2, "+ // nomenclature.CodeSkmtr
 			"$3, "+ // nomenclature.CodeKsNsi
@@ -455,7 +494,16 @@ func (e ExcelRepositoryImpl) SaveNomenclature(ctx context.Context, nomenclature
 			"$21, "+ // nomenclature.ProductAvailability
 			"(select id from loading_type  where name = $22), "+ // nomenclature.LoadingType
 			"(select id from regions where name = $23), "+
-			"(select id from delivery_type where name = $24))"+
+			"(select id from delivery_type where name = $24), "+
+			"$30, "+ // nomenclature.UploadId
+			"$32, "+ // nomenclature.PriceIncludesVat
+			"$33, "+ // nomenclature.PriceNet
+			"$34, "+ // nomenclature.VatAmount
+			"$35, "+ // nomenclature.PriceGross
+			"$36, "+ // nomenclature.Attributes
+			"$37, "+ // nomenclature.Source
+			"$38::uuid, "+ // nomenclature.ManufacturerId
+			"$39) "+ // nomenclature.CountryCode
 			"returning id",
 		nomenclature.Id,
 		newNullString(nomenclature.CodeSkmtr),
@@ -483,9 +531,19 @@ func (e ExcelRepositoryImpl) SaveNomenclature(ctx context.Context, nomenclature
 		nomenclature.DeliveryType,
 		nomenclature.Payload,
 		nomenclature.DrawingName,
-		nomenclature.CategoryName,
+		newNullString(nomenclature.CategoryId),
 		companyId,
 		userId,
+		newNullString(nomenclature.UploadId),
+		currencyCode(nomenclature.Currency),
+		nomenclature.PriceIncludesVat,
+		newNullFloat(nomenclature.PriceNet),
+		newNullFloat(nomenclature.VatAmount),
+		newNullFloat(nomenclature.PriceGross),
+		nomenclature.Attributes,
+		newNullString(nomenclature.Source),
+		newNullString(nomenclature.ManufacturerId),
+		newNullString(nomenclature.CountryCode),
 	)
 
 	if execErr != nil {
@@ -579,6 +637,14 @@ func newNullString(s string) *sql.NullString {
 	}
 }
 
+// currencyCode falls back to rubles for rows without an explicit currency.
+func currencyCode(code string) string {
+	if code == "" {
+		return "RUB"
+	}
+	return code
+}
+
 func newNullFloat(f float32) *sql.NullFloat64 {
 	if f == 0 {
 		return &sql.NullFloat64{}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+	"excel-service/internal/models"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+func (e ExcelRepositoryImpl) SelectExtractionRules(ctx context.Context) ([]*models.ExtractionRule, error) {
+	rows, err := e.lb.CallPrimaryPreferred().PGxPool().Query(
+		ctx,
+		"select id, position, pattern, field, coalesce(unit, ''), active from extraction_rule where active order by position",
+	)
+	if err != nil {
+		log.Errorf("failed to select extraction rules: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	defer rows.Close()
+
+	var rules []*models.ExtractionRule
+	for rows.Next() {
+		rule := &models.ExtractionRule{}
+		if scanErr := rows.Scan(&rule.Id, &rule.Position, &rule.Pattern, &rule.Field, &rule.Unit, &rule.Active); scanErr != nil {
+			log.Errorf("failed to scan extraction rule: %v", scanErr)
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, scanErr)
+		}
+		rules = append(rules, rule)
+	}
+	if rows.Err() != nil {
+		log.Errorf("failed to read extraction rules: %v", rows.Err())
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, rows.Err())
+	}
+	return rules, nil
+}
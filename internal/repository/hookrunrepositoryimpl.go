@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"context"
+	"excel-service/internal/models"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+// uniqueViolation is the SQLSTATE of a unique constraint violation.
+const uniqueViolation = "23505"
+
+const hookRunColumns = "key, upload::text, status, attempts, response, coalesce(error, ''), coalesce(error_code, 0), coalesce(error_type, ''), started_at, finished_at"
+
+// StartHookRun claims the upload for a run under the key, one run of an
+// upload runs at a time. Running runs whose heartbeat is older than
+// staleAfter were abandoned by a stopped process and are failed first. The
+// key is claimed when it is new or its last run failed. Otherwise the run
+// of the key, or the run the upload is busy with, is returned with started
+// false.
+func (e ExcelRepositoryImpl) StartHookRun(ctx context.Context, key, uploadId string, staleAfter time.Duration) (*models.HookRun, bool, error) {
+	_, err := e.lb.CallPrimaryPreferred().PGxPool().Exec(
+		ctx,
+		"update hook_run set status = 'failed', error = 'abandoned', error_code = 500, finished_at = now() "+
+			"where upload = $1 and status = 'running' and coalesce(heartbeat_at, started_at) < now() - make_interval(secs => $2)",
+		uploadId, staleAfter.Seconds(),
+	)
+	if err != nil {
+		log.Errorf("failed to fail abandoned hook runs: %v", err)
+		return nil, false, echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	run, err := e.scanHookRun(e.lb.CallPrimaryPreferred().PGxPool().QueryRow(
+		ctx,
+		"insert into hook_run (key, upload, status, heartbeat_at) values ($1, $2, 'running', now()) "+
+			"on conflict (key) do update set status = 'running', attempts = hook_run.attempts + 1, started_at = now(), heartbeat_at = now(), "+
+			"finished_at = null, response = null, error = null, error_code = null, error_type = null "+
+			"where hook_run.status = 'failed' "+
+			"returning "+hookRunColumns,
+		key, uploadId,
+	))
+	if err == nil {
+		return run, true, nil
+	}
+	// the upload already has a running run under another key
+	if state, ok := err.(interface{ SQLState() string }); ok && state.SQLState() == uniqueViolation {
+		run, err = e.scanHookRun(e.lb.CallPrimaryPreferred().PGxPool().QueryRow(
+			ctx,
+			"select "+hookRunColumns+" from hook_run where upload = $1 and status = 'running'",
+			uploadId,
+		))
+		if err == nil {
+			return run, false, nil
+		}
+		if err == pgx.ErrNoRows {
+			log.Warnf("running hook run of upload %s finished meanwhile", uploadId)
+			return nil, false, echo.NewHTTPError(http.StatusConflict, "upload is being processed")
+		}
+	}
+	if err != pgx.ErrNoRows {
+		log.Errorf("failed to start hook run: %v", err)
+		return nil, false, echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	run, err = e.SelectHookRun(ctx, key)
+	return run, false, err
+}
+
+// TouchHookRun refreshes the heartbeat of a running run.
+func (e ExcelRepositoryImpl) TouchHookRun(ctx context.Context, key string) error {
+	_, err := e.lb.CallPrimaryPreferred().PGxPool().Exec(ctx, "update hook_run set heartbeat_at = now() where key = $1 and status = 'running'", key)
+	if err != nil {
+		log.Errorf("failed to touch hook run: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	return nil
+}
+
+func (e ExcelRepositoryImpl) SelectHookRun(ctx context.Context, key string) (*models.HookRun, error) {
+	run, err := e.scanHookRun(e.lb.CallPrimaryPreferred().PGxPool().QueryRow(
+		ctx,
+		"select "+hookRunColumns+" from hook_run where key = $1",
+		key,
+	))
+	if err == pgx.ErrNoRows {
+		log.Warnf("hook run %s not found", key)
+		return nil, echo.NewHTTPError(http.StatusNotFound, "hook run not found")
+	}
+	if err != nil {
+		log.Errorf("failed to select hook run: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	return run, nil
+}
+
+// SelectDoneHookRun returns the last run that imported the upload, nil
+// when none did.
+func (e ExcelRepositoryImpl) SelectDoneHookRun(ctx context.Context, uploadId string) (*models.HookRun, error) {
+	run, err := e.scanHookRun(e.lb.CallPrimaryPreferred().PGxPool().QueryRow(
+		ctx,
+		"select "+hookRunColumns+" from hook_run where upload = $1 and status = 'done' order by finished_at desc limit 1",
+		uploadId,
+	))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		log.Errorf("failed to select done hook run: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	return run, nil
+}
+
+func (e ExcelRepositoryImpl) scanHookRun(row pgx.Row) (*models.HookRun, error) {
+	run := &models.HookRun{}
+	err := row.Scan(&run.Key, &run.UploadId, &run.Status, &run.Attempts, &run.Response, &run.Error, &run.ErrorCode, &run.ErrorType, &run.StartedAt, &run.FinishedAt)
+	if err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// FinishHookRun stores the outcome of the run, the response of a finished
+// one or the error of a failed one.
+func (e ExcelRepositoryImpl) FinishHookRun(ctx context.Context, run *models.HookRun) error {
+	_, err := e.lb.CallPrimaryPreferred().PGxPool().Exec(
+		ctx,
+		"update hook_run set status = $2, response = $3, error = $4, error_code = $5, error_type = $6, finished_at = now() where key = $1",
+		run.Key, run.Status, run.Response, newNullString(run.Error), newNullInt(run.ErrorCode), newNullString(run.ErrorType),
+	)
+	if err != nil {
+		log.Errorf("failed to finish hook run: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	return nil
+}
+
+func (e ExcelRepositoryImpl) DeleteUploadRowErrors(ctx context.Context, uploadId string) error {
+	_, err := e.lb.CallPrimaryPreferred().PGxPool().Exec(ctx, "delete from upload_row_error where upload = $1", uploadId)
+	if err != nil {
+		log.Errorf("failed to delete upload row errors: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	return nil
+}
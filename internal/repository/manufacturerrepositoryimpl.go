@@ -0,0 +1,229 @@
+package repository
+
+import (
+	"context"
+	"excel-service/internal/models"
+	"net/http"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+// SelectManufacturerAliases maps every alias key to its manufacturer id.
+func (e ExcelRepositoryImpl) SelectManufacturerAliases(ctx context.Context) (map[string]string, error) {
+	rows, err := e.lb.CallPrimaryPreferred().PGxPool().Query(ctx, "select alias, manufacturer_id::text from manufacturer_alias")
+	if err != nil {
+		log.Errorf("failed to select manufacturer aliases: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	defer rows.Close()
+
+	aliases := map[string]string{}
+	for rows.Next() {
+		var alias, id string
+		if scanErr := rows.Scan(&alias, &id); scanErr != nil {
+			log.Errorf("failed to scan manufacturer alias: %v", scanErr)
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, scanErr)
+		}
+		aliases[alias] = id
+	}
+	if rows.Err() != nil {
+		log.Errorf("failed to read manufacturer aliases: %v", rows.Err())
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, rows.Err())
+	}
+	return aliases, nil
+}
+
+func (e ExcelRepositoryImpl) SelectManufacturers(ctx context.Context) ([]*models.Manufacturer, error) {
+	rows, err := e.lb.CallPrimaryPreferred().PGxPool().Query(
+		ctx,
+		"select m.id::text, m.name, coalesce(m.inn, ''), coalesce(m.country, ''), "+
+			"coalesce(array_agg(a.alias order by a.alias) filter (where a.alias is not null), '{}') "+
+			"from manufacturer m left join manufacturer_alias a on a.manufacturer_id = m.id "+
+			"group by m.id order by m.name",
+	)
+	if err != nil {
+		log.Errorf("failed to select manufacturers: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	defer rows.Close()
+
+	manufacturers := []*models.Manufacturer{}
+	for rows.Next() {
+		m := &models.Manufacturer{}
+		if scanErr := rows.Scan(&m.Id, &m.Name, &m.Inn, &m.Country, &m.Aliases); scanErr != nil {
+			log.Errorf("failed to scan manufacturer: %v", scanErr)
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, scanErr)
+		}
+		manufacturers = append(manufacturers, m)
+	}
+	if rows.Err() != nil {
+		log.Errorf("failed to read manufacturers: %v", rows.Err())
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, rows.Err())
+	}
+	return manufacturers, nil
+}
+
+// UpsertManufacturer saves the manufacturer by name and points the alias
+// keys at it. Known INN and country are kept when the new row has none.
+func (e ExcelRepositoryImpl) UpsertManufacturer(ctx context.Context, m *models.Manufacturer, aliases []string, tx pgx.Tx) (string, error) {
+	var id string
+	err := tx.QueryRow(
+		ctx,
+		"insert into manufacturer (name, inn, country) values ($1, $2, $3) "+
+			"on conflict (name) do update set inn = coalesce(excluded.inn, manufacturer.inn), country = coalesce(excluded.country, manufacturer.country) "+
+			"returning id::text",
+		m.Name, newNullString(m.Inn), newNullString(m.Country),
+	).Scan(&id)
+	if err != nil {
+		return "", e.rollbackManufacturer(ctx, tx, err)
+	}
+
+	for _, alias := range aliases {
+		_, aliasErr := tx.Exec(
+			ctx,
+			"insert into manufacturer_alias (alias, manufacturer_id) values ($1, $2) "+
+				"on conflict (alias) do update set manufacturer_id = excluded.manufacturer_id",
+			alias, id,
+		)
+		if aliasErr != nil {
+			return "", e.rollbackManufacturer(ctx, tx, aliasErr)
+		}
+	}
+	return id, nil
+}
+
+// QueueUnresolvedManufacturers adds import strings to the curation queue,
+// spellings of a known key are merged into it. The state of a key before the
+// upload is kept as its pre-image, so a rollback can restore it.
+func (e ExcelRepositoryImpl) QueueUnresolvedManufacturers(ctx context.Context, uploadId string, queue []*models.UnresolvedManufacturer) error {
+	batch := &pgx.Batch{}
+	for _, item := range queue {
+		if uploadId != "" {
+			batch.Queue(
+				"insert into upload_pre_image (upload, table_name, row_key, row) "+
+					"values ($1, 'manufacturer_unresolved', $2, (select to_jsonb(m) from manufacturer_unresolved m where m.key = $2)) "+
+					"on conflict (upload, table_name, row_key) do nothing",
+				uploadId, item.Key,
+			)
+		}
+		batch.Queue(
+			"insert into manufacturer_unresolved (key, raw_values, occurrences, last_seen) values ($1, $2, $3, $4) "+
+				"on conflict (key) do update set "+
+				"raw_values = array(select distinct unnest(manufacturer_unresolved.raw_values || excluded.raw_values)), "+
+				"occurrences = manufacturer_unresolved.occurrences + excluded.occurrences, last_seen = excluded.last_seen",
+			item.Key, item.Values, item.Occurrences, item.LastSeen,
+		)
+	}
+
+	if bErr := e.lb.CallPrimaryPreferred().PGxPool().SendBatch(ctx, batch).Close(); bErr != nil {
+		log.Errorf("failed to queue unresolved manufacturers: %v", bErr)
+		return echo.NewHTTPError(http.StatusInternalServerError, bErr)
+	}
+	return nil
+}
+
+func (e ExcelRepositoryImpl) SelectUnresolvedManufacturers(ctx context.Context) ([]*models.UnresolvedManufacturer, error) {
+	rows, err := e.lb.CallPrimaryPreferred().PGxPool().Query(
+		ctx,
+		"select key, raw_values, occurrences, last_seen from manufacturer_unresolved order by occurrences desc, key",
+	)
+	if err != nil {
+		log.Errorf("failed to select unresolved manufacturers: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	defer rows.Close()
+
+	queue := []*models.UnresolvedManufacturer{}
+	for rows.Next() {
+		item := &models.UnresolvedManufacturer{}
+		if scanErr := rows.Scan(&item.Key, &item.Values, &item.Occurrences, &item.LastSeen); scanErr != nil {
+			log.Errorf("failed to scan unresolved manufacturer: %v", scanErr)
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, scanErr)
+		}
+		queue = append(queue, item)
+	}
+	if rows.Err() != nil {
+		log.Errorf("failed to read unresolved manufacturers: %v", rows.Err())
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, rows.Err())
+	}
+	return queue, nil
+}
+
+// LockUnresolvedManufacturers returns the queued keys among keys and locks
+// them for the tx. Keys locked by a concurrent resolve are skipped, it
+// links them itself.
+func (e ExcelRepositoryImpl) LockUnresolvedManufacturers(ctx context.Context, keys []string, tx pgx.Tx) ([]string, error) {
+	rows, err := tx.Query(ctx, "select key from manufacturer_unresolved where key = any($1) order by key for update skip locked", keys)
+	if err != nil {
+		return nil, e.rollbackManufacturer(ctx, tx, err)
+	}
+	defer rows.Close()
+
+	var queued []string
+	for rows.Next() {
+		var key string
+		if scanErr := rows.Scan(&key); scanErr != nil {
+			rows.Close()
+			return nil, e.rollbackManufacturer(ctx, tx, scanErr)
+		}
+		queued = append(queued, key)
+	}
+	if rows.Err() != nil {
+		return nil, e.rollbackManufacturer(ctx, tx, rows.Err())
+	}
+	return queued, nil
+}
+
+// ResolveManufacturerKey makes the queued key an alias of the manufacturer,
+// links the items saved with its spellings and drops it from the queue.
+// It returns the number of items linked.
+func (e ExcelRepositoryImpl) ResolveManufacturerKey(ctx context.Context, key, manufacturerId string, tx pgx.Tx) (int64, error) {
+	tag, err := tx.Exec(
+		ctx,
+		"insert into manufacturer_alias (alias, manufacturer_id) select $1, id from manufacturer where id::text = $2 "+
+			"on conflict (alias) do update set manufacturer_id = excluded.manufacturer_id",
+		key, manufacturerId,
+	)
+	if err != nil {
+		return 0, e.rollbackManufacturer(ctx, tx, err)
+	}
+	if tag.RowsAffected() == 0 {
+		tx.Rollback(ctx)
+		log.Warnf("manufacturer %s not found", manufacturerId)
+		return 0, echo.NewHTTPError(http.StatusNotFound, "manufacturer not found")
+	}
+
+	var raw []string
+	err = tx.QueryRow(ctx, "delete from manufacturer_unresolved where key = $1 returning raw_values", key).Scan(&raw)
+	if err == pgx.ErrNoRows {
+		tx.Rollback(ctx)
+		log.Warnf("unresolved manufacturer %s not found", key)
+		return 0, echo.NewHTTPError(http.StatusNotFound, "unresolved manufacturer not found")
+	}
+	if err != nil {
+		return 0, e.rollbackManufacturer(ctx, tx, err)
+	}
+
+	tag, err = tx.Exec(
+		ctx,
+		"update nomenclature set manufacturer_id = (select id from manufacturer where id::text = $1) "+
+			"where manufacturer_id is null and btrim(manufacturer) = any($2)",
+		manufacturerId, raw,
+	)
+	if err != nil {
+		return 0, e.rollbackManufacturer(ctx, tx, err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (e ExcelRepositoryImpl) rollbackManufacturer(ctx context.Context, tx pgx.Tx, err error) error {
+	rbErr := tx.Rollback(ctx)
+	if rbErr != nil {
+		log.Errorf("failed to roll back manufacturer tx: %v", rbErr)
+		return echo.NewHTTPError(http.StatusInternalServerError, rbErr)
+	}
+	log.Errorf("failed to save manufacturer: %v", err)
+	return echo.NewHTTPError(http.StatusInternalServerError, err)
+}
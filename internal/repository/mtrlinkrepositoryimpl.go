@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"excel-service/internal/models"
+	"net/http"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+// SelectUnlinkedSupplierItems returns supplier items without an MTR link,
+// of one upload or of the whole catalogue when uploadId is empty.
+func (e ExcelRepositoryImpl) SelectUnlinkedSupplierItems(ctx context.Context, uploadId string, limit, offset int) ([]*models.MatchItem, error) {
+	items, err := e.selectMatchItems(
+		ctx,
+		"select "+matchItemColumns+" from nomenclature n "+
+			"where n.source = 'supplier' and n.merged_into is null and ($1::uuid is null or n.upload = $1::uuid) "+
+			"and not exists (select 1 from mtr_link l where l.nomenclature_id = n.id) "+
+			"order by n.name, n.id limit $2 offset $3",
+		newNullString(uploadId), limit, offset,
+	)
+	if items == nil && err == nil {
+		items = []*models.MatchItem{}
+	}
+	return items, err
+}
+
+// organizerCatalogueColumns reads an organizer_catalogue row as a match
+// item: vendor_code holds the SKMTR code, code the KS NSI code.
+const organizerCatalogueColumns = "c.id::text, coalesce(c.name, ''), coalesce(c.vendor_code, ''), coalesce(c.code, ''), coalesce(c.sl_amto, ''), coalesce(c.manufacturer, ''), coalesce(c.sl_mark_tmc, ''), coalesce(c.sl_state_standard, ''), coalesce(c.sl_draw, '')"
+
+// SelectMtrCandidates picks organizer catalogue references sharing a code or
+// a similar name with the supplier item.
+func (e ExcelRepositoryImpl) SelectMtrCandidates(ctx context.Context, item *models.MatchItem, limit int) ([]*models.MatchItem, error) {
+	return e.selectMatchItems(
+		ctx,
+		"select "+organizerCatalogueColumns+" from organizer_catalogue c "+
+			"where ($1 <> '' and c.vendor_code = $1) or ($2 <> '' and c.sl_amto = $2) or ($3 <> '' and c.code = $3) "+
+			"or c.name % $4 "+
+			"order by similarity(c.name, $4) desc limit $5",
+		item.CodeSkmtr, item.CodeAmto, item.CodeKsNsi, item.Name, limit,
+	)
+}
+
+// DeleteStaleMtrLinks drops the links whose organizer catalogue row is
+// gone, the catalogue is kept in Directus and has no foreign key to them.
+func (e ExcelRepositoryImpl) DeleteStaleMtrLinks(ctx context.Context) (int64, error) {
+	tag, err := e.lb.CallPrimaryPreferred().PGxPool().Exec(
+		ctx,
+		"delete from mtr_link l where not exists (select 1 from organizer_catalogue c where c.id::text = l.mtr_id)",
+	)
+	if err != nil {
+		log.Errorf("failed to delete stale mtr links: %v", err)
+		return 0, echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// SaveMtrLinks keeps manual links, automatic ones are replaced.
+func (e ExcelRepositoryImpl) SaveMtrLinks(ctx context.Context, links []*models.MtrLink) error {
+	batch := &pgx.Batch{}
+	for _, link := range links {
+		batch.Queue(
+			"insert into mtr_link (nomenclature_id, mtr_id, confidence, method) values ($1, $2, $3, $4) "+
+				"on conflict (nomenclature_id) do update set mtr_id = excluded.mtr_id, confidence = excluded.confidence, method = excluded.method, created_at = now() "+
+				"where mtr_link.method <> 'manual'",
+			link.NomenclatureId, link.MtrId, link.Confidence, link.Method,
+		)
+	}
+
+	if bErr := e.lb.CallPrimaryPreferred().PGxPool().SendBatch(ctx, batch).Close(); bErr != nil {
+		log.Errorf("failed to save mtr links: %v", bErr)
+		return echo.NewHTTPError(http.StatusInternalServerError, bErr)
+	}
+	return nil
+}
+
+// SetManualMtrLink links the supplier item to the organizer catalogue
+// reference chosen by a manager, both have to exist.
+func (e ExcelRepositoryImpl) SetManualMtrLink(ctx context.Context, nomenclatureId, mtrId string) (*models.MtrLink, error) {
+	link := &models.MtrLink{NomenclatureId: nomenclatureId, MtrId: mtrId, Confidence: 1, Method: "manual"}
+	tag, err := e.lb.CallPrimaryPreferred().PGxPool().Exec(
+		ctx,
+		"insert into mtr_link (nomenclature_id, mtr_id, confidence, method) "+
+			"select s.id, c.id::text, $3, $4 from nomenclature s, organizer_catalogue c "+
+			"where s.id::text = $1 and s.source = 'supplier' and c.id::text = $2 "+
+			"on conflict (nomenclature_id) do update set mtr_id = excluded.mtr_id, confidence = excluded.confidence, method = excluded.method, created_at = now()",
+		nomenclatureId, mtrId, link.Confidence, link.Method,
+	)
+	if err != nil {
+		log.Errorf("failed to save manual mtr link: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	if tag.RowsAffected() == 0 {
+		log.Warnf("supplier item %s or mtr %s not found", nomenclatureId, mtrId)
+		return nil, echo.NewHTTPError(http.StatusNotFound, "supplier item or MTR reference not found")
+	}
+	return link, nil
+}
@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"context"
+	"excel-service/internal/models"
+	"net/http"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+// NewPriceListVersion stores the items of one price list upload as the next
+// version of that price list. Concurrent imports of the same price list
+// wait for each other on a transaction lock of the price id, so max(version)
+// is read by one of them at a time. The price list table belongs to Directus
+// and is not locked itself.
+func (e ExcelRepositoryImpl) NewPriceListVersion(ctx context.Context, priceId, uploadId string, items []*models.PriceListItem) (*models.PriceListVersion, error) {
+	tx, txErr := e.lb.CallPrimaryPreferred().PGxPool().Begin(ctx)
+	if txErr != nil {
+		log.Errorf("failed to begin tx: %v", txErr)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, txErr)
+	}
+
+	if _, lockErr := tx.Exec(ctx, "select pg_advisory_xact_lock(hashtext('price_list_version:' || $1))", priceId); lockErr != nil {
+		rbErr := tx.Rollback(ctx)
+		if rbErr != nil {
+			log.Errorf("failed to roll back tx in NewPriceListVersion: %v", rbErr)
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, rbErr)
+		}
+		log.Errorf("failed to lock price list %s: %v", priceId, lockErr)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, lockErr)
+	}
+
+	version := &models.PriceListVersion{PriceId: priceId, UploadId: uploadId, Items: len(items)}
+	err := tx.QueryRow(
+		ctx,
+		"insert into price_list_version (id, price_id, upload, version, created_at) "+
+			"values (uuid_generate_v4(), $1, $2, (select coalesce(max(version), 0) + 1 from price_list_version where price_id = $1), now()) "+
+			"returning id, version, created_at",
+		priceId, newNullString(uploadId),
+	).Scan(&version.Id, &version.Version, &version.CreatedAt)
+	if err != nil {
+		rbErr := tx.Rollback(ctx)
+		if rbErr != nil {
+			log.Errorf("failed to roll back tx in NewPriceListVersion: %v", rbErr)
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, rbErr)
+		}
+		log.Errorf("failed to insert price list version: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	batch := &pgx.Batch{}
+	for _, item := range items {
+		batch.Queue(
+			"insert into price_list_version_item (version_id, item_key, nomenclature_id, name, price_per_unit, currency, wholesale_items, price_tiers) values ($1, $2, $3, $4, $5, $6, $7, $8)",
+			version.Id, item.ItemKey, newNullString(item.NomenclatureId), item.Name, newNullFloat(item.PricePerUnit), currencyCode(item.Currency), item.WholesaleItems, item.PriceTiers,
+		)
+	}
+
+	if bErr := tx.SendBatch(ctx, batch).Close(); bErr != nil {
+		rbErr := tx.Rollback(ctx)
+		if rbErr != nil {
+			log.Errorf("failed to roll back tx in NewPriceListVersion: %v", rbErr)
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, rbErr)
+		}
+		log.Errorf("failed to insert price list version items: %v", bErr)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, bErr)
+	}
+
+	if cErr := tx.Commit(ctx); cErr != nil {
+		log.Errorf("failed to commit tx in NewPriceListVersion: %v", cErr)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, cErr)
+	}
+
+	return version, nil
+}
+
+func (e ExcelRepositoryImpl) SelectPriceListVersions(ctx context.Context, priceId string) ([]*models.PriceListVersion, error) {
+	rows, err := e.lb.CallPrimaryPreferred().PGxPool().Query(
+		ctx,
+		"select v.id, v.price_id, coalesce(v.upload::text, ''), v.version, v.created_at, count(i.item_key) "+
+			"from price_list_version v left join price_list_version_item i on i.version_id = v.id "+
+			"where v.price_id = $1 group by v.id order by v.version",
+		priceId,
+	)
+	if err != nil {
+		log.Errorf("failed to select price list versions: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	defer rows.Close()
+
+	var versions []*models.PriceListVersion
+	for rows.Next() {
+		version := &models.PriceListVersion{}
+		scErr := rows.Scan(&version.Id, &version.PriceId, &version.UploadId, &version.Version, &version.CreatedAt, &version.Items)
+		if scErr != nil {
+			log.Errorf("failed to scan price list version: %v", scErr)
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, scErr)
+		}
+		versions = append(versions, version)
+	}
+	return versions, nil
+}
+
+func (e ExcelRepositoryImpl) SelectPriceListItems(ctx context.Context, priceId string, version int) ([]*models.PriceListItem, error) {
+	rows, err := e.lb.CallPrimaryPreferred().PGxPool().Query(
+		ctx,
+		"select i.item_key, coalesce(i.nomenclature_id::text, ''), coalesce(i.name, ''), coalesce(i.price_per_unit, 0)::float4, coalesce(i.currency, 'RUB'), i.wholesale_items, i.price_tiers "+
+			"from price_list_version_item i join price_list_version v on v.id = i.version_id "+
+			"where v.price_id = $1 and v.version = $2 order by i.item_key",
+		priceId, version,
+	)
+	if err != nil {
+		log.Errorf("failed to select price list items: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	defer rows.Close()
+
+	var items []*models.PriceListItem
+	for rows.Next() {
+		item := &models.PriceListItem{}
+		scErr := rows.Scan(&item.ItemKey, &item.NomenclatureId, &item.Name, &item.PricePerUnit, &item.Currency, &item.WholesaleItems, &item.PriceTiers)
+		if scErr != nil {
+			log.Errorf("failed to scan price list item: %v", scErr)
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, scErr)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (e ExcelRepositoryImpl) SavePriceTiers(ctx context.Context, nomenclatureId string, tiers []*models.PriceTier) error {
+	batch := &pgx.Batch{}
+	for _, tier := range tiers {
+		batch.Queue(
+			"insert into nomenclature_price_tier (id, nomenclature_id, quantity_from, quantity_to, price) values (uuid_generate_v4(), $1, $2, $3, $4)",
+			nomenclatureId, tier.QuantityFrom, tier.QuantityTo, tier.Price,
+		)
+	}
+
+	if bErr := e.lb.CallPrimaryPreferred().PGxPool().SendBatch(ctx, batch).Close(); bErr != nil {
+		log.Errorf("failed to insert price tiers: %v", bErr)
+		return echo.NewHTTPError(http.StatusInternalServerError, bErr)
+	}
+	return nil
+}
+
+func (e ExcelRepositoryImpl) SelectPriceTiers(ctx context.Context, nomenclatureId string) ([]*models.PriceTier, error) {
+	rows, err := e.lb.CallPrimaryPreferred().PGxPool().Query(
+		ctx,
+		"select quantity_from::float8, quantity_to::float8, price::float8 from nomenclature_price_tier where nomenclature_id = $1 order by quantity_from",
+		nomenclatureId,
+	)
+	if err != nil {
+		log.Errorf("failed to select price tiers: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	defer rows.Close()
+
+	var tiers []*models.PriceTier
+	for rows.Next() {
+		tier := &models.PriceTier{}
+		if scErr := rows.Scan(&tier.QuantityFrom, &tier.QuantityTo, &tier.Price); scErr != nil {
+			log.Errorf("failed to scan price tier: %v", scErr)
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, scErr)
+		}
+		tiers = append(tiers, tier)
+	}
+	return tiers, nil
+}
+
+func (e ExcelRepositoryImpl) SelectNomenclaturePrice(ctx context.Context, nomenclatureId string) (float64, string, error) {
+	var price float64
+	var currency *string
+	err := e.lb.CallPrimaryPreferred().PGxPool().QueryRow(
+		ctx,
+		"select coalesce(n.price_per_unit, 0)::float8, c.code from nomenclature n left join currency c on c.id = n.currency where n.id = $1",
+		nomenclatureId,
+	).Scan(&price, &currency)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, "", echo.NewHTTPError(http.StatusNotFound, "nomenclature not found")
+		}
+		log.Errorf("failed to select nomenclature price: %v", err)
+		return 0, "", echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	// a price without a currency is not taken for rubles
+	if currency == nil {
+		log.Warnf("nomenclature %s has no currency", nomenclatureId)
+		return 0, "", echo.NewHTTPError(http.StatusUnprocessableEntity, "nomenclature price has no currency")
+	}
+	return price, *currency, nil
+}
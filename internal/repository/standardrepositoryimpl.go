@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"excel-service/internal/models"
+	"net/http"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+// SaveNomenclatureStandards adds unknown standards to the dictionary and
+// links the nomenclature to all of them.
+func (e ExcelRepositoryImpl) SaveNomenclatureStandards(ctx context.Context, nomenclatureId string, standards []*models.Standard) error {
+	batch := &pgx.Batch{}
+	for _, standard := range standards {
+		batch.Queue(
+			"insert into standard (kind, number, code) values ($1, $2, $3) on conflict (code) do nothing",
+			standard.Kind, standard.Number, standard.Code,
+		)
+		batch.Queue(
+			"insert into nomenclature_standard (nomenclature_id, standard_id) select $1, id from standard where code = $2 on conflict do nothing",
+			nomenclatureId, standard.Code,
+		)
+	}
+
+	if bErr := e.lb.CallPrimaryPreferred().PGxPool().SendBatch(ctx, batch).Close(); bErr != nil {
+		log.Errorf("failed to save nomenclature standards: %v", bErr)
+		return echo.NewHTTPError(http.StatusInternalServerError, bErr)
+	}
+	return nil
+}
+
+// SelectNomenclatureByStandard matches the code exactly or, when the code
+// has no year, every edition of the standard: "ГОСТ 8732" finds "ГОСТ 8732-78".
+func (e ExcelRepositoryImpl) SelectNomenclatureByStandard(ctx context.Context, code string) ([]*models.StandardNomenclature, error) {
+	rows, err := e.lb.CallPrimaryPreferred().PGxPool().Query(
+		ctx,
+		"select n.id, n.name, s.code from standard s "+
+			"join nomenclature_standard ns on ns.standard_id = s.id "+
+			"join nomenclature n on n.id = ns.nomenclature_id "+
+			"where s.code = $1 or s.code like $1 || '-%' order by s.code, n.name",
+		code,
+	)
+	if err != nil {
+		log.Errorf("failed to select nomenclature by standard: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	defer rows.Close()
+
+	res := []*models.StandardNomenclature{}
+	for rows.Next() {
+		item := &models.StandardNomenclature{}
+		if scanErr := rows.Scan(&item.NomenclatureId, &item.Name, &item.Standard); scanErr != nil {
+			log.Errorf("failed to scan nomenclature by standard: %v", scanErr)
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, scanErr)
+		}
+		res = append(res, item)
+	}
+	if rows.Err() != nil {
+		log.Errorf("failed to read nomenclature by standard: %v", rows.Err())
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, rows.Err())
+	}
+	return res, nil
+}
@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	"excel-service/internal/models"
+	"net/http"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+// LockUploadStatus returns the status of the upload and locks its row
+// until tx ends, an import cannot change the status meanwhile.
+func (e ExcelRepositoryImpl) LockUploadStatus(ctx context.Context, uploadId string, tx pgx.Tx) (string, error) {
+	var status string
+	err := tx.QueryRow(ctx, "select status from uploads where id = $1 for update", uploadId).Scan(&status)
+	if err != nil {
+		if rbErr := tx.Rollback(ctx); rbErr != nil {
+			log.Errorf("failed to roll back tx in LockUploadStatus: %v", rbErr)
+		}
+		if err == pgx.ErrNoRows {
+			log.Warnf("upload %s not found", uploadId)
+			return "", echo.NewHTTPError(http.StatusNotFound, "upload not found")
+		}
+		log.Errorf("failed to lock upload status: %v", err)
+		return "", echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	return status, nil
+}
+
+// NewDirectUpload records an upload for a file imported directly, without a
+// Directus upload, so its rows can be tagged and rolled back.
+func (e ExcelRepositoryImpl) NewDirectUpload(ctx context.Context, companyId string) (string, error) {
+	var id string
+	err := e.lb.CallPrimaryPreferred().PGxPool().QueryRow(
+		ctx,
+		"insert into uploads (id, status, created_at, company) values (uuid_generate_v4(), 'processing', now(), $1) returning id::text",
+		newNullString(companyId),
+	).Scan(&id)
+	if err != nil {
+		log.Errorf("failed to insert direct upload: %v", err)
+		return "", echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	return id, nil
+}
+
+// SetUploadStatusTx is SetUploadStatus within the transaction.
+func (e ExcelRepositoryImpl) SetUploadStatusTx(ctx context.Context, uploadId, status string, tx pgx.Tx) error {
+	if _, err := tx.Exec(ctx, "update uploads set status = $1 where id = $2", status, uploadId); err != nil {
+		log.Errorf("failed to update upload status: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	return nil
+}
+
+// DeleteUploadData removes every row SaveNomenclature created for the upload
+// and restores the rows it changed from their pre-images. Merges of its rows
+// with other rows are undone, the price links they moved go back. Links are
+// deleted before the rows they point to.
+func (e ExcelRepositoryImpl) DeleteUploadData(ctx context.Context, uploadId string, tx pgx.Tx) (*models.UploadRollback, error) {
+	res := &models.UploadRollback{UploadId: uploadId}
+
+	var packageLinks, created, images, movedLinks, unmergedRows int64
+	steps := []struct {
+		query    string
+		affected *int64
+	}{
+		// merges of the upload rows are undone first, the links a merge
+		// moved go back to the merged row one by one
+		{"update price_nomenclature p set nomenclature_id = m.merged_id " +
+			"from nomenclature_merge m, unnest(m.price_ids) moved(price_id) " +
+			"where (m.merged_id in (select id from nomenclature where upload = $1) or m.master_id in (select id from nomenclature where upload = $1)) " +
+			"and p.ctid = (select q.ctid from price_nomenclature q where q.nomenclature_id = m.master_id and q.price_id::text = moved.price_id limit 1)", &movedLinks},
+		{"update nomenclature n set merged_into = null from nomenclature_merge m " +
+			"where (m.merged_id in (select id from nomenclature where upload = $1) or m.master_id in (select id from nomenclature where upload = $1)) " +
+			"and n.id = m.merged_id and n.merged_into = m.master_id", &unmergedRows},
+		{"delete from nomenclature_merge where merged_id in (select id from nomenclature where upload = $1) or master_id in (select id from nomenclature where upload = $1)", &res.Unmerged},
+		{"delete from manufacturer_unresolved m using upload_pre_image i " +
+			"where i.upload = $1 and i.table_name = 'manufacturer_unresolved' and i.row is null and m.key = i.row_key", &created},
+		{"update manufacturer_unresolved m set raw_values = p.raw_values, occurrences = p.occurrences, last_seen = p.last_seen " +
+			"from upload_pre_image i, jsonb_populate_record(null::manufacturer_unresolved, i.row) p " +
+			"where i.upload = $1 and i.table_name = 'manufacturer_unresolved' and i.row is not null and m.key = i.row_key", &res.Restored},
+		{"delete from upload_pre_image where upload = $1", &images},
+		{"delete from price_list_version where upload = $1", &res.PriceVersions},
+		{"delete from price_nomenclature where nomenclature_id in (select id from nomenclature where upload = $1)", &res.PriceLinks},
+		{"delete from nomenclature_package where nomenclature_id in (select id from nomenclature where upload = $1) or package_id in (select id from package where upload = $1)", &packageLinks},
+		{"delete from package where upload = $1", &res.Packages},
+		{"delete from nomenclature where upload = $1", &res.Nomenclature},
+	}
+
+	for _, step := range steps {
+		tag, execErr := tx.Exec(ctx, step.query, uploadId)
+		if execErr != nil {
+			rbErr := tx.Rollback(ctx)
+			if rbErr != nil {
+				log.Errorf("failed to roll back tx in DeleteUploadData: %v", rbErr)
+				return nil, echo.NewHTTPError(http.StatusInternalServerError, rbErr)
+			}
+			log.Errorf("failed to delete upload data: %v", execErr)
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, execErr)
+		}
+		*step.affected = tag.RowsAffected()
+	}
+
+	return res, nil
+}
+
+func (e ExcelRepositoryImpl) NewUploadRowErrors(ctx context.Context, rowErrs []*models.UploadRowError) error {
+	batch := &pgx.Batch{}
+	for _, rowErr := range rowErrs {
+		batch.Queue(
+			"insert into upload_row_error (upload, file_name, sheet, row_number, field, value, code, message) values ($1, $2, $3, $4, $5, $6, $7, $8)",
+			newNullString(rowErr.UploadId), rowErr.FileName, newNullString(rowErr.Sheet), rowErr.Row, rowErr.Field, rowErr.Value, newNullString(rowErr.Code), rowErr.Message,
+		)
+	}
+
+	if bErr := e.lb.CallPrimaryPreferred().PGxPool().SendBatch(ctx, batch).Close(); bErr != nil {
+		log.Errorf("failed to save upload row errors: %v", bErr)
+		return echo.NewHTTPError(http.StatusInternalServerError, bErr)
+	}
+	return nil
+}
+
+func (e ExcelRepositoryImpl) SelectUploadRowErrors(ctx context.Context, uploadId string) ([]*models.UploadRowError, error) {
+	rows, err := e.lb.CallPrimaryPreferred().PGxPool().Query(
+		ctx,
+		"select file_name, coalesce(sheet, ''), row_number, field, coalesce(value, ''), coalesce(code, ''), message from upload_row_error where upload = $1 order by sheet nulls first, row_number, id",
+		uploadId,
+	)
+	if err != nil {
+		log.Errorf("failed to select upload row errors: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	defer rows.Close()
+
+	res := []*models.UploadRowError{}
+	for rows.Next() {
+		rowErr := &models.UploadRowError{UploadId: uploadId}
+		if scanErr := rows.Scan(&rowErr.FileName, &rowErr.Sheet, &rowErr.Row, &rowErr.Field, &rowErr.Value, &rowErr.Code, &rowErr.Message); scanErr != nil {
+			log.Errorf("failed to scan upload row error: %v", scanErr)
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, scanErr)
+		}
+		res = append(res, rowErr)
+	}
+	if rows.Err() != nil {
+		log.Errorf("failed to read upload row errors: %v", rows.Err())
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, rows.Err())
+	}
+	return res, nil
+}
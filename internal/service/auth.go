@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"excel-service/internal/auth"
+	"excel-service/internal/models"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+// Authenticate resolves a bearer token to the caller. Static service tokens
+// are checked first, anything else has to be a Directus access token of an
+// active user.
+func (e ExcelServiceImpl) Authenticate(ctx context.Context, token string) (*models.Principal, error) {
+	if token == "" {
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, "missing bearer token")
+	}
+	for serviceToken, name := range e.cfg.Auth.ServiceTokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(serviceToken)) == 1 {
+			return &models.Principal{Service: name, Admin: true}, nil
+		}
+	}
+
+	if e.cfg.Auth.JwtSecret == "" {
+		log.Errorf("AUTH_JWT_SECRET is not set, user tokens are rejected")
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, "invalid token")
+	}
+	claims, err := auth.ParseHS256(token, []byte(e.cfg.Auth.JwtSecret), time.Now())
+	if err != nil {
+		log.Warnf("rejected token: %v", err)
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, "invalid token")
+	}
+
+	p, pErr := e.repo.SelectUserPrincipal(ctx, claims.Id)
+	if pErr != nil {
+		return nil, pErr
+	}
+	p.Admin = p.Admin || claims.AdminAccess
+	for _, role := range e.cfg.Auth.AdminRoles {
+		if p.Role == role {
+			p.Admin = true
+		}
+	}
+	return p, nil
+}
+
+// authorizeCompany lets admins, service clients and members of the company
+// act on its data.
+func authorizeCompany(ctx context.Context, companyId string) error {
+	p := auth.PrincipalFromContext(ctx)
+	if p == nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "not authenticated")
+	}
+	if p.Admin || (companyId != "" && p.CompanyId == companyId) {
+		return nil
+	}
+	log.Warnf("user %s of company %s is denied access to company %s", p.UserId, p.CompanyId, companyId)
+	return echo.NewHTTPError(http.StatusForbidden, "no access to the company")
+}
+
+func (e ExcelServiceImpl) authorizeUpload(ctx context.Context, uploadId string) error {
+	companyId, err := e.repo.SelectUploadCompany(ctx, uploadId)
+	if err != nil {
+		return err
+	}
+	return authorizeCompany(ctx, companyId)
+}
+
+// authorizePriceList lets the caller read a price list when it has access to
+// every company whose uploads fill it.
+func (e ExcelServiceImpl) authorizePriceList(ctx context.Context, priceId string) error {
+	companies, err := e.repo.SelectPriceListCompanies(ctx, priceId)
+	if err != nil {
+		return err
+	}
+	for _, companyId := range companies {
+		if authErr := authorizeCompany(ctx, companyId); authErr != nil {
+			return authErr
+		}
+	}
+	return nil
+}
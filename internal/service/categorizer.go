@@ -0,0 +1,295 @@
+package service
+
+import (
+	"context"
+	"excel-service/internal/models"
+	"excel-service/internal/repository"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+// unclassifiedCategory is the name of the existing catch-all category. It is
+// spelled with a Latin "H" in the DB, so the spelling is kept.
+const unclassifiedCategory = "He классифицированные"
+
+const (
+	// minCategoryConfidence is the lowest cosine similarity between an item
+	// and a category centroid that is still worth showing to a manager.
+	minCategoryConfidence = 0.25
+	categoryTrainLimit    = 50000
+	categorySuggestLimit  = 5000
+	categoryModelTTL      = time.Hour
+
+	suggestionPending  = "pending"
+	suggestionAccepted = "accepted"
+	suggestionRejected = "rejected"
+)
+
+// categoryModel is a TF-IDF nearest centroid classifier. Every category is
+// the normalized sum of the vectors of its items, an item goes to the
+// category with the closest centroid. Centroids are keyed by category id,
+// names repeat under different parents.
+type categoryModel struct {
+	idf       map[string]float64
+	centroids map[string]map[string]float64
+	names     map[string]string
+}
+
+// categoryModels keeps the trained model between imports, training reads up
+// to categoryTrainLimit items. Category changes of this instance drop it,
+// other instances pick them up after categoryModelTTL.
+var categoryModels = &categoryModelCache{}
+
+type categoryModelCache struct {
+	mu       sync.Mutex
+	model    *categoryModel
+	loadedAt time.Time
+}
+
+// categoryFeatures are the stemmed words of the name, the OKPD2 class, group
+// and subgroup and the manufacturer.
+func categoryFeatures(name, okpd2, manufacturer string) []string {
+	var features []string
+	for _, word := range tokenize(name) {
+		if isNumber(word) {
+			continue
+		}
+		features = append(features, stem(word))
+	}
+	if code, ok := normalizeOkpd2(okpd2); ok {
+		for _, n := range []int{2, 5, 8} {
+			if len(code) >= n {
+				features = append(features, "okpd2:"+code[:n])
+			}
+		}
+	}
+	if m := normalizeText(manufacturer); m != "" {
+		features = append(features, "mfr:"+m)
+	}
+	return features
+}
+
+func trainCategoryModel(samples []*models.CategorySample) *categoryModel {
+	m := &categoryModel{idf: map[string]float64{}, centroids: map[string]map[string]float64{}, names: map[string]string{}}
+
+	docs := make([][]string, len(samples))
+	df := map[string]int{}
+	for i, sample := range samples {
+		docs[i] = categoryFeatures(sample.Name, sample.Okpd2, sample.Manufacturer)
+		seen := map[string]bool{}
+		for _, f := range docs[i] {
+			if !seen[f] {
+				seen[f] = true
+				df[f]++
+			}
+		}
+	}
+	for f, n := range df {
+		m.idf[f] = math.Log(float64(len(samples)+1)/float64(n+1)) + 1
+	}
+
+	for i, sample := range samples {
+		centroid := m.centroids[sample.CategoryId]
+		if centroid == nil {
+			centroid = map[string]float64{}
+			m.centroids[sample.CategoryId] = centroid
+			m.names[sample.CategoryId] = sample.Category
+		}
+		for f, w := range m.vector(docs[i]) {
+			centroid[f] += w
+		}
+	}
+	for _, centroid := range m.centroids {
+		normalizeVector(centroid)
+	}
+	return m
+}
+
+func (m *categoryModel) vector(features []string) map[string]float64 {
+	v := map[string]float64{}
+	for _, f := range features {
+		if idf, ok := m.idf[f]; ok {
+			v[f] += idf
+		}
+	}
+	normalizeVector(v)
+	return v
+}
+
+func normalizeVector(v map[string]float64) {
+	var sum float64
+	for _, w := range v {
+		sum += w * w
+	}
+	if sum == 0 {
+		return
+	}
+	norm := math.Sqrt(sum)
+	for f := range v {
+		v[f] /= norm
+	}
+}
+
+// Suggest returns the id of the closest category and the cosine similarity
+// to it as confidence, an empty id when nothing is close enough.
+func (m *categoryModel) Suggest(name, okpd2, manufacturer string) (string, float64) {
+	v := m.vector(categoryFeatures(name, okpd2, manufacturer))
+	best, confidence := "", 0.0
+	for category, centroid := range m.centroids {
+		var dot float64
+		for f, w := range v {
+			dot += w * centroid[f]
+		}
+		if dot > confidence || (dot == confidence && category < best) {
+			best, confidence = category, dot
+		}
+	}
+	if confidence < minCategoryConfidence {
+		return "", confidence
+	}
+	return best, math.Round(confidence*1000) / 1000
+}
+
+// suggestion makes a suggestion of the category for the item.
+func (m *categoryModel) suggestion(nomenclatureId, categoryId string, confidence float64) *models.CategorySuggestion {
+	return &models.CategorySuggestion{NomenclatureId: nomenclatureId, CategoryId: categoryId, Category: m.names[categoryId], Confidence: confidence}
+}
+
+// loadCategoryModel returns the cached model, it is trained again when it is
+// missing or older than categoryModelTTL. nil means there is nothing to learn
+// from yet.
+func loadCategoryModel(ctx context.Context, repo repository.ExcelRepository) (*categoryModel, error) {
+	c := categoryModels
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.model != nil && time.Since(c.loadedAt) < categoryModelTTL {
+		return c.model, nil
+	}
+	model, err := trainCategoryModelFrom(ctx, repo)
+	if err != nil || model == nil {
+		return nil, err
+	}
+	c.model, c.loadedAt = model, time.Now()
+	return model, nil
+}
+
+// invalidateCategoryModel drops the cached model after categories or the
+// categories of items changed.
+func invalidateCategoryModel() {
+	categoryModels.mu.Lock()
+	categoryModels.model = nil
+	categoryModels.mu.Unlock()
+}
+
+func trainCategoryModelFrom(ctx context.Context, repo repository.ExcelRepository) (*categoryModel, error) {
+	samples, err := repo.SelectCategorizedNomenclature(ctx, unclassifiedCategory, categoryTrainLimit)
+	if err != nil {
+		return nil, err
+	}
+	if len(samples) == 0 {
+		log.Warn("no categorized nomenclature to train the category model")
+		return nil, nil
+	}
+	return trainCategoryModel(samples), nil
+}
+
+// suggestCategory stores a suggestion for an item saved without a category
+// or as unclassified, also when its category value matched nothing. The item
+// keeps its category until a manager accepts it. The hint is the product
+// group the item was listed under and counts as part of its name.
+func suggestCategory(ctx context.Context, repo repository.ExcelRepository, model *categoryModel, n *models.Nomenclature, hint string) {
+	if model == nil || (n.CategoryId != "" && n.CategoryName != unclassifiedCategory) {
+		return
+	}
+	categoryId, confidence := model.Suggest(n.Name+" "+hint, n.OKPD2, n.Manufacturer)
+	if categoryId == "" {
+		return
+	}
+	suggestion := model.suggestion(n.Id, categoryId, confidence)
+	if err := repo.SaveCategorySuggestions(ctx, []*models.CategorySuggestion{suggestion}); err != nil {
+		log.Errorf("failed to save category suggestion for %s: %v", n.Id, err)
+	}
+}
+
+// SuggestCategories makes suggestions for unclassified nomenclature that has
+// no pending suggestion yet.
+func (e ExcelServiceImpl) SuggestCategories(ctx context.Context) (*models.CategorySuggestionRun, error) {
+	model, err := loadCategoryModel(ctx, e.repo)
+	if err != nil {
+		return nil, err
+	}
+	if model == nil {
+		return nil, echo.NewHTTPError(http.StatusConflict, "нет номенклатуры с категориями для обучения")
+	}
+
+	items, itemsErr := e.repo.SelectUnclassifiedNomenclature(ctx, unclassifiedCategory, categorySuggestLimit)
+	if itemsErr != nil {
+		return nil, itemsErr
+	}
+
+	var suggestions []*models.CategorySuggestion
+	for _, item := range items {
+		categoryId, confidence := model.Suggest(item.Name, item.Okpd2, item.Manufacturer)
+		if categoryId == "" {
+			continue
+		}
+		suggestions = append(suggestions, model.suggestion(item.NomenclatureId, categoryId, confidence))
+	}
+	if len(suggestions) > 0 {
+		if saveErr := e.repo.SaveCategorySuggestions(ctx, suggestions); saveErr != nil {
+			return nil, saveErr
+		}
+	}
+
+	return &models.CategorySuggestionRun{Checked: len(items), Suggested: len(suggestions)}, nil
+}
+
+func (e ExcelServiceImpl) GetCategorySuggestions(ctx context.Context, status string) ([]*models.CategorySuggestion, error) {
+	if status == "" {
+		status = suggestionPending
+	}
+	switch status {
+	case suggestionPending, suggestionAccepted, suggestionRejected:
+	default:
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "status must be pending, accepted or rejected")
+	}
+	return e.repo.SelectCategorySuggestions(ctx, status)
+}
+
+func (e ExcelServiceImpl) ReviewCategorySuggestions(ctx context.Context, req *models.CategoryReviewReq) (*models.CategoryReview, error) {
+	if len(req.Accept) == 0 && len(req.Reject) == 0 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "nothing to accept or reject")
+	}
+	for _, id := range req.Accept {
+		for _, rejected := range req.Reject {
+			if id == rejected {
+				return nil, echo.NewHTTPError(http.StatusBadRequest, "suggestion "+id+" is both accepted and rejected")
+			}
+		}
+	}
+
+	tx, txErr := e.db.Begin(ctx)
+	if txErr != nil {
+		log.Errorf("failed to begin tx: %v", txErr)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, txErr)
+	}
+
+	res, err := e.repo.ReviewCategorySuggestions(ctx, req.Accept, req.Reject, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	if cErr := tx.Commit(ctx); cErr != nil {
+		log.Errorf("failed to commit tx in ReviewCategorySuggestions: %v", cErr)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, cErr)
+	}
+	if res.Accepted > 0 {
+		invalidateCategoryModel()
+	}
+	return res, nil
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"excel-service/internal/models"
+	"testing"
+)
+
+func TestCategoryModelKeepsSameNamesApart(t *testing.T) {
+	m := trainCategoryModel([]*models.CategorySample{
+		{Name: "Труба стальная бесшовная", CategoryId: "steel", Category: "Трубы"},
+		{Name: "Труба стальная электросварная", CategoryId: "steel", Category: "Трубы"},
+		{Name: "Труба полипропиленовая PN20", CategoryId: "plumbing", Category: "Трубы"},
+		{Name: "Труба полипропиленовая армированная", CategoryId: "plumbing", Category: "Трубы"},
+		{Name: "Кабель силовой медный", CategoryId: "cable", Category: "Кабель"},
+	})
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Труба стальная 57х3,5", "steel"},
+		{"Труба полипропиленовая 25 мм", "plumbing"},
+		{"Кабель силовой ВВГ", "cable"},
+		{"Краска эмаль", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := m.Suggest(tt.name, "", "")
+			if got != tt.want {
+				t.Errorf("Suggest(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+
+	s := m.suggestion("n1", "plumbing", 0.5)
+	if s.CategoryId != "plumbing" || s.Category != "Трубы" {
+		t.Errorf("suggestion() = %+v", s)
+	}
+}
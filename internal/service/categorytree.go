@@ -0,0 +1,426 @@
+package service
+
+import (
+	"context"
+	"excel-service/internal/models"
+	"excel-service/internal/repository"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+var (
+	categoryCodeReg    = regexp.MustCompile(`^\d+(?:\.\d+)*$`)
+	categoryPathSepReg = regexp.MustCompile(`\s*[/>\\]\s*`)
+)
+
+// maxCategoryProblems limits the error message, a broken file usually has
+// the same problem in every row.
+const maxCategoryProblems = 20
+
+// categoryRow is one node of the imported tree. Nodes with a code are keyed
+// by it, nodes given as a name path by the path.
+type categoryRow struct {
+	key       string
+	parentKey string
+	name      string
+	code      string
+	row       int
+}
+
+// parseCategoryTree reads rows of "code or path | name | parent code". A
+// dotted code "01.02.03" has "01.02" as parent unless the third column says
+// otherwise, a path "Родитель / Дочерняя" creates every level of the path
+// and needs no name column.
+func parseCategoryTree(rows [][]string) ([]*categoryRow, []string) {
+	nodes := map[string]*categoryRow{}
+	var order []*categoryRow
+	var problems []string
+
+	add := func(node *categoryRow) {
+		if prev, ok := nodes[node.key]; ok {
+			if prev.parentKey != node.parentKey {
+				problems = append(problems, fmt.Sprintf("строка %d: %s уже задан в строке %d с другим родителем", node.row, node.key, prev.row))
+			}
+			if node.name != "" {
+				prev.name = node.name
+			}
+			return
+		}
+		nodes[node.key] = node
+		order = append(order, node)
+	}
+
+	for i, row := range rows {
+		if i == 0 || len(row) == 0 || strings.TrimSpace(row[0]) == "" {
+			continue
+		}
+		cell := strings.TrimSpace(row[0])
+		name := ""
+		if len(row) > 1 {
+			name = strings.TrimSpace(row[1])
+		}
+
+		if categoryCodeReg.MatchString(cell) {
+			if name == "" {
+				problems = append(problems, fmt.Sprintf("строка %d: у категории %s нет наименования", i+1, cell))
+				continue
+			}
+			parent := ""
+			if dot := strings.LastIndex(cell, "."); dot > 0 {
+				parent = cell[:dot]
+			}
+			if len(row) > 2 && strings.TrimSpace(row[2]) != "" {
+				parent = strings.TrimSpace(row[2])
+			}
+			node := &categoryRow{key: "code:" + cell, name: name, code: cell, row: i + 1}
+			if parent != "" {
+				node.parentKey = "code:" + parent
+			}
+			add(node)
+			continue
+		}
+
+		parts := categoryPathSepReg.Split(cell, -1)
+		parentKey := ""
+		for j, part := range parts {
+			if part == "" {
+				problems = append(problems, fmt.Sprintf("строка %d: пустой уровень в пути %q", i+1, cell))
+				break
+			}
+			key := "path:" + strings.Join(parts[:j+1], "/")
+			add(&categoryRow{key: key, parentKey: parentKey, name: part, row: i + 1})
+			parentKey = key
+		}
+	}
+	return order, problems
+}
+
+// checkCategoryTree makes sure every parent exists in the file or in the DB
+// and that the links do not form a cycle. existing maps keys of categories
+// already in the DB to the keys of their parents.
+func checkCategoryTree(nodes []*categoryRow, existing map[string]string) []string {
+	parents := map[string]string{}
+	for key, parent := range existing {
+		parents[key] = parent
+	}
+	rows := map[string]int{}
+	for _, node := range nodes {
+		parents[node.key] = node.parentKey
+		rows[node.key] = node.row
+	}
+
+	var problems []string
+	for _, node := range nodes {
+		if node.parentKey == "" {
+			continue
+		}
+		if _, ok := parents[node.parentKey]; !ok {
+			problems = append(problems, fmt.Sprintf("строка %d: родитель %s не найден", node.row, categoryKeyName(node.parentKey)))
+		}
+	}
+
+	reported := map[string]bool{}
+	for _, node := range nodes {
+		seen := map[string]bool{}
+		for key := node.key; key != ""; key = parents[key] {
+			if !seen[key] {
+				seen[key] = true
+				continue
+			}
+			if reported[key] {
+				break
+			}
+			// key is on the cycle, walk it once more to name its members
+			cycle := []string{categoryKeyName(key)}
+			reported[key] = true
+			for k := parents[key]; k != key; k = parents[k] {
+				reported[k] = true
+				cycle = append(cycle, categoryKeyName(k))
+			}
+			cycle = append(cycle, categoryKeyName(key))
+			problems = append(problems, fmt.Sprintf("строка %d: цикл %s", rows[node.key], strings.Join(cycle, " -> ")))
+			break
+		}
+	}
+	return problems
+}
+
+func categoryKeyName(key string) string {
+	return strings.SplitN(key, ":", 2)[1]
+}
+
+func categoryProblemsError(problems []string) error {
+	if len(problems) > maxCategoryProblems {
+		problems = append(problems[:maxCategoryProblems], fmt.Sprintf("и еще %d", len(problems)-maxCategoryProblems))
+	}
+	log.Warnf("category tree rejected: %s", strings.Join(problems, "; "))
+	return echo.NewHTTPError(http.StatusUnprocessableEntity, strings.Join(problems, "; "))
+}
+
+// categoryField is the field category problems of import rows are reported
+// under.
+const categoryField = "category"
+
+// categoryResolver finds the categories of import rows. A name is unique
+// only under its parent, so a name several categories share has to be given
+// as a path "Родитель / Дочерняя".
+type categoryResolver struct {
+	byId   map[string]*models.CategoryNode
+	byName map[string][]*models.CategoryNode
+}
+
+func newCategoryResolver(ctx context.Context, repo repository.ExcelRepository) *categoryResolver {
+	categories, err := repo.SelectCategories(ctx)
+	if err != nil {
+		log.Warnf("categories are not resolved: %v", err)
+		return &categoryResolver{}
+	}
+	return newCategoryIndex(categories)
+}
+
+func newCategoryIndex(categories []*models.CategoryNode) *categoryResolver {
+	r := &categoryResolver{byId: map[string]*models.CategoryNode{}, byName: map[string][]*models.CategoryNode{}}
+	for _, category := range categories {
+		r.byId[category.Id] = category
+		key := normalizeText(category.Name)
+		r.byName[key] = append(r.byName[key], category)
+	}
+	return r
+}
+
+// find returns the categories the value names. The whole value is tried as
+// a name first, names such as "Трубы б/у" contain a separator.
+func (r *categoryResolver) find(value string) []*models.CategoryNode {
+	if found := r.byName[normalizeText(value)]; len(found) > 0 {
+		return found
+	}
+	parts := categoryPathSepReg.Split(value, -1)
+	if len(parts) < 2 {
+		return nil
+	}
+	var found []*models.CategoryNode
+	for _, category := range r.byName[normalizeText(parts[len(parts)-1])] {
+		if r.underPath(category, parts[:len(parts)-1]) {
+			found = append(found, category)
+		}
+	}
+	return found
+}
+
+// underPath reports whether the parents of the category are named by the
+// path, the closest parent last. The path may start below the root.
+func (r *categoryResolver) underPath(category *models.CategoryNode, path []string) bool {
+	node := category
+	for i := len(path) - 1; i >= 0; i-- {
+		parent, ok := r.byId[node.ParentId]
+		if !ok || parent == category || normalizeText(parent.Name) != normalizeText(path[i]) {
+			return false
+		}
+		node = parent
+	}
+	return true
+}
+
+// resolve sets the category of the item. A value that names no category or
+// several of them is reported and the item is saved without a category.
+func (r *categoryResolver) resolve(n *models.Nomenclature, value string) []*models.UploadRowError {
+	value = strings.TrimSpace(value)
+	if value == "" || r.byName == nil {
+		return nil
+	}
+	found := r.find(value)
+	switch len(found) {
+	case 0:
+		return []*models.UploadRowError{{Field: categoryField, Value: value, Message: "категория не найдена"}}
+	case 1:
+		n.CategoryId = found[0].Id
+		return nil
+	}
+	return []*models.UploadRowError{{Field: categoryField, Value: value, Message: "категория с таким наименованием есть в нескольких разделах, укажите путь через \"/\""}}
+}
+
+// categoryKeys keys the categories of the DB the way parseCategoryTree keys
+// file rows, by code or by the path of names from the root. Categories on a
+// cycle have no path and are left out.
+func categoryKeys(categories []*models.CategoryNode) map[string]string {
+	byId := map[string]*models.CategoryNode{}
+	for _, category := range categories {
+		byId[category.Id] = category
+	}
+	paths := map[string]string{}
+	var path func(category *models.CategoryNode, depth int) string
+	path = func(category *models.CategoryNode, depth int) string {
+		if p, ok := paths[category.Id]; ok {
+			return p
+		}
+		if depth > len(categories) {
+			return ""
+		}
+		p := category.Name
+		if parent, ok := byId[category.ParentId]; ok {
+			parentPath := path(parent, depth+1)
+			if parentPath == "" {
+				return ""
+			}
+			p = parentPath + "/" + category.Name
+		}
+		paths[category.Id] = p
+		return p
+	}
+
+	keys := map[string]string{}
+	for _, category := range categories {
+		if category.Code != "" {
+			keys[category.Id] = "code:" + category.Code
+		} else if p := path(category, 0); p != "" {
+			keys[category.Id] = "path:" + p
+		}
+	}
+	return keys
+}
+
+// ImportCategoryTree saves the whole tree in one transaction, nothing is
+// saved when the file has missing parents or cycles.
+func (e ExcelServiceImpl) ImportCategoryTree(ctx context.Context, file *multipart.FileHeader) (*models.CategoryTreeImport, error) {
+	src, err := file.Open()
+	if err != nil {
+		log.Errorf("failed to open file: %v", err)
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	defer src.Close()
+
+	excelFile, fileErr := openWorkbook(src, e.cfg.Limits)
+	if fileErr != nil {
+		return nil, fileErr
+	}
+	rows, rowsErr := readRows(excelFile, excelFile.GetSheetList()[0], e.cfg.Limits)
+	if rowsErr != nil {
+		return nil, rowsErr
+	}
+
+	nodes, problems := parseCategoryTree(rows)
+	if len(problems) > 0 {
+		return nil, categoryProblemsError(problems)
+	}
+	if len(nodes) == 0 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "в файле нет категорий")
+	}
+
+	categories, catErr := e.repo.SelectCategories(ctx)
+	if catErr != nil {
+		return nil, catErr
+	}
+	ids := map[string]string{}
+	existing := map[string]string{}
+	keys := categoryKeys(categories)
+	for _, category := range categories {
+		key, ok := keys[category.Id]
+		if !ok {
+			continue
+		}
+		ids[key] = category.Id
+		existing[key] = keys[category.ParentId]
+	}
+	if problems := checkCategoryTree(nodes, existing); len(problems) > 0 {
+		return nil, categoryProblemsError(problems)
+	}
+
+	tx, txErr := e.db.Begin(ctx)
+	if txErr != nil {
+		log.Errorf("failed to begin tx: %v", txErr)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, txErr)
+	}
+
+	byKey := map[string]*categoryRow{}
+	for _, node := range nodes {
+		byKey[node.key] = node
+	}
+	res := &models.CategoryTreeImport{}
+	saved := map[string]bool{}
+	var save func(node *categoryRow) error
+	save = func(node *categoryRow) error {
+		if saved[node.key] {
+			return nil
+		}
+		if parent, ok := byKey[node.parentKey]; ok {
+			if err := save(parent); err != nil {
+				return err
+			}
+		}
+		id, created, err := e.repo.UpsertCategory(ctx, &models.CategoryNode{Name: node.name, Code: node.code, ParentId: ids[node.parentKey]}, tx)
+		if err != nil {
+			return err
+		}
+		ids[node.key] = id
+		saved[node.key] = true
+		if created {
+			res.Created++
+		} else {
+			res.Updated++
+		}
+		return nil
+	}
+	for _, node := range nodes {
+		if err := save(node); err != nil {
+			return nil, err
+		}
+	}
+
+	if cErr := tx.Commit(ctx); cErr != nil {
+		log.Errorf("failed to commit tx in ImportCategoryTree: %v", cErr)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, cErr)
+	}
+	invalidateCategoryModel()
+	return res, nil
+}
+
+// GetCategoryTree returns the roots with their children. Categories caught
+// in a cycle in the DB are not reachable from a root and are left out.
+func (e ExcelServiceImpl) GetCategoryTree(ctx context.Context) ([]*models.CategoryNode, error) {
+	categories, err := e.repo.SelectCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return buildCategoryTree(categories), nil
+}
+
+func buildCategoryTree(categories []*models.CategoryNode) []*models.CategoryNode {
+	byId := map[string]*models.CategoryNode{}
+	for _, category := range categories {
+		byId[category.Id] = category
+	}
+
+	roots := []*models.CategoryNode{}
+	for _, category := range categories {
+		if parent, ok := byId[category.ParentId]; ok && category.ParentId != category.Id {
+			parent.Children = append(parent.Children, category)
+			continue
+		}
+		if category.ParentId != "" && byId[category.ParentId] == nil {
+			log.Warnf("category %s points to missing parent %s", category.Id, category.ParentId)
+		}
+		roots = append(roots, category)
+	}
+
+	var sortNodes func(nodes []*models.CategoryNode)
+	sortNodes = func(nodes []*models.CategoryNode) {
+		sort.SliceStable(nodes, func(i, j int) bool {
+			if nodes[i].Code != nodes[j].Code {
+				return nodes[i].Code < nodes[j].Code
+			}
+			return nodes[i].Name < nodes[j].Name
+		})
+		for _, node := range nodes {
+			sortNodes(node.Children)
+		}
+	}
+	sortNodes(roots)
+	return roots
+}
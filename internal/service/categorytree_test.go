@@ -0,0 +1,83 @@
+package service
+
+import (
+	"excel-service/internal/models"
+	"testing"
+)
+
+// testCategories has "Трубы" under two parents.
+var testCategories = []*models.CategoryNode{
+	{Id: "1", Name: "Металлопрокат", Code: "01"},
+	{Id: "2", Name: "Трубы", ParentId: "1"},
+	{Id: "3", Name: "Сантехника"},
+	{Id: "4", Name: "Трубы", ParentId: "3"},
+	{Id: "5", Name: "Трубы б/у", ParentId: "3"},
+	{Id: "6", Name: "Фитинги", Code: "03.01", ParentId: "3"},
+}
+
+func TestCategoryResolver(t *testing.T) {
+	r := newCategoryIndex(testCategories)
+	tests := []struct {
+		value   string
+		want    string
+		problem bool
+	}{
+		{"Металлопрокат", "1", false},
+		{"металлопрокат ", "1", false},
+		{"Трубы", "", true},
+		{"Металлопрокат / Трубы", "2", false},
+		{"Сантехника > Трубы", "4", false},
+		{"Трубы б/у", "5", false},
+		{"Арматура / Трубы", "", true},
+		{"Кабель", "", true},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			n := &models.Nomenclature{}
+			rowErrs := r.resolve(n, tt.value)
+			if n.CategoryId != tt.want || (len(rowErrs) > 0) != tt.problem {
+				t.Errorf("resolve(%q) = %q, %d problems, want %q, problem %v", tt.value, n.CategoryId, len(rowErrs), tt.want, tt.problem)
+			}
+		})
+	}
+}
+
+func TestCategoryKeys(t *testing.T) {
+	keys := categoryKeys(append(testCategories, &models.CategoryNode{Id: "7", Name: "Цикл", ParentId: "7"}))
+	want := map[string]string{
+		"1": "code:01",
+		"2": "path:Металлопрокат/Трубы",
+		"3": "path:Сантехника",
+		"4": "path:Сантехника/Трубы",
+		"5": "path:Сантехника/Трубы б/у",
+		"6": "code:03.01",
+	}
+	if len(keys) != len(want) {
+		t.Errorf("categoryKeys() = %v", keys)
+	}
+	for id, key := range want {
+		if keys[id] != key {
+			t.Errorf("key of %s = %q, want %q", id, keys[id], key)
+		}
+	}
+}
+
+func TestCheckCategoryTreeExistingPath(t *testing.T) {
+	nodes, problems := parseCategoryTree([][]string{
+		{"Код или путь", "Наименование", "Родитель"},
+		{"Сантехника / Трубы / Медные"},
+		{"03.01.01", "Угольники", "03.01"},
+	})
+	if len(problems) > 0 {
+		t.Fatal(problems)
+	}
+	existing := map[string]string{}
+	keys := categoryKeys(testCategories)
+	for _, category := range testCategories {
+		existing[keys[category.Id]] = keys[category.ParentId]
+	}
+	if problems := checkCategoryTree(nodes, existing); len(problems) > 0 {
+		t.Errorf("checkCategoryTree() = %v", problems)
+	}
+}
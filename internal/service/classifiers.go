@@ -0,0 +1,291 @@
+package service
+
+import (
+	"context"
+	"excel-service/internal/models"
+	"excel-service/internal/repository"
+	"mime/multipart"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+const (
+	classifierOkpd2 = "okpd2"
+	classifierTnved = "tnved"
+)
+
+var (
+	okpd2DottedReg    = regexp.MustCompile(`^\d{2}(?:(?:\.\d{2}){0,2}(?:\.\d)?|\.\d{2}\.\d{2}\.\d{2,3})$`)
+	okpd2SectionReg   = regexp.MustCompile(`^(?i:раздел\s+)?([A-U])(?:$|[\s.:-]+(.*))`)
+	tnvedSectionReg   = regexp.MustCompile(`^(?i:раздел\s+)?([IVX]+)(?:$|[\s.:-]+(.*))`)
+	classifierCodeReg = regexp.MustCompile(`^[\d.\s]+$`)
+)
+
+// normalizeOkpd2 brings "25.99.29.190", "25 99 29 190" and "2599291" to the
+// dotted form. Dotted input has to follow the OKPD2 grouping.
+func normalizeOkpd2(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	if !classifierCodeReg.MatchString(s) {
+		return "", false
+	}
+	if strings.Contains(s, ".") && !okpd2DottedReg.MatchString(s) {
+		return "", false
+	}
+
+	digits := strings.NewReplacer(".", "", " ", "").Replace(s)
+	if len(digits) < 2 || len(digits) > 9 {
+		return "", false
+	}
+	var b strings.Builder
+	for i := 0; i < len(digits); i++ {
+		if i == 2 || i == 4 || i == 6 {
+			b.WriteByte('.')
+		}
+		b.WriteByte(digits[i])
+	}
+	return b.String(), true
+}
+
+// normalizeTnved drops the spaces of "8471 30 000 0", TN VED codes have
+// 2, 4, 6, 8, 9 or 10 digits.
+func normalizeTnved(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	if !classifierCodeReg.MatchString(s) {
+		return "", false
+	}
+	digits := strings.NewReplacer(".", "", " ", "").Replace(s)
+	switch len(digits) {
+	case 2, 4, 6, 8, 9, 10:
+		return digits, true
+	}
+	return "", false
+}
+
+func normalizeClassifierCode(kind, s string) (string, bool) {
+	if kind == classifierTnved {
+		return normalizeTnved(s)
+	}
+	return normalizeOkpd2(s)
+}
+
+// classifierCandidates lists the codes a cell may stand for. Excel drops the
+// leading zero of numeric TN VED cells, "102030000" is "0102030000".
+func classifierCandidates(kind, s string) []string {
+	code, ok := normalizeClassifierCode(kind, s)
+	if !ok {
+		return nil
+	}
+	if kind == classifierTnved && len(code) == 9 {
+		return []string{code, "0" + code}
+	}
+	return []string{code}
+}
+
+func shorterClassifierCode(code string) string {
+	if len(code) <= 1 {
+		return ""
+	}
+	return strings.TrimSuffix(code[:len(code)-1], ".")
+}
+
+// parseClassifier reads the official OKPD2 or TN VED table: code in the
+// first column, name in the second. Section rows ("РАЗДЕЛ C", "XVI") become
+// parents of the top level codes that follow them.
+func parseClassifier(kind string, rows [][]string) ([]*models.ClassifierCode, int) {
+	sectionReg := okpd2SectionReg
+	if kind == classifierTnved {
+		sectionReg = tnvedSectionReg
+	}
+
+	var codes []*models.ClassifierCode
+	known := map[string]bool{}
+	section, skipped := "", 0
+	for _, row := range rows {
+		if len(row) == 0 || strings.TrimSpace(row[0]) == "" {
+			continue
+		}
+		cell := strings.TrimSpace(row[0])
+		name := ""
+		if len(row) > 1 {
+			name = strings.TrimSpace(row[1])
+		}
+
+		if m := sectionReg.FindStringSubmatch(cell); m != nil {
+			section = m[1]
+			if name == "" {
+				name = strings.TrimSpace(m[2])
+			}
+			known[section] = true
+			codes = append(codes, &models.ClassifierCode{Code: section, Name: name})
+			continue
+		}
+
+		code, ok := normalizeClassifierCode(kind, cell)
+		if !ok {
+			skipped++
+			continue
+		}
+		parent := section
+		for c := shorterClassifierCode(code); c != ""; c = shorterClassifierCode(c) {
+			if known[c] {
+				parent = c
+				break
+			}
+		}
+		known[code] = true
+		codes = append(codes, &models.ClassifierCode{
+			Code:       code,
+			Name:       name,
+			ParentCode: parent,
+			Level:      len(strings.Replace(code, ".", "", -1)),
+		})
+	}
+	return codes, skipped
+}
+
+func checkClassifierKind(kind string) error {
+	if kind != classifierOkpd2 && kind != classifierTnved {
+		log.Warnf("unknown classifier %s", kind)
+		return echo.NewHTTPError(http.StatusBadRequest, "classifier must be okpd2 or tnved")
+	}
+	return nil
+}
+
+func (e ExcelServiceImpl) ImportClassifier(ctx context.Context, kind string, file *multipart.FileHeader) (*models.ClassifierImport, error) {
+	if err := checkClassifierKind(kind); err != nil {
+		return nil, err
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		log.Errorf("failed to open file: %v", err)
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	defer src.Close()
+
+	excelFile, fileErr := openWorkbook(src, e.cfg.Limits)
+	if fileErr != nil {
+		return nil, fileErr
+	}
+	rows, rowsErr := readRows(excelFile, excelFile.GetSheetList()[0], e.cfg.Limits)
+	if rowsErr != nil {
+		return nil, rowsErr
+	}
+
+	codes, skipped := parseClassifier(kind, rows)
+	if len(codes) == 0 {
+		log.Warnf("no %s codes in file %s", kind, file.Filename)
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "в файле нет кодов "+kind)
+	}
+	if saveErr := e.repo.SaveClassifierCodes(ctx, kind, codes); saveErr != nil {
+		return nil, saveErr
+	}
+
+	return &models.ClassifierImport{Kind: kind, Codes: len(codes), Skipped: skipped}, nil
+}
+
+func (e ExcelServiceImpl) GetClassifierCode(ctx context.Context, kind, code string) (*models.ClassifierLookup, error) {
+	if err := checkClassifierKind(kind); err != nil {
+		return nil, err
+	}
+	candidates := classifierCandidates(kind, code)
+	if len(candidates) == 0 {
+		log.Warnf("wrong %s code %s", kind, code)
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "неверный код "+kind+": "+code)
+	}
+
+	var path []*models.ClassifierCode
+	var pathErr error
+	for _, candidate := range candidates {
+		if path, pathErr = e.repo.SelectClassifierPath(ctx, kind, candidate); pathErr == nil {
+			break
+		}
+	}
+	if pathErr != nil {
+		return nil, pathErr
+	}
+
+	last := path[len(path)-1]
+	return &models.ClassifierLookup{Kind: kind, Code: last.Code, Name: last.Name, Path: path}, nil
+}
+
+// classifierChecker validates the OKPD2 and TN VED codes of imported rows.
+// Codes are checked against the dictionaries only when those were imported,
+// lookups are cached for the whole file.
+type classifierChecker struct {
+	repo     repository.ExcelRepository
+	filled   map[string]bool
+	resolved map[string]string
+}
+
+func newClassifierChecker(ctx context.Context, repo repository.ExcelRepository) *classifierChecker {
+	c := &classifierChecker{repo: repo, filled: map[string]bool{}, resolved: map[string]string{}}
+	for _, kind := range []string{classifierOkpd2, classifierTnved} {
+		filled, err := repo.HasClassifierCodes(ctx, kind)
+		if err != nil {
+			log.Warnf("%s codes are checked by format only: %v", kind, err)
+		}
+		c.filled[kind] = filled
+	}
+	return c
+}
+
+// resolve returns the canonical code or the reason it is rejected. When the
+// dictionary cannot be read the code is kept and the row is reported, the
+// failure is not cached.
+func (c *classifierChecker) resolve(ctx context.Context, kind, raw string) (string, string) {
+	candidates := classifierCandidates(kind, raw)
+	if len(candidates) == 0 {
+		return "", "неверный формат кода"
+	}
+	if !c.filled[kind] {
+		return candidates[0], ""
+	}
+
+	key := kind + ":" + raw
+	if code, ok := c.resolved[key]; ok {
+		if code == "" {
+			return "", "код отсутствует в классификаторе"
+		}
+		return code, ""
+	}
+	for _, candidate := range candidates {
+		exists, err := c.repo.ClassifierCodeExists(ctx, kind, candidate)
+		if err != nil {
+			return candidates[0], "код не проверен по классификатору, повторите загрузку"
+		}
+		if exists {
+			c.resolved[key] = candidate
+			return candidate, ""
+		}
+	}
+	c.resolved[key] = ""
+	return "", "код отсутствует в классификаторе"
+}
+
+// check normalizes the codes of n. Unknown OKPD2 codes are cleared, they
+// could not be linked anyway, TN VED keeps the text as written. A code that
+// could not be checked is kept.
+func (c *classifierChecker) check(ctx context.Context, n *models.Nomenclature) []*models.UploadRowError {
+	var rowErrs []*models.UploadRowError
+	if n.OKPD2 != "" {
+		code, msg := c.resolve(ctx, classifierOkpd2, n.OKPD2)
+		if msg != "" {
+			rowErrs = append(rowErrs, &models.UploadRowError{Field: classifierOkpd2, Value: n.OKPD2, Message: msg})
+		}
+		n.OKPD2 = code
+	}
+	if n.CodeTnved != "" {
+		code, msg := c.resolve(ctx, classifierTnved, n.CodeTnved)
+		if msg != "" {
+			rowErrs = append(rowErrs, &models.UploadRowError{Field: classifierTnved, Value: n.CodeTnved, Message: msg})
+		} else {
+			n.CodeTnved = code
+		}
+	}
+	return rowErrs
+}
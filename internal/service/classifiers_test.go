@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"excel-service/internal/models"
+	"excel-service/internal/repository"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeOkpd2(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+		ok   bool
+	}{
+		{"25.99.29.190", "25.99.29.190", true},
+		{"25 99 29 190", "25.99.29.190", true},
+		{"259929190", "25.99.29.190", true},
+		{"25992919", "25.99.29.19", true},
+		{" 25.99 ", "25.99", true},
+		{"25.99.29.1", "25.99.29.1", true},
+		{"25", "25", true},
+		{"25.9.29", "", false},
+		{"2599291900", "", false},
+		{"2", "", false},
+		{"C.25", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, ok := normalizeOkpd2(tt.s)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("normalizeOkpd2(%q) = %q, %v, want %q, %v", tt.s, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestClassifierCandidates(t *testing.T) {
+	tests := []struct {
+		kind string
+		s    string
+		want []string
+	}{
+		{classifierOkpd2, "25992919", []string{"25.99.29.19"}},
+		{classifierTnved, "8471 30 000 0", []string{"8471300000"}},
+		{classifierTnved, "102030000", []string{"102030000", "0102030000"}},
+		{classifierTnved, "84713", nil},
+		{classifierTnved, "84.71", []string{"8471"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.kind+" "+tt.s, func(t *testing.T) {
+			if got := classifierCandidates(tt.kind, tt.s); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("classifierCandidates(%q, %q) = %q, want %q", tt.kind, tt.s, got, tt.want)
+			}
+		})
+	}
+}
+
+// classifierRepo knows the codes in codes and fails when err is set.
+type classifierRepo struct {
+	repository.ExcelRepository
+	codes map[string]bool
+	err   error
+	calls int
+}
+
+func (r *classifierRepo) ClassifierCodeExists(ctx context.Context, kind, code string) (bool, error) {
+	r.calls++
+	return r.codes[code], r.err
+}
+
+func TestClassifierCheckerCheck(t *testing.T) {
+	repo := &classifierRepo{codes: map[string]bool{"25.99.29.190": true, "0102030000": true}}
+	c := &classifierChecker{repo: repo, filled: map[string]bool{classifierOkpd2: true, classifierTnved: true}, resolved: map[string]string{}}
+
+	n := &models.Nomenclature{OKPD2: "259929190", CodeTnved: "102030000"}
+	if rowErrs := c.check(context.Background(), n); len(rowErrs) != 0 {
+		t.Errorf("check() errors = %+v", rowErrs[0])
+	}
+	if n.OKPD2 != "25.99.29.190" || n.CodeTnved != "0102030000" {
+		t.Errorf("check() codes = %q, %q", n.OKPD2, n.CodeTnved)
+	}
+
+	n = &models.Nomenclature{OKPD2: "25992919"}
+	if rowErrs := c.check(context.Background(), n); len(rowErrs) != 1 || n.OKPD2 != "" {
+		t.Errorf("unknown code: OKPD2 = %q, errors = %d", n.OKPD2, len(rowErrs))
+	}
+
+	repo.err = errors.New("connection refused")
+	repo.calls = 0
+	for i := 0; i < 2; i++ {
+		n = &models.Nomenclature{OKPD2: "25 99 11"}
+		rowErrs := c.check(context.Background(), n)
+		if len(rowErrs) != 1 || n.OKPD2 != "25.99.11" {
+			t.Errorf("failed lookup: OKPD2 = %q, errors = %d", n.OKPD2, len(rowErrs))
+		}
+	}
+	if repo.calls != 2 {
+		t.Errorf("failed lookups were cached, %d calls", repo.calls)
+	}
+}
@@ -0,0 +1,647 @@
+package service
+
+import (
+	"context"
+	"excel-service/internal/apperrors"
+	"excel-service/internal/auth"
+	"excel-service/internal/models"
+	"excel-service/internal/repository"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"github.com/xuri/excelize/v2"
+)
+
+// Target fields of a user mapping.
+const (
+	mappingName         = "name"
+	mappingCodeSkmtr    = "code_skmtr"
+	mappingCodeKsNsi    = "code_ks_nsi"
+	mappingCodeAmto     = "code_amto"
+	mappingOkpd2        = "okpd2"
+	mappingCodeTnved    = "code_tnved"
+	mappingVendorCode   = "vendor_code"
+	mappingMark         = "mark"
+	mappingStandard     = "gost_tu"
+	mappingManufacturer = "manufacturer"
+	mappingCountry      = countryField
+	mappingPrice        = "price"
+	mappingCurrency     = currencyField
+	mappingVat          = "vat"
+	mappingMeasurement  = "measurement"
+	mappingQuantity     = "quantity"
+	mappingAddress      = "warehouse_address"
+)
+
+const (
+	// mappedTemplate is the template name sheets imported with a user
+	// mapping are reported with.
+	mappedTemplate = "mapping"
+	mappedFileName = "mapped_nomenclature"
+	columnSamples  = 3
+	// exactAliasConfidence is for a header that is an alias of the field,
+	// partAliasConfidence for one that only has an alias among its words.
+	exactAliasConfidence = 0.9
+	partAliasConfidence  = 0.6
+)
+
+// mappingField is a target field of a user mapping. Aliases are header
+// names suppliers use for it, normalized the way normalizeText does.
+type mappingField struct {
+	key      string
+	title    string
+	required bool
+	aliases  []string
+}
+
+var mappingFields = []*mappingField{
+	{key: mappingName, title: "Наименование", required: true, aliases: []string{"наименование", "наименование товара", "наименование тмц", "наименование продукции", "название", "номенклатура", "товар", "name", "product"}},
+	{key: mappingCodeSkmtr, title: "Код СКМТР", aliases: []string{"код скмтр", "скмтр"}},
+	{key: mappingCodeKsNsi, title: "Код КС НСИ", aliases: []string{"код кс нси", "кс нси"}},
+	{key: mappingCodeAmto, title: "Код АМТО", aliases: []string{"код амто", "амто"}},
+	{key: mappingOkpd2, title: "ОКПД2", aliases: []string{"окпд2", "окпд 2", "код окпд2", "код окпд 2", "окпд"}},
+	{key: mappingCodeTnved, title: "Код ТН ВЭД", aliases: []string{"тн вэд", "код тн вэд", "тнвэд", "тн вэд еаэс"}},
+	{key: mappingVendorCode, title: "Артикул", aliases: []string{"артикул", "артикул производителя", "код производителя", "код товара", "sku", "part number"}},
+	{key: mappingMark, title: "Марка", aliases: []string{"марка", "марка тмц", "модель"}},
+	{key: mappingStandard, title: "ГОСТ/ТУ", aliases: []string{"гост", "гост ту", "ту", "стандарт", "нормативный документ"}},
+	{key: mappingManufacturer, title: "Производитель", aliases: []string{"производитель", "изготовитель", "завод изготовитель", "бренд", "brand", "manufacturer"}},
+	{key: mappingCountry, title: "Страна происхождения", aliases: []string{"страна", "страна происхождения", "страна производства", "страна производитель"}},
+	{key: mappingPrice, title: "Цена за единицу", aliases: []string{"цена", "цена за единицу", "цена за ед", "цена с ндс", "цена без ндс", "стоимость", "price"}},
+	{key: mappingCurrency, title: "Валюта", aliases: []string{"валюта", "валюта цены", "currency"}},
+	{key: mappingVat, title: "НДС", aliases: []string{"ндс", "ставка ндс"}},
+	{key: mappingMeasurement, title: "Единица измерения", aliases: []string{"ед изм", "единица измерения", "ед", "ед измерения"}},
+	{key: mappingQuantity, title: "Количество", aliases: []string{"количество", "кол во", "остаток", "в наличии", "наличие"}},
+	{key: logisticsWeightNetto, title: "Масса нетто, кг", aliases: []string{"масса нетто", "вес нетто", "нетто"}},
+	{key: logisticsWeightBrutto, title: "Масса брутто, кг", aliases: []string{"масса брутто", "вес брутто", "брутто"}},
+	{key: mappingAddress, title: "Адрес склада", aliases: []string{"адрес склада", "склад", "место хранения"}},
+}
+
+func findMappingField(key string) *mappingField {
+	for _, f := range mappingFields {
+		if f.key == key {
+			return f
+		}
+	}
+	return nil
+}
+
+// fieldSuggestion is the field proposed for a column.
+type fieldSuggestion struct {
+	field      string
+	confidence float64
+}
+
+// suggestFields proposes a field for every column it can. A saved template
+// of the company whose header names are all on the sheet comes first, the
+// one covering most fields wins. Columns it leaves are matched by field
+// aliases, every field goes to one column at most.
+func suggestFields(columns []string, templates []*models.ColumnTemplate) (map[int]fieldSuggestion, *models.ColumnTemplate) {
+	byName := map[string]int{}
+	for i, column := range columns {
+		if key := normalizeText(column); key != "" {
+			if _, ok := byName[key]; !ok {
+				byName[key] = i
+			}
+		}
+	}
+
+	suggestions := map[int]fieldSuggestion{}
+	used := map[string]bool{}
+	var saved *models.ColumnTemplate
+	for _, t := range templates {
+		if len(t.Columns) == 0 || (saved != nil && len(t.Columns) <= len(saved.Columns)) {
+			continue
+		}
+		fits := true
+		for _, name := range t.Columns {
+			if _, ok := byName[normalizeText(name)]; !ok {
+				fits = false
+				break
+			}
+		}
+		if fits {
+			saved = t
+		}
+	}
+	if saved != nil {
+		for field, name := range saved.Columns {
+			col := byName[normalizeText(name)]
+			if _, taken := suggestions[col]; taken || findMappingField(field) == nil {
+				continue
+			}
+			suggestions[col] = fieldSuggestion{field: field, confidence: 1}
+			used[field] = true
+		}
+	}
+
+	type candidate struct {
+		col, alias int
+		fieldSuggestion
+	}
+	var candidates []candidate
+	for i, column := range columns {
+		if _, taken := suggestions[i]; taken {
+			continue
+		}
+		header := normalizeText(column)
+		if header == "" {
+			continue
+		}
+		for _, f := range mappingFields {
+			if used[f.key] {
+				continue
+			}
+			best := candidate{col: i, fieldSuggestion: fieldSuggestion{field: f.key}}
+			for _, alias := range f.aliases {
+				confidence := 0.0
+				switch {
+				case header == alias:
+					confidence = exactAliasConfidence
+				case strings.Contains(" "+header+" ", " "+alias+" "):
+					confidence = partAliasConfidence
+				}
+				if confidence > best.confidence || (confidence == best.confidence && len(alias) > best.alias) {
+					best.confidence, best.alias = confidence, len(alias)
+				}
+			}
+			if best.confidence > 0 {
+				candidates = append(candidates, best)
+			}
+		}
+	}
+	// the surest and longest matches go first, "цена с ндс" is the price
+	// rather than the VAT
+	sort.SliceStable(candidates, func(a, b int) bool {
+		if candidates[a].confidence != candidates[b].confidence {
+			return candidates[a].confidence > candidates[b].confidence
+		}
+		if candidates[a].alias != candidates[b].alias {
+			return candidates[a].alias > candidates[b].alias
+		}
+		return candidates[a].col < candidates[b].col
+	})
+	for _, c := range candidates {
+		if _, taken := suggestions[c.col]; taken || used[c.field] {
+			continue
+		}
+		suggestions[c.col] = c.fieldSuggestion
+		used[c.field] = true
+	}
+	return suggestions, saved
+}
+
+// columnValues returns up to limit filled values of the column from the
+// data rows.
+func columnValues(rows [][]string, dataRow, col, limit int) []string {
+	values := []string{}
+	for r := dataRow; r < len(rows) && len(values) < limit; r++ {
+		if col < len(rows[r]) {
+			if value := strings.TrimSpace(rows[r][col]); value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+// mappedLayout is the layout of a sheet read with a user mapping. dataRow
+// is 0-based, 0 keeps the detected header. Otherwise the row above the
+// data is the header unless the detected band already ends there.
+func mappedLayout(excelFile *excelize.File, sheet string, rows [][]string, dataRow int) *sheetLayout {
+	layout := detectLayout(excelFile, sheet, rows, nil, 1)
+	if dataRow <= 0 || dataRow == layout.dataRow || dataRow > len(rows) {
+		return layout
+	}
+	return &sheetLayout{headerRow: dataRow - 1, dataRow: dataRow, columns: flattenHeader(rows, sheetMerges(excelFile, sheet), dataRow-1, dataRow)}
+}
+
+// uploadSheet is the sheet of an upload the columns are mapped on.
+type uploadSheet struct {
+	upload    *models.UploadsEntity
+	excelFile *excelize.File
+	sheet     string
+	rows      [][]string
+	layout    *sheetLayout
+}
+
+// loadUploadSheet finds the sheet to map, in the named file only when
+// fileName is set. An explicit sheet is looked up in the files of the
+// upload in turn, otherwise the first sheet no built-in template fits is
+// taken, the first sheet of the first file when they all fit.
+func (e ExcelServiceImpl) loadUploadSheet(ctx context.Context, uploadId, fileName string, sel models.SheetSelector) (*uploadSheet, error) {
+	uploads, uploadErr := e.repo.GetFromUploadCatalogue(ctx, uploadId)
+	if uploadErr != nil {
+		log.Warnf("failed to get upload catalog: %v", uploadErr)
+		return nil, uploadErr
+	}
+	if fileName != "" {
+		var named []*models.UploadsEntity
+		for _, upload := range uploads {
+			if upload.FileId == fileName {
+				named = append(named, upload)
+			}
+		}
+		if len(named) == 0 {
+			log.Warnf("upload %s has no file %s", uploadId, fileName)
+			return nil, apperrors.New(apperrors.NotFound, fmt.Sprintf("в загрузке нет файла %q", fileName))
+		}
+		uploads = named
+	}
+	if len(uploads) == 0 {
+		log.Warnf("upload %s has no files", uploadId)
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "upload has no files")
+	}
+
+	var found, first *uploadSheet
+	for i, upload := range uploads {
+		excelFile, fileErr := e.openUploadFile(ctx, upload)
+		if fileErr != nil {
+			return nil, fileErr
+		}
+
+		if sel != (models.SheetSelector{}) {
+			sheet, sheetErr := selectSheet(excelFile, sel, "")
+			if sheetErr != nil {
+				if sel.Name != "" && i < len(uploads)-1 {
+					continue
+				}
+				return nil, sheetErr
+			}
+			found = &uploadSheet{upload: upload, excelFile: excelFile, sheet: sheet}
+			break
+		}
+
+		for _, sheet := range excelFile.GetSheetList() {
+			if detectLayout(excelFile, sheet, sheetTop(excelFile, sheet), uploadTemplates, 0).template == nil {
+				found = &uploadSheet{upload: upload, excelFile: excelFile, sheet: sheet}
+				break
+			}
+		}
+		if found != nil {
+			break
+		}
+		if first == nil {
+			first = &uploadSheet{upload: upload, excelFile: excelFile, sheet: excelFile.GetSheetList()[0]}
+		}
+	}
+	if found == nil {
+		found = first
+	}
+
+	rows, rowsErr := readRows(found.excelFile, found.sheet, e.cfg.Limits)
+	if rowsErr != nil {
+		return nil, rowsErr
+	}
+	found.rows = rows
+	found.layout = detectLayout(found.excelFile, found.sheet, rows, uploadTemplates, 0)
+	if found.layout.template == nil {
+		found.layout = mappedLayout(found.excelFile, found.sheet, rows, 0)
+	}
+	return found, nil
+}
+
+func (e ExcelServiceImpl) openUploadFile(ctx context.Context, upload *models.UploadsEntity) (*excelize.File, error) {
+	minioObj, getObjErr := e.storage.Get(ctx, upload.FileId)
+	if getObjErr != nil {
+		log.Error("failed to get object:", getObjErr)
+		return nil, apperrors.Wrap(apperrors.StorageUnavailable, getObjErr)
+	}
+	defer minioObj.Close()
+
+	return openWorkbook(minioObj, e.cfg.Limits)
+}
+
+// GetFileColumns returns the columns of the upload sheet to map with sample
+// values and suggested fields.
+func (e ExcelServiceImpl) GetFileColumns(ctx context.Context, req *models.DirectusModel) (*models.ColumnDraft, error) {
+	if req.Collection != "uploads" {
+		log.Warnf("collection is %s not uploads", req.Collection)
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "collection is not uploads")
+	}
+	if authErr := e.authorizeUpload(ctx, req.Key); authErr != nil {
+		return nil, authErr
+	}
+
+	src, err := e.loadUploadSheet(ctx, req.Key, "", req.SheetSelector)
+	if err != nil {
+		return nil, err
+	}
+	templates, tErr := e.repo.SelectColumnTemplates(ctx, src.upload.CompanyId)
+	if tErr != nil {
+		return nil, tErr
+	}
+
+	draft := &models.ColumnDraft{
+		UploadId:  req.Key,
+		FileName:  src.upload.FileId,
+		Sheet:     src.sheet,
+		Sheets:    src.excelFile.GetSheetList(),
+		HeaderRow: src.layout.headerRow + 1,
+		DataRow:   src.layout.dataRow + 1,
+		Columns:   []*models.FileColumn{},
+	}
+	if src.layout.template != nil {
+		draft.Template = src.layout.template.name
+	}
+	suggestions, saved := suggestFields(src.layout.columns, templates)
+	if saved != nil {
+		draft.ColumnTemplateId = saved.Id
+	}
+	for i, name := range src.layout.columns {
+		column := &models.FileColumn{Index: i, Name: name, Samples: columnValues(src.rows, src.layout.dataRow, i, columnSamples)}
+		if s, ok := suggestions[i]; ok {
+			column.Field, column.Confidence = s.field, s.confidence
+		}
+		draft.Columns = append(draft.Columns, column)
+	}
+	for _, f := range mappingFields {
+		draft.Fields = append(draft.Fields, &models.MappingField{Key: f.key, Title: f.title, Required: f.required})
+	}
+	return draft, nil
+}
+
+// validateMapping checks that every field is known and mapped to its own
+// existing column and that the required fields are there.
+func validateMapping(columns map[string]int, width int) error {
+	owners := map[int]string{}
+	for field, col := range columns {
+		if findMappingField(field) == nil {
+			return apperrors.New(apperrors.ValidationFailed, fmt.Sprintf("неизвестное поле %q", field))
+		}
+		if col < 0 || col >= width {
+			return apperrors.New(apperrors.ValidationFailed, fmt.Sprintf("поле %q указывает на столбец %d, на листе столбцов: %d", field, col, width))
+		}
+		if owner, ok := owners[col]; ok {
+			return apperrors.New(apperrors.ValidationFailed, fmt.Sprintf("столбец %d сопоставлен полям %q и %q", col, owner, field))
+		}
+		owners[col] = field
+	}
+	for _, f := range mappingFields {
+		if _, ok := columns[f.key]; f.required && !ok {
+			return apperrors.New(apperrors.ValidationFailed, fmt.Sprintf("не указан столбец обязательного поля %q", f.key))
+		}
+	}
+	return nil
+}
+
+// SetColumnMapping saves the mapping the user confirmed for the upload,
+// with SaveAs also as a template of the company, and imports the upload
+// again with it.
+func (e ExcelServiceImpl) SetColumnMapping(ctx context.Context, uploadId string, req *models.ColumnMappingReq) (*models.ColumnMappingRun, error) {
+	if authErr := e.authorizeUpload(ctx, uploadId); authErr != nil {
+		return nil, authErr
+	}
+
+	src, err := e.loadUploadSheet(ctx, uploadId, req.FileName, models.SheetSelector{Name: req.Sheet})
+	if err != nil {
+		return nil, err
+	}
+	if src.layout.template != nil {
+		log.Warnf("sheet %s of upload %s fits template %s", src.sheet, uploadId, src.layout.template.name)
+		return nil, apperrors.New(apperrors.ValidationFailed, fmt.Sprintf("лист %q подходит под шаблон %s и загружается без сопоставления", src.sheet, src.layout.template.name))
+	}
+	if req.DataRow != 0 && (req.DataRow < 2 || req.DataRow > len(src.rows)) {
+		log.Warnf("data row %d is out of sheet %s", req.DataRow, src.sheet)
+		return nil, apperrors.New(apperrors.ValidationFailed, fmt.Sprintf("данные не могут начинаться со строки %d, на листе строк: %d", req.DataRow, len(src.rows)))
+	}
+	layout := mappedLayout(src.excelFile, src.sheet, src.rows, req.DataRow-1)
+	if validErr := validateMapping(req.Columns, len(layout.columns)); validErr != nil {
+		log.Warnf("invalid mapping of upload %s: %v", uploadId, validErr)
+		return nil, validErr
+	}
+
+	mapping := &models.ColumnMapping{UploadId: uploadId, FileName: src.upload.FileId, Sheet: src.sheet, DataRow: layout.dataRow + 1, Columns: req.Columns}
+	run := &models.ColumnMappingRun{Mapping: mapping}
+	var template *models.ColumnTemplate
+	if name := strings.TrimSpace(req.SaveAs); name != "" {
+		template = &models.ColumnTemplate{CompanyId: src.upload.CompanyId, Name: name, Columns: map[string]string{}}
+		for field, col := range req.Columns {
+			if layout.columns[col] == "" {
+				log.Warnf("column %d of sheet %s has no header", col, src.sheet)
+				return nil, apperrors.New(apperrors.ValidationFailed, fmt.Sprintf("у столбца %d нет заголовка, шаблон по нему не узнать", col))
+			}
+			template.Columns[field] = layout.columns[col]
+		}
+	}
+
+	// the import runs under a hook run like the Directus hook, so a hook
+	// delivery for the upload cannot import it at the same time
+	hookRun, started, claimErr := e.repo.StartHookRun(ctx, "mapping:"+uploadId+":"+uuid.New().String(), uploadId, hookRunStaleAfter)
+	if claimErr != nil {
+		return nil, claimErr
+	}
+	if !started {
+		log.Warnf("upload %s is being processed by hook run %s", uploadId, hookRun.Key)
+		return nil, apperrors.New(apperrors.Conflict, "загрузка уже обрабатывается")
+	}
+	if template != nil {
+		if saveErr := e.repo.SaveColumnTemplate(ctx, template); saveErr != nil {
+			e.finishHookRun(ctx, hookRun, nil, saveErr)
+			return nil, saveErr
+		}
+		mapping.TemplateId, run.Template = template.Id, template
+	}
+	if saveErr := e.repo.SaveUploadColumnMapping(ctx, mapping); saveErr != nil {
+		e.finishHookRun(ctx, hookRun, nil, saveErr)
+		return nil, saveErr
+	}
+
+	res, procErr := e.runUpload(ctx, hookRun, &models.DirectusModel{Key: uploadId, Collection: "uploads"})
+	if procErr != nil {
+		return nil, procErr
+	}
+	run.Result = res
+	return run, nil
+}
+
+// GetColumnTemplates lists the saved templates of the company, the company
+// of the caller when none is given.
+func (e ExcelServiceImpl) GetColumnTemplates(ctx context.Context, companyId string) ([]*models.ColumnTemplate, error) {
+	if p := auth.PrincipalFromContext(ctx); companyId == "" && p != nil {
+		companyId = p.CompanyId
+	}
+	if companyId == "" {
+		log.Warn("company of column templates is not set")
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "company is required")
+	}
+	if authErr := authorizeCompany(ctx, companyId); authErr != nil {
+		return nil, authErr
+	}
+	return e.repo.SelectColumnTemplates(ctx, companyId)
+}
+
+// fileMapping is the mapping when it was confirmed for the file, nil
+// otherwise.
+func fileMapping(mapping *models.ColumnMapping, fileName string) *models.ColumnMapping {
+	if mapping == nil || mapping.FileName == "" || mapping.FileName != fileName {
+		return nil
+	}
+	return mapping
+}
+
+// uploadSheets resolves the sheets of an upload file, the sheet of the
+// mapping confirmed for the file is imported along with those fitting a
+// template.
+func uploadSheets(excelFile *excelize.File, sel models.SheetSelector, mapping *models.ColumnMapping) ([]string, error) {
+	if mapping == nil {
+		return selectSheets(excelFile, sel, "", uploadTemplates...)
+	}
+	sheets, err := selectSheets(excelFile, sel, mapping.Sheet, uploadTemplates...)
+	if err != nil || sel != (models.SheetSelector{}) || excelFile.GetSheetIndex(mapping.Sheet) < 0 {
+		return sheets, err
+	}
+	for _, sheet := range sheets {
+		if sheet == mapping.Sheet {
+			return sheets, nil
+		}
+	}
+	return append(sheets, mapping.Sheet), nil
+}
+
+// newMappedNomenclature imports a supplier sheet with a user mapping. Rows
+// without a name are skipped, values that do not parse are reported and
+// left empty.
+func newMappedNomenclature(rows [][]string, mapping *models.ColumnMapping, prices *uploadPrices, repo repository.ExcelRepository, ctx context.Context, companyId, userId, uploadId string, sheet *models.SheetReport) error {
+	templateCurrency := defaultCurrency
+	priceIncludesVat := true
+	if sheet.DataRow > sheet.HeaderRow && sheet.DataRow-1 <= len(rows) {
+		header := rows[sheet.HeaderRow-1 : sheet.DataRow-1]
+		_, templateCurrency = detectCurrency(header)
+		priceIncludesVat = detectPriceIncludesVat(header)
+	}
+	currencies, currencyErr := newCurrencyChecker(ctx, repo)
+	if currencyErr != nil {
+		return currencyErr
+	}
+	classifiers := newClassifierChecker(ctx, repo)
+	categories, modelErr := loadCategoryModel(ctx, repo)
+	if modelErr != nil {
+		log.Warnf("category suggestions are off: %v", modelErr)
+	}
+	manufacturers := newManufacturerResolver(ctx, repo)
+	defer manufacturers.flush(ctx, repo, uploadId)
+	countries := newCountryResolver(ctx, repo)
+	extract, extractErr := loadExtractor(ctx, repo)
+	if extractErr != nil {
+		return extractErr
+	}
+
+	for i, row := range rows {
+		if i+1 < sheet.DataRow {
+			continue
+		}
+		value := func(field string) string {
+			col, ok := mapping.Columns[field]
+			if !ok || col >= len(row) {
+				return ""
+			}
+			return strings.TrimSpace(row[col])
+		}
+		if value(mappingName) == "" {
+			continue
+		}
+
+		nomenclature := &models.Nomenclature{}
+		nomenclature.Id = uuid.New().String()
+		nomenclature.PackageId = uuid.New().String()
+		nomenclature.UploadId = uploadId
+		nomenclature.Source = sourceSupplier
+		nomenclature.Name = value(mappingName)
+		nomenclature.CodeSkmtr = value(mappingCodeSkmtr)
+		nomenclature.CodeKsNsi = value(mappingCodeKsNsi)
+		nomenclature.CodeAmto = value(mappingCodeAmto)
+		nomenclature.OKPD2 = value(mappingOkpd2)
+		nomenclature.CodeTnved = value(mappingCodeTnved)
+		nomenclature.TmcCodeVendor = value(mappingVendorCode)
+		nomenclature.TmcMark = value(mappingMark)
+		nomenclature.Manufacturer = value(mappingManufacturer)
+		nomenclature.Measurement = value(mappingMeasurement)
+		nomenclature.WarehouseAddress = value(mappingAddress)
+		collectStandards(nomenclature, value(mappingStandard), nomenclature.Name)
+		if len(prices.lists) > 0 {
+			nomenclature.PriceLists = prices.lists
+		}
+
+		var rowErrs []*models.UploadRowError
+		if price := value(mappingPrice); price != "" {
+			number, err := parseDecimal(price)
+			if err != nil || number < 0 || math.IsNaN(number) || math.IsInf(number, 0) {
+				rowErrs = append(rowErrs, &models.UploadRowError{Field: mappingPrice, Value: price, Message: "не число"})
+			} else {
+				nomenclature.PricePerUnit = float32(number)
+			}
+		}
+		vat, vatErr := parseVat(value(mappingVat))
+		if vatErr != nil {
+			rowErrs = append(rowErrs, &models.UploadRowError{Field: mappingVat, Value: value(mappingVat), Message: "ставка НДС не распознана"})
+		}
+		applyVat(nomenclature, vat, priceIncludesVat)
+
+		currencyErrs := currencies.resolve(nomenclature, value(mappingCurrency), templateCurrency)
+		rowErrs = append(rowErrs, currencyErrs...)
+
+		if quantity := value(mappingQuantity); quantity != "" {
+			number, err := parseDecimal(quantity)
+			if err != nil || number < 0 || number != math.Trunc(number) {
+				rowErrs = append(rowErrs, &models.UploadRowError{Field: mappingQuantity, Value: quantity, Message: "количество должно быть целым числом"})
+			} else {
+				nomenclature.Quantity = int(number)
+				nomenclature.ProductAvailability = number > 0
+			}
+		}
+
+		for _, field := range []string{logisticsWeightNetto, logisticsWeightBrutto} {
+			weight := value(field)
+			if weight == "" {
+				continue
+			}
+			number, err := parseDecimal(weight)
+			if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+				rowErrs = append(rowErrs, &models.UploadRowError{Field: field, Value: weight, Message: "не число"})
+				continue
+			}
+			if msg := checkLogisticsValue(field, number); msg != "" {
+				rowErrs = append(rowErrs, &models.UploadRowError{Field: field, Value: weight, Message: msg})
+				continue
+			}
+			if field == logisticsWeightNetto {
+				nomenclature.WeightNetto = float32(number)
+			} else {
+				nomenclature.WeightBrutto = float32(number)
+			}
+		}
+		applyAttributes(nomenclature, extract.Extract(nomenclature.Name).Attributes)
+		rowErrs = append(rowErrs, checkLogistics(nomenclature)...)
+		rowErrs = append(rowErrs, countries.resolve(nomenclature, value(mappingCountry))...)
+
+		if len(currencyErrs) == 0 {
+			rowErrs = append(rowErrs, prices.check(nomenclature)...)
+		}
+
+		manufacturers.resolve(nomenclature)
+		reportSheetRow(ctx, repo, sheet, uploadId, mappedFileName, i, append(rowErrs, classifiers.check(ctx, nomenclature)...))
+		if len(currencyErrs) > 0 {
+			continue
+		}
+
+		saveErr := repo.SaveNomenclature(ctx, nomenclature, nil, userId, companyId)
+		if saveErr != nil {
+			repo.NewErrorNomenclatureId(ctx, i, mappedFileName)
+			continue
+		}
+		sheet.Saved++
+		if stdErr := saveStandards(ctx, repo, nomenclature); stdErr != nil {
+			repo.NewErrorNomenclatureId(ctx, i, mappedFileName)
+		}
+		suggestCategory(ctx, repo, categories, nomenclature, sheet.CategoryHint)
+		prices.add(nomenclature, sheet.Sheet, i)
+	}
+
+	return nil
+}
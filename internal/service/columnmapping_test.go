@@ -0,0 +1,126 @@
+package service
+
+import (
+	"excel-service/internal/apperrors"
+	"excel-service/internal/models"
+	"testing"
+)
+
+func TestSuggestFields(t *testing.T) {
+	tests := []struct {
+		name      string
+		columns   []string
+		templates []*models.ColumnTemplate
+		want      map[int]fieldSuggestion
+		saved     string
+	}{
+		{
+			name:    "exact aliases",
+			columns: []string{"№", "Наименование товара", "Ед. изм.", "Кол-во", "Артикул", "Примечание"},
+			want: map[int]fieldSuggestion{
+				1: {mappingName, exactAliasConfidence},
+				2: {mappingMeasurement, exactAliasConfidence},
+				3: {mappingQuantity, exactAliasConfidence},
+				4: {mappingVendorCode, exactAliasConfidence},
+			},
+		},
+		{
+			name:    "цена с ндс is the price, not the VAT",
+			columns: []string{"Наименование", "Цена с НДС", "Ставка НДС"},
+			want: map[int]fieldSuggestion{
+				0: {mappingName, exactAliasConfidence},
+				1: {mappingPrice, exactAliasConfidence},
+				2: {mappingVat, exactAliasConfidence},
+			},
+		},
+		{
+			name:    "цена с ндс without a VAT column",
+			columns: []string{"Товар", "Цена с НДС, руб."},
+			want: map[int]fieldSuggestion{
+				0: {mappingName, exactAliasConfidence},
+				1: {mappingPrice, partAliasConfidence},
+			},
+		},
+		{
+			name:    "a field goes to one column, the longer match",
+			columns: []string{"Наименование", "Наименование товара"},
+			want: map[int]fieldSuggestion{
+				1: {mappingName, exactAliasConfidence},
+			},
+		},
+		{
+			name:    "saved template wins over aliases",
+			columns: []string{"Позиция", "Наименование", "Прим."},
+			templates: []*models.ColumnTemplate{
+				{Id: "small", Columns: map[string]string{mappingName: "Позиция"}},
+				{Id: "big", Columns: map[string]string{mappingName: "позиция", mappingVendorCode: "Прим."}},
+				{Id: "other", Columns: map[string]string{mappingName: "Позиция", mappingPrice: "Цена", mappingVat: "НДС"}},
+			},
+			want: map[int]fieldSuggestion{
+				0: {mappingName, 1},
+				2: {mappingVendorCode, 1},
+			},
+			saved: "big",
+		},
+		{
+			name:    "empty and unknown headers",
+			columns: []string{"", "Примечание"},
+			want:    map[int]fieldSuggestion{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, saved := suggestFields(tt.columns, tt.templates)
+			if len(got) != len(tt.want) {
+				t.Errorf("suggestions = %v, want %v", got, tt.want)
+			}
+			for col, want := range tt.want {
+				if got[col] != want {
+					t.Errorf("column %d = %v, want %v", col, got[col], want)
+				}
+			}
+			savedId := ""
+			if saved != nil {
+				savedId = saved.Id
+			}
+			if savedId != tt.saved {
+				t.Errorf("saved template = %q, want %q", savedId, tt.saved)
+			}
+		})
+	}
+}
+
+func TestValidateMapping(t *testing.T) {
+	tests := []struct {
+		name    string
+		columns map[string]int
+		width   int
+		ok      bool
+	}{
+		{"name only", map[string]int{mappingName: 0}, 3, true},
+		{"several fields", map[string]int{mappingName: 0, mappingPrice: 2, mappingVat: 1}, 3, true},
+		{"name missing", map[string]int{mappingPrice: 1}, 3, false},
+		{"empty", map[string]int{}, 3, false},
+		{"unknown field", map[string]int{mappingName: 0, "colour": 1}, 3, false},
+		{"column out of sheet", map[string]int{mappingName: 3}, 3, false},
+		{"negative column", map[string]int{mappingName: -1}, 3, false},
+		{"column used twice", map[string]int{mappingName: 1, mappingPrice: 1}, 3, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateMapping(tt.columns, tt.width)
+			if tt.ok {
+				if err != nil {
+					t.Errorf("validateMapping() = %v", err)
+				}
+				return
+			}
+			appErr, isApp := err.(*apperrors.Error)
+			if !isApp || appErr.Code != apperrors.ValidationFailed {
+				t.Errorf("validateMapping() = %v, want %s", err, apperrors.ValidationFailed)
+			}
+		})
+	}
+}
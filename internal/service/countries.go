@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"excel-service/internal/models"
+	"excel-service/internal/repository"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+const (
+	countryField = "country"
+	// domesticCountry is the OKSM code of Russia, the rest counts as import
+	// in the import substitution summary.
+	domesticCountry = "643"
+)
+
+// normalizeCountryCode pads numeric OKSM codes written without leading
+// zeros ("51" is Armenia, "051").
+func normalizeCountryCode(s string) string {
+	s = strings.TrimSpace(s)
+	if !isNumber(s) || len(s) > 3 {
+		return s
+	}
+	return strings.Repeat("0", 3-len(s)) + s
+}
+
+// countryResolver maps country strings of an import to OKSM codes. Keys
+// are normalized names, letter codes, numeric codes and aliases.
+type countryResolver struct {
+	codes map[string]string
+}
+
+func newCountryResolver(ctx context.Context, repo repository.ExcelRepository) *countryResolver {
+	countries, err := repo.SelectCountries(ctx)
+	if err != nil {
+		log.Warnf("countries are not normalized: %v", err)
+		return &countryResolver{}
+	}
+	return &countryResolver{codes: countryKeys(countries)}
+}
+
+func countryKeys(countries []*models.Country) map[string]string {
+	codes := map[string]string{}
+	for _, c := range countries {
+		for _, name := range append([]string{c.Code, c.Alpha2, c.Alpha3, c.NameRu, c.NameEn}, c.Aliases...) {
+			if key := normalizeText(name); key != "" {
+				codes[key] = c.Code
+			}
+		}
+	}
+	return codes
+}
+
+// resolve sets the country code of the item, a value the reference does not
+// know is reported and the item is saved without a country.
+func (r *countryResolver) resolve(n *models.Nomenclature, value string) []*models.UploadRowError {
+	value = strings.TrimSpace(value)
+	if value == "" || r.codes == nil {
+		return nil
+	}
+	if code, ok := r.codes[normalizeText(normalizeCountryCode(value))]; ok {
+		n.CountryCode = code
+		return nil
+	}
+	return []*models.UploadRowError{{Field: countryField, Value: value, Message: "страна не найдена в ОКСМ"}}
+}
+
+// parseCountries reads an OKSM file: numeric code, short name, full name,
+// alpha-2, alpha-3 and an optional English name. The full name becomes an
+// alias. The header and rows without a numeric code are skipped.
+func parseCountries(rows [][]string) ([]*models.Country, int) {
+	var countries []*models.Country
+	skipped := 0
+	for i, row := range rows {
+		cells := make([]string, 6)
+		for j := range cells {
+			if j < len(row) {
+				cells[j] = strings.TrimSpace(row[j])
+			}
+		}
+		code := normalizeCountryCode(cells[0])
+		if len(code) != 3 || !isNumber(code) || cells[1] == "" || len(cells[3]) != 2 || len(cells[4]) != 3 {
+			if i > 0 && strings.Join(cells, "") != "" {
+				skipped++
+			}
+			continue
+		}
+
+		c := &models.Country{
+			Code:   code,
+			NameRu: cells[1],
+			Alpha2: strings.ToUpper(cells[3]),
+			Alpha3: strings.ToUpper(cells[4]),
+			NameEn: cells[5],
+		}
+		if cells[2] != "" && cells[2] != cells[1] {
+			c.Aliases = []string{normalizeText(cells[2])}
+		}
+		countries = append(countries, c)
+	}
+	return countries, skipped
+}
+
+func (e ExcelServiceImpl) ImportCountries(ctx context.Context, file *multipart.FileHeader) (*models.CountryImport, error) {
+	src, err := file.Open()
+	if err != nil {
+		log.Errorf("failed to open file: %v", err)
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	defer src.Close()
+
+	excelFile, fileErr := openWorkbook(src, e.cfg.Limits)
+	if fileErr != nil {
+		return nil, fileErr
+	}
+	rows, rowsErr := readRows(excelFile, excelFile.GetSheetList()[0], e.cfg.Limits)
+	if rowsErr != nil {
+		return nil, rowsErr
+	}
+
+	countries, skipped := parseCountries(rows)
+	if len(countries) == 0 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("в файле нет стран ОКСМ, пропущено строк: %d", skipped))
+	}
+	if saveErr := e.repo.SaveCountries(ctx, countries); saveErr != nil {
+		return nil, saveErr
+	}
+	return &models.CountryImport{Countries: len(countries), Skipped: skipped}, nil
+}
+
+func (e ExcelServiceImpl) GetCountries(ctx context.Context) ([]*models.Country, error) {
+	return e.repo.SelectCountries(ctx)
+}
+
+func (e ExcelServiceImpl) GetCountrySummary(ctx context.Context) ([]*models.CountrySummary, error) {
+	summary, err := e.repo.SelectCountrySummary(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range summary {
+		s.Domestic = s.Code == domesticCountry
+	}
+	return summary, nil
+}
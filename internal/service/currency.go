@@ -0,0 +1,251 @@
+package service
+
+import (
+	"context"
+	"encoding/xml"
+	"excel-service/internal/models"
+	"excel-service/internal/repository"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"golang.org/x/text/encoding/charmap"
+)
+
+const (
+	defaultCurrency = "RUB"
+	currencyField   = "currency"
+)
+
+var currencyAliases = map[string]string{
+	"rub":     "RUB",
+	"rur":     "RUB",
+	"руб":     "RUB",
+	"рубль":   "RUB",
+	"рубли":   "RUB",
+	"рублей":  "RUB",
+	"р":       "RUB",
+	"₽":       "RUB",
+	"usd":     "USD",
+	"$":       "USD",
+	"us$":     "USD",
+	"долл":    "USD",
+	"доллар":  "USD",
+	"доллары": "USD",
+	"eur":     "EUR",
+	"€":       "EUR",
+	"евро":    "EUR",
+	"cny":     "CNY",
+	"юань":    "CNY",
+	"юани":    "CNY",
+}
+
+var (
+	currencyCodeReg   = regexp.MustCompile(`^[A-Z]{3}$`)
+	currencyHeaderReg = regexp.MustCompile(`(?i)^\s*(валюта|currency)`)
+)
+
+// normalizeCurrency turns "руб.", "$", "usd" or "Евро" into an ISO 4217 code.
+func normalizeCurrency(s string) (string, bool) {
+	key := strings.ToLower(strings.Trim(strings.TrimSpace(s), "."))
+	if code, ok := currencyAliases[key]; ok {
+		return code, true
+	}
+	if code := strings.ToUpper(key); currencyCodeReg.MatchString(code) {
+		return code, true
+	}
+	return "", false
+}
+
+// currencyChecker knows the codes of the currency dictionary, a price in a
+// currency it lacks would be saved without one.
+type currencyChecker struct {
+	codes map[string]bool
+}
+
+func newCurrencyChecker(ctx context.Context, repo repository.ExcelRepository) (*currencyChecker, error) {
+	codes, err := repo.SelectCurrencyCodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &currencyChecker{codes: codes}, nil
+}
+
+// resolve sets the currency of the item from the row value, the currency of
+// the file when the value is empty. An unknown value and a code missing from
+// the dictionary are reported, such rows are not saved.
+func (c *currencyChecker) resolve(n *models.Nomenclature, value, fileCurrency string) []*models.UploadRowError {
+	n.Currency = fileCurrency
+	if value = strings.TrimSpace(value); value != "" {
+		code, ok := normalizeCurrency(value)
+		if !ok {
+			return []*models.UploadRowError{{Field: currencyField, Value: value, Message: "неизвестная валюта, строка не загружена"}}
+		}
+		n.Currency = code
+	}
+	if !c.codes[n.Currency] {
+		return []*models.UploadRowError{{Field: currencyField, Value: n.Currency, Message: "валюты нет в справочнике, строка не загружена"}}
+	}
+	return nil
+}
+
+// detectCurrency looks for a "Валюта" column in the header rows. Without such
+// a column the currency may be given in a header, e.g. "Цена за ед., USD" or
+// "Валюта: EUR", and then applies to the whole file.
+func detectCurrency(header [][]string) (int, string) {
+	templateCurrency := defaultCurrency
+	for _, row := range header {
+		for i, cell := range row {
+			if !currencyHeaderReg.MatchString(cell) {
+				if code, ok := headerCurrency(cell); ok && strings.Contains(strings.ToLower(cell), "цена") {
+					templateCurrency = code
+				}
+				continue
+			}
+			if parts := strings.SplitN(cell, ":", 2); len(parts) == 2 {
+				if code, ok := normalizeCurrency(parts[1]); ok {
+					templateCurrency = code
+					continue
+				}
+			}
+			return i, templateCurrency
+		}
+	}
+	return -1, templateCurrency
+}
+
+func headerCurrency(cell string) (string, bool) {
+	parts := strings.FieldsFunc(cell, func(r rune) bool { return r == ',' || r == '(' || r == ')' || r == ' ' })
+	if len(parts) < 2 {
+		return "", false
+	}
+	return normalizeCurrency(parts[len(parts)-1])
+}
+
+type cbrValCurs struct {
+	Date   string `xml:"Date,attr"`
+	Valute []struct {
+		CharCode string `xml:"CharCode"`
+		Nominal  string `xml:"Nominal"`
+		Value    string `xml:"Value"`
+	} `xml:"Valute"`
+}
+
+// parseCbrRates reads the XML_daily.asp document of the Central Bank of Russia.
+func parseCbrRates(r io.Reader) (*models.CurrencyRates, error) {
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		if strings.EqualFold(label, "windows-1251") {
+			return charmap.Windows1251.NewDecoder().Reader(input), nil
+		}
+		return nil, fmt.Errorf("unsupported charset %s", label)
+	}
+
+	var curs cbrValCurs
+	if err := decoder.Decode(&curs); err != nil {
+		return nil, err
+	}
+
+	date, dateErr := time.Parse("02.01.2006", curs.Date)
+	if dateErr != nil {
+		return nil, fmt.Errorf("wrong rates date %q: %v", curs.Date, dateErr)
+	}
+
+	res := &models.CurrencyRates{Date: date}
+	for _, v := range curs.Valute {
+		nominal, nominalErr := strconv.Atoi(strings.TrimSpace(v.Nominal))
+		if nominalErr != nil {
+			return nil, fmt.Errorf("wrong nominal of %s: %v", v.CharCode, nominalErr)
+		}
+		value, valueErr := parseDecimal(v.Value)
+		if valueErr != nil {
+			return nil, fmt.Errorf("wrong rate of %s: %v", v.CharCode, valueErr)
+		}
+		res.Rates = append(res.Rates, &models.CurrencyRate{
+			Code:    strings.TrimSpace(v.CharCode),
+			Date:    date,
+			Nominal: nominal,
+			Value:   value,
+		})
+	}
+	return res, nil
+}
+
+func (e ExcelServiceImpl) fetchCbrRates(ctx context.Context, date time.Time) (*models.CurrencyRates, error) {
+	ratesUrl, urlErr := url.Parse(e.cfg.Cbr.RatesUrl)
+	if urlErr != nil {
+		log.Errorf("wrong CBR rates url: %v", urlErr)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, urlErr)
+	}
+	query := ratesUrl.Query()
+	query.Set("date_req", date.Format("02/01/2006"))
+	ratesUrl.RawQuery = query.Encode()
+
+	req, reqErr := http.NewRequestWithContext(ctx, http.MethodGet, ratesUrl.String(), nil)
+	if reqErr != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, reqErr)
+	}
+
+	resp, respErr := http.DefaultClient.Do(req)
+	if respErr != nil {
+		log.Errorf("failed to get CBR rates: %v", respErr)
+		return nil, echo.NewHTTPError(http.StatusBadGateway, "failed to get exchange rates")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		log.Errorf("CBR rates responded with %s", resp.Status)
+		return nil, echo.NewHTTPError(http.StatusBadGateway, "failed to get exchange rates")
+	}
+
+	return parseCbrRates(resp.Body)
+}
+
+// ImportCurrencyRates saves the CBR daily rates. The XML document is taken
+// from src when it is given, otherwise it is downloaded for the date.
+func (e ExcelServiceImpl) ImportCurrencyRates(ctx context.Context, date time.Time, src io.Reader) (*models.CurrencyRates, error) {
+	var rates *models.CurrencyRates
+	var err error
+	if src != nil {
+		rates, err = parseCbrRates(src)
+		if err != nil {
+			log.Errorf("failed to parse CBR rates: %v", err)
+			return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+	} else {
+		rates, err = e.fetchCbrRates(ctx, date)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if saveErr := e.repo.SaveCurrencyRates(ctx, rates.Rates); saveErr != nil {
+		return nil, saveErr
+	}
+
+	log.Infof("saved %d exchange rates for %s", len(rates.Rates), rates.Date.Format("2006-01-02"))
+	return rates, nil
+}
+
+func (e ExcelServiceImpl) rubRate(ctx context.Context, currency string, date time.Time) (float64, time.Time, error) {
+	if currency == defaultCurrency {
+		return 1, date, nil
+	}
+	rate, err := e.repo.SelectCurrencyRate(ctx, currency, date)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	return rate.Value / float64(rate.Nominal), rate.Date, nil
+}
+
+func roundMoney(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"excel-service/internal/models"
+	"excel-service/internal/repository"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+const (
+	// minDuplicateScore is the lowest name similarity that goes to the
+	// review queue when no code matches.
+	minDuplicateScore     = 0.6
+	duplicateCandidateCap = 20
+
+	duplicatePending   = "pending"
+	duplicateConfirmed = "confirmed"
+	duplicateRejected  = "rejected"
+	duplicateMerged    = "merged"
+)
+
+// nameSimilarity is the Jaccard index of the stemmed words of two names.
+// Names with different numbers ("57х3,5" and "89х4") are different sizes of
+// a product and get 0.
+func nameSimilarity(a, b string) float64 {
+	wordsA, numbersA := nameFeatures(a)
+	wordsB, numbersB := nameFeatures(b)
+	if numbersA != numbersB || len(wordsA) == 0 || len(wordsB) == 0 {
+		return 0
+	}
+
+	common := 0
+	for w := range wordsA {
+		if wordsB[w] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(wordsA)+len(wordsB)-common)
+}
+
+func nameFeatures(name string) (map[string]bool, string) {
+	words := map[string]bool{}
+	var numbers []string
+	for _, token := range strings.Fields(normalizeText(name)) {
+		if strings.ContainsAny(token, "0123456789") {
+			numbers = append(numbers, token)
+			continue
+		}
+		if utf8.RuneCountInString(token) > 1 && !stopWords[token] {
+			words[stem(token)] = true
+		}
+	}
+	sort.Strings(numbers)
+	return words, strings.Join(numbers, " ")
+}
+
+func sameCode(a, b string) bool {
+	a, b = strings.TrimSpace(a), strings.TrimSpace(b)
+	return a != "" && strings.EqualFold(a, b)
+}
+
+func conflictingCode(a, b string) bool {
+	a, b = strings.TrimSpace(a), strings.TrimSpace(b)
+	return a != "" && b != "" && !strings.EqualFold(a, b)
+}
+
+// matchDuplicate scores a pair: a shared SKMTR, KS NSI or AMTO code is a
+// sure match, the same manufacturer and mark almost sure, otherwise the
+// name similarity decides. Different codes of the same kind rule a pair out.
+func matchDuplicate(item, candidate *models.MatchItem) (float64, []string) {
+	var reasons []string
+	score := 0.0
+	codes := []struct {
+		reason string
+		a, b   string
+	}{
+		{"code_skmtr", item.CodeSkmtr, candidate.CodeSkmtr},
+		{"code_ks_nsi", item.CodeKsNsi, candidate.CodeKsNsi},
+		{"code_amto", item.CodeAmto, candidate.CodeAmto},
+	}
+	conflict := false
+	for _, code := range codes {
+		if sameCode(code.a, code.b) {
+			reasons = append(reasons, code.reason)
+			score = 1
+		} else if conflictingCode(code.a, code.b) {
+			conflict = true
+		}
+	}
+	if conflict && score < 1 {
+		return 0, nil
+	}
+
+	if normalizeText(item.Manufacturer) != "" && normalizeText(item.TmcMark) != "" &&
+		normalizeText(item.Manufacturer) == normalizeText(candidate.Manufacturer) &&
+		normalizeText(item.TmcMark) == normalizeText(candidate.TmcMark) {
+		reasons = append(reasons, "manufacturer_mark")
+		score = math.Max(score, 0.9)
+	}
+
+	if similarity := nameSimilarity(item.Name, candidate.Name); similarity >= minDuplicateScore {
+		reasons = append(reasons, "name")
+		score = math.Max(score, similarity)
+	}
+	return math.Round(score*1000) / 1000, reasons
+}
+
+// findUploadDuplicates queues probable duplicates of the nomenclature created
+// by the upload.
+func findUploadDuplicates(ctx context.Context, repo repository.ExcelRepository, uploadId string) (*models.DuplicateRun, error) {
+	items, err := repo.SelectUploadMatchItems(ctx, uploadId)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &models.DuplicateRun{Checked: len(items)}
+	for _, item := range items {
+		candidates, candErr := repo.SelectDuplicateCandidates(ctx, item, uploadId, duplicateCandidateCap)
+		if candErr != nil {
+			return nil, candErr
+		}
+
+		var found []*models.DuplicateCandidate
+		for _, candidate := range candidates {
+			score, reasons := matchDuplicate(item, candidate)
+			if len(reasons) == 0 {
+				continue
+			}
+			found = append(found, &models.DuplicateCandidate{NomenclatureId: item.Id, DuplicateId: candidate.Id, Score: score, Reasons: reasons})
+		}
+		if len(found) == 0 {
+			continue
+		}
+		if saveErr := repo.SaveDuplicateCandidates(ctx, found); saveErr != nil {
+			return nil, saveErr
+		}
+		res.Found += len(found)
+	}
+
+	log.Infof("upload %s: %d duplicate candidates for %d items", uploadId, res.Found, res.Checked)
+	return res, nil
+}
+
+func (e ExcelServiceImpl) FindUploadDuplicates(ctx context.Context, uploadId string) (*models.DuplicateRun, error) {
+	if err := e.authorizeUpload(ctx, uploadId); err != nil {
+		return nil, err
+	}
+	return findUploadDuplicates(ctx, e.repo, uploadId)
+}
+
+func (e ExcelServiceImpl) GetDuplicateCandidates(ctx context.Context, status string) ([]*models.DuplicateCandidate, error) {
+	if status == "" {
+		status = duplicatePending
+	}
+	switch status {
+	case duplicatePending, duplicateConfirmed, duplicateRejected, duplicateMerged:
+	default:
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "status must be pending, confirmed, rejected or merged")
+	}
+	return e.repo.SelectDuplicateQueue(ctx, status)
+}
+
+func (e ExcelServiceImpl) ReviewDuplicates(ctx context.Context, req *models.DuplicateReviewReq) (*models.DuplicateReview, error) {
+	if len(req.Confirm) == 0 && len(req.Reject) == 0 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "nothing to confirm or reject")
+	}
+	for _, id := range req.Confirm {
+		for _, rejected := range req.Reject {
+			if id == rejected {
+				return nil, echo.NewHTTPError(http.StatusBadRequest, "candidate "+id+" is both confirmed and rejected")
+			}
+		}
+	}
+	return e.repo.ReviewDuplicates(ctx, req.Confirm, req.Reject)
+}
+
+func (e ExcelServiceImpl) MergeDuplicate(ctx context.Context, candidateId string) (*models.DuplicateMerge, error) {
+	tx, txErr := e.db.Begin(ctx)
+	if txErr != nil {
+		log.Errorf("failed to begin tx: %v", txErr)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, txErr)
+	}
+
+	res, err := e.repo.MergeDuplicate(ctx, candidateId, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	if cErr := tx.Commit(ctx); cErr != nil {
+		log.Errorf("failed to commit tx in MergeDuplicate: %v", cErr)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, cErr)
+	}
+	return res, nil
+}
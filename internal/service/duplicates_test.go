@@ -0,0 +1,104 @@
+package service
+
+import (
+	"excel-service/internal/models"
+	"reflect"
+	"testing"
+)
+
+func TestNameSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		min  float64
+		max  float64
+	}{
+		{"same name", "Труба стальная бесшовная 57х3,5", "Труба стальная бесшовная 57х3,5", 1, 1},
+		{"case and punctuation", "Труба стальная, бесшовная 57х3,5", "ТРУБА СТАЛЬНАЯ БЕСШОВНАЯ 57х3,5", 1, 1},
+		{"word forms", "Трубы стальные 57х3,5", "Труба стальная 57х3,5", 1, 1},
+		{"different size", "Труба стальная 57х3,5", "Труба стальная 89х4", 0, 0},
+		{"one word differs", "Труба стальная бесшовная 57х3,5", "Труба стальная электросварная 57х3,5", 0.4, 0.6},
+		{"different products", "Кабель силовой", "Труба стальная", 0, 0},
+		{"empty", "", "Труба", 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := nameSimilarity(tt.a, tt.b)
+			if got < tt.min || got > tt.max {
+				t.Errorf("nameSimilarity(%q, %q) = %v, want %v..%v", tt.a, tt.b, got, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestMatchDuplicate(t *testing.T) {
+	tests := []struct {
+		name      string
+		item      *models.MatchItem
+		candidate *models.MatchItem
+		score     float64
+		reasons   []string
+	}{
+		{
+			name:      "shared skmtr code",
+			item:      &models.MatchItem{Name: "Труба 57", CodeSkmtr: "123"},
+			candidate: &models.MatchItem{Name: "Кабель", CodeSkmtr: "123"},
+			score:     1,
+			reasons:   []string{"code_skmtr"},
+		},
+		{
+			name:      "codes match ignoring case and spaces",
+			item:      &models.MatchItem{CodeAmto: " ab-1 "},
+			candidate: &models.MatchItem{CodeAmto: "AB-1"},
+			score:     1,
+			reasons:   []string{"code_amto"},
+		},
+		{
+			name:      "conflicting code rules out a similar name",
+			item:      &models.MatchItem{Name: "Труба стальная 57х3,5", CodeKsNsi: "1"},
+			candidate: &models.MatchItem{Name: "Труба стальная 57х3,5", CodeKsNsi: "2"},
+			score:     0,
+		},
+		{
+			name:      "shared code wins over another conflicting code",
+			item:      &models.MatchItem{CodeSkmtr: "123", CodeAmto: "1"},
+			candidate: &models.MatchItem{CodeSkmtr: "123", CodeAmto: "2"},
+			score:     1,
+			reasons:   []string{"code_skmtr"},
+		},
+		{
+			name:      "manufacturer and mark",
+			item:      &models.MatchItem{Name: "Насос", Manufacturer: "Grundfos", TmcMark: "CR 10-2"},
+			candidate: &models.MatchItem{Name: "Агрегат", Manufacturer: "GRUNDFOS", TmcMark: "cr 10-2"},
+			score:     0.9,
+			reasons:   []string{"manufacturer_mark"},
+		},
+		{
+			name:      "manufacturer without mark is no match",
+			item:      &models.MatchItem{Name: "Насос", Manufacturer: "Grundfos"},
+			candidate: &models.MatchItem{Name: "Агрегат", Manufacturer: "Grundfos"},
+			score:     0,
+		},
+		{
+			name:      "similar name",
+			item:      &models.MatchItem{Name: "Труба стальная бесшовная 57х3,5"},
+			candidate: &models.MatchItem{Name: "Трубы стальные бесшовные 57х3,5"},
+			score:     1,
+			reasons:   []string{"name"},
+		},
+		{
+			name:      "name below the threshold",
+			item:      &models.MatchItem{Name: "Труба стальная бесшовная 57х3,5"},
+			candidate: &models.MatchItem{Name: "Труба стальная электросварная 57х3,5"},
+			score:     0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, reasons := matchDuplicate(tt.item, tt.candidate)
+			if score != tt.score || !reflect.DeepEqual(reasons, tt.reasons) {
+				t.Errorf("matchDuplicate() = %v, %v, want %v, %v", score, reasons, tt.score, tt.reasons)
+			}
+		})
+	}
+}
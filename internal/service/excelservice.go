@@ -3,18 +3,53 @@ package service
 import (
 	"context"
 	"excel-service/internal/models"
+	"io"
 	"mime/multipart"
+	"time"
 )
 
 type ExcelService interface {
-	SaveExcelFile(ctx context.Context, file *multipart.FileHeader) (*models.ResponseMsg, error)
-	SaveMTRExcelFile(ctx context.Context, file *multipart.FileHeader) (*models.ResponseMsg, error)
-	SaveCategory(ctx context.Context, file *multipart.FileHeader) (*models.ResponseMsg, error)
-	CreateCompany(ctx context.Context, file *multipart.FileHeader) (*models.ResponseMsg, error)
-	SaveOrganizerNomenclature(ctx context.Context, file *multipart.FileHeader) (*models.ResponseMsg, error)
-	SaveBanks(ctx context.Context, file *multipart.FileHeader) (*models.ResponseMsg, error)
+	SaveExcelFile(ctx context.Context, file *multipart.FileHeader, sheet models.SheetSelector) (*models.ResponseMsg, error)
+	SaveMTRExcelFile(ctx context.Context, file *multipart.FileHeader, sheet models.SheetSelector) (*models.ResponseMsg, error)
+	SaveCategory(ctx context.Context, file *multipart.FileHeader, sheet models.SheetSelector) (*models.ResponseMsg, error)
+	CreateCompany(ctx context.Context, file *multipart.FileHeader, sheet models.SheetSelector) (*models.ResponseMsg, error)
+	SaveOrganizerNomenclature(ctx context.Context, file *multipart.FileHeader, sheet models.SheetSelector) (*models.ResponseMsg, error)
+	SaveBanks(ctx context.Context, file *multipart.FileHeader, sheet models.SheetSelector) (*models.ResponseMsg, error)
 	GetExcelFromAwsByFileId(ctx context.Context, req *models.GetExcelFromAwsByFileIdReq) (*models.ResponseMsg, error)
 	UploadExcelFile(ctx context.Context, file *multipart.FileHeader, companuyName string) (*models.ResponseMsg, error)
-	SaveNomenclatureFromDirectus(ctx context.Context, req *models.DirectusModel) (*models.ResponseMsg, error)
-	GetFileColumns(ctx context.Context, req *models.DirectusModel) ([]*models.FileColumns, error)
+	SaveNomenclatureFromDirectus(ctx context.Context, req *models.DirectusModel, idempotencyKey string) (*models.ResponseMsg, error)
+	GetFileColumns(ctx context.Context, req *models.DirectusModel) (*models.ColumnDraft, error)
+	SetColumnMapping(ctx context.Context, uploadId string, req *models.ColumnMappingReq) (*models.ColumnMappingRun, error)
+	GetColumnTemplates(ctx context.Context, companyId string) ([]*models.ColumnTemplate, error)
+	RollbackUpload(ctx context.Context, uploadId string) (*models.UploadRollback, error)
+	GetPriceListVersions(ctx context.Context, priceId string) ([]*models.PriceListVersion, error)
+	GetPriceListDiff(ctx context.Context, priceId string, fromVersion, toVersion int) (*models.PriceListDiff, error)
+	GetNomenclaturePrice(ctx context.Context, nomenclatureId string, quantity float64, date time.Time) (*models.PriceQuote, error)
+	ImportCurrencyRates(ctx context.Context, date time.Time, src io.Reader) (*models.CurrencyRates, error)
+	TestExtractionRules(ctx context.Context, req *models.ExtractionTestReq) ([]*models.ExtractionResult, error)
+	GetNomenclatureByStandard(ctx context.Context, standard string) ([]*models.StandardNomenclature, error)
+	ImportClassifier(ctx context.Context, kind string, file *multipart.FileHeader) (*models.ClassifierImport, error)
+	GetClassifierCode(ctx context.Context, kind, code string) (*models.ClassifierLookup, error)
+	GetUploadRowErrors(ctx context.Context, uploadId string) ([]*models.UploadRowError, error)
+	ExportUploadRowErrors(ctx context.Context, uploadId, format string) (*models.ExportFile, error)
+	SuggestCategories(ctx context.Context) (*models.CategorySuggestionRun, error)
+	GetCategorySuggestions(ctx context.Context, status string) ([]*models.CategorySuggestion, error)
+	ReviewCategorySuggestions(ctx context.Context, req *models.CategoryReviewReq) (*models.CategoryReview, error)
+	ImportCategoryTree(ctx context.Context, file *multipart.FileHeader) (*models.CategoryTreeImport, error)
+	GetCategoryTree(ctx context.Context) ([]*models.CategoryNode, error)
+	FindUploadDuplicates(ctx context.Context, uploadId string) (*models.DuplicateRun, error)
+	GetDuplicateCandidates(ctx context.Context, status string) ([]*models.DuplicateCandidate, error)
+	ReviewDuplicates(ctx context.Context, req *models.DuplicateReviewReq) (*models.DuplicateReview, error)
+	MergeDuplicate(ctx context.Context, candidateId string) (*models.DuplicateMerge, error)
+	LinkSupplierItemsToMtr(ctx context.Context) (*models.MtrLinkRun, error)
+	GetUnmatchedSupplierItems(ctx context.Context, limit, offset int) ([]*models.MatchItem, error)
+	SetMtrLink(ctx context.Context, nomenclatureId string, req *models.MtrLinkReq) (*models.MtrLink, error)
+	ImportManufacturers(ctx context.Context, file *multipart.FileHeader) (*models.ManufacturerImport, error)
+	GetManufacturers(ctx context.Context) ([]*models.Manufacturer, error)
+	GetUnresolvedManufacturers(ctx context.Context) ([]*models.UnresolvedManufacturer, error)
+	ResolveManufacturer(ctx context.Context, req *models.ManufacturerResolveReq) (*models.ManufacturerResolve, error)
+	ImportCountries(ctx context.Context, file *multipart.FileHeader) (*models.CountryImport, error)
+	GetCountries(ctx context.Context) ([]*models.Country, error)
+	GetCountrySummary(ctx context.Context) ([]*models.CountrySummary, error)
+	Authenticate(ctx context.Context, token string) (*models.Principal, error)
 }
@@ -2,35 +2,52 @@ package service
 
 import (
 	"context"
+	"excel-service/internal/apperrors"
+	"excel-service/internal/auth"
 	"excel-service/internal/configs"
 	"excel-service/internal/models"
 	"excel-service/internal/repository"
 	"fmt"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/gommon/log"
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
 	container "github.com/vielendanke/go-db-lb"
-	"github.com/xuri/excelize/v2"
 	"mime/multipart"
 	"net/http"
-	"regexp"
 	"strconv"
 	"strings"
 )
 
 type ExcelServiceImpl struct {
-	repo repository.ExcelRepository
-	lb   *container.LoadBalancer
-	cfg  *configs.Configs
+	repo    repository.ExcelRepository
+	lb      *container.LoadBalancer
+	cfg     *configs.Configs
+	storage fileStorage
+	db      txBeginner
 }
 
 func NewExcelService(repo repository.ExcelRepository, lb *container.LoadBalancer, cfg *configs.Configs) ExcelService {
-	return &ExcelServiceImpl{repo: repo, lb: lb, cfg: cfg}
+	return &ExcelServiceImpl{repo: repo, lb: lb, cfg: cfg, storage: minioStorage{cfg: cfg.Aws}, db: primaryDB{lb: lb}}
 }
 
-func (e ExcelServiceImpl) SaveExcelFile(ctx context.Context, file *multipart.FileHeader) (*models.ResponseMsg, error) {
+// txBeginner starts the transactions the repository methods run in.
+type txBeginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// primaryDB begins transactions on the primary of the load balancer.
+type primaryDB struct {
+	lb *container.LoadBalancer
+}
+
+func (d primaryDB) Begin(ctx context.Context) (pgx.Tx, error) {
+	return d.lb.CallPrimaryPreferred().PGxPool().Begin(ctx)
+}
+
+func (e ExcelServiceImpl) SaveExcelFile(ctx context.Context, file *multipart.FileHeader, sheet models.SheetSelector) (*models.ResponseMsg, error) {
 	src, err := file.Open()
 	if err != nil {
 		log.Errorf("failed ti open file: %v", err)
@@ -39,35 +56,76 @@ func (e ExcelServiceImpl) SaveExcelFile(ctx context.Context, file *multipart.Fil
 
 	defer src.Close()
 
-	excelFile, fileErr := excelize.OpenReader(src)
+	excelFile, fileErr := openWorkbook(src, e.cfg.Limits)
 	if fileErr != nil {
-		log.Errorf("failed to open reader: %v", fileErr)
-		return nil, echo.NewHTTPError(http.StatusBadRequest, fileErr)
+		return nil, fileErr
 	}
 
-	// Get all the rows in the Sheet1.
-	rows, rowsErr := excelFile.GetRows("Шаблон")
-	if rowsErr != nil {
-		log.Errorf("failed to read sheet: %v", rowsErr)
-		return nil, echo.NewHTTPError(http.StatusBadRequest, "Не правильный наименование страницы excel файла. Переименуйте на Лист1")
+	sheetNames, sheetErr := selectSheets(excelFile, sheet, "Шаблон", mtrTemplate)
+	if sheetErr != nil {
+		return nil, sheetErr
 	}
 
-	saveErr := NewMTRFile(rows, e.repo, ctx, "", "")
-	if saveErr != nil {
-		return nil, saveErr
+	uploadId, uploadErr := e.newDirectUpload(ctx)
+	if uploadErr != nil {
+		return nil, uploadErr
 	}
 
-	return &models.ResponseMsg{Message: "success"}, nil
+	res := &models.ResponseMsg{Message: "success", UploadId: uploadId}
+	for _, sheetName := range sheetNames {
+		rows, rowsErr := readRows(excelFile, sheetName, e.cfg.Limits)
+		if rowsErr != nil {
+			e.finishDirectUpload(ctx, uploadId, rowsErr)
+			return nil, rowsErr
+		}
+
+		report := newSheetReport(file.Filename, sheetName, templateLayout(excelFile, sheetName, rows, mtrTemplate))
+		saveErr := NewMTRFile(rows, e.repo, ctx, "", "", uploadId, report)
+		if saveErr != nil {
+			e.finishDirectUpload(ctx, uploadId, saveErr)
+			return nil, saveErr
+		}
+		res.Sheets = append(res.Sheets, report)
+	}
+
+	e.finishDirectUpload(ctx, uploadId, nil)
+	return res, nil
 }
 
-func newSupplierNomenclature(rows [][]string, priceLists []string, repo repository.ExcelRepository, ctx context.Context, companyId, userId string) error {
+func newSupplierNomenclature(rows [][]string, prices *uploadPrices, repo repository.ExcelRepository, ctx context.Context, companyId, userId, uploadId string, sheet *models.SheetReport) error {
+	currencyCol, templateCurrency := -1, defaultCurrency
+	priceIncludesVat := true
+	storageCol := -1
+	if sheet.DataRow > sheet.HeaderRow && sheet.DataRow-1 <= len(rows) {
+		header := rows[sheet.HeaderRow-1 : sheet.DataRow-1]
+		currencyCol, templateCurrency = detectCurrency(header)
+		priceIncludesVat = detectPriceIncludesVat(header)
+		storageCol = detectStorageColumn(header)
+	}
+	currencies, currencyErr := newCurrencyChecker(ctx, repo)
+	if currencyErr != nil {
+		return currencyErr
+	}
+	classifiers := newClassifierChecker(ctx, repo)
+	categories, modelErr := loadCategoryModel(ctx, repo)
+	if modelErr != nil {
+		log.Warnf("category suggestions are off: %v", modelErr)
+	}
+	extract, extractErr := loadExtractor(ctx, repo)
+	if extractErr != nil {
+		return extractErr
+	}
+	manufacturers := newManufacturerResolver(ctx, repo)
+	defer manufacturers.flush(ctx, repo, uploadId)
 	for i, row := range rows {
-		if i == 0 || i == 1 {
+		if i+1 < sheet.DataRow {
 			continue
 		}
 		nomenclature := &models.Nomenclature{}
 		nomenclature.Id = uuid.New().String()
 		nomenclature.PackageId = uuid.New().String()
+		nomenclature.UploadId = uploadId
+		nomenclature.Source = sourceSupplier
 		nomenclature.CodeSkmtr = row[0]
 		nomenclature.CodeKsNsi = row[1]
 		nomenclature.CodeAmto = row[2]
@@ -76,7 +134,7 @@ func newSupplierNomenclature(rows [][]string, priceLists []string, repo reposito
 		nomenclature.Name = row[5]
 		nomenclature.TmcCodeVendor = row[7]
 		nomenclature.TmcMark = row[8]
-		nomenclature.GostTu = row[9]
+		collectStandards(nomenclature, row[9], row[5])
 		if len(row) > 10 {
 			nomenclature.DateOfManufacture = row[10]
 		}
@@ -87,20 +145,21 @@ func newSupplierNomenclature(rows [][]string, priceLists []string, repo reposito
 			nomenclature.BatchNumber = row[12]
 		}
 		//nomenclature.CompanyInn = companyInn
-		if len(row) > 13 && row[13] == "облагается" {
-			nomenclature.IsTax = true
+		var vatNotation string
+		if len(row) > 13 {
+			vatNotation = row[13]
 		}
-		if len(row) > 14 && nomenclature.IsTax {
-			taxPercentage, taxErr := strconv.ParseFloat(row[14], 8)
-			if taxErr != nil {
-				log.Errorf("failed to parse string to float: %v", taxErr)
-			} else {
-				nomenclature.TaxPercentage = float32(taxPercentage)
-			}
+		if len(row) > 14 {
+			vatNotation += " " + row[14]
+		}
+		vat, vatErr := parseVat(vatNotation)
+		var vatErrs []*models.UploadRowError
+		if vatErr != nil {
+			vatErrs = append(vatErrs, &models.UploadRowError{Field: mappingVat, Value: strings.TrimSpace(vatNotation), Message: "ставка НДС не распознана"})
 		}
 
-		if len(priceLists) > 0 {
-			nomenclature.PriceLists = priceLists
+		if len(prices.lists) > 0 {
+			nomenclature.PriceLists = prices.lists
 		}
 
 		if len(row) > 16 {
@@ -112,6 +171,14 @@ func newSupplierNomenclature(rows [][]string, priceLists []string, repo reposito
 			}
 		}
 
+		applyVat(nomenclature, vat, priceIncludesVat)
+
+		var rowCurrency string
+		if currencyCol >= 0 && len(row) > currencyCol {
+			rowCurrency = row[currencyCol]
+		}
+		currencyErrs := currencies.resolve(nomenclature, rowCurrency, templateCurrency)
+
 		if len(row) > 16 {
 			nomenclature.Measurement = row[16]
 		}
@@ -119,24 +186,20 @@ func newSupplierNomenclature(rows [][]string, priceLists []string, repo reposito
 			nomenclature.PriceValidThrough = row[17]
 		}
 
-		wholesaleItems := &models.WholesaleItems{}
-
+		var wholesalePrice, wholesaleRange string
 		if len(row) > 18 {
-			wholesaleItems.WholesalePricePerUnit = row[18]
-		}
-		if len(row) > 19 && row[19] != "" {
-			orderDateArr := strings.Split(row[19], "и")
-			if len(orderDateArr) == 2 {
-				// wholesaleOrderFrom, woFromErr := strconv.Atoi(orderDateArr[0])
-				// if woFromErr != nil {
-				// 	log.Errorf("failed to parse string to int: %v", woFromErr)
-				// }
-				wholesaleItems.WholesaleOrderFrom = orderDateArr[0]
-
-				wholesaleItems.WholesaleOrderTo = orderDateArr[1]
-			}
-
+			wholesalePrice = row[18]
+		}
+		if len(row) > 19 {
+			wholesaleRange = row[19]
 		}
+		tiers, tiersErr := parsePriceTiers(wholesalePrice, wholesaleRange)
+		var tierErrs []*models.UploadRowError
+		if tiersErr != nil {
+			tierErrs = append(tierErrs, &models.UploadRowError{Field: "wholesale", Value: wholesalePrice + " / " + wholesaleRange, Message: "оптовые цены не распознаны, строка загружена без них"})
+		}
+		nomenclature.PriceTiers = tiers
+		wholesaleItems := legacyWholesaleItems(wholesalePrice, tiers)
 		nomenclature.WholesaleItems = wholesaleItems
 
 		if len(row) > 20 && row[20] != "" {
@@ -162,66 +225,11 @@ func newSupplierNomenclature(rows [][]string, priceLists []string, repo reposito
 		if len(row) > 27 {
 			nomenclature.PackingMaterial = row[27]
 		}
-		if len(row) > 32 {
-			nomenclature.StorageType = row[32]
-		}
-		if len(row) > 32 && row[32] != "" {
-			length, lenErr := strconv.ParseFloat(row[32], 32)
-			if lenErr != nil {
-				log.Errorf("float to parse length to float: %v", lenErr)
-			}
-			nomenclature.Length = float32(length)
-
-		}
-		if len(row) > 33 && row[33] != "" {
-			width, widthErr := strconv.ParseFloat(row[33], 32)
-			if widthErr != nil {
-				log.Errorf("float to parse width to float: %v", widthErr)
-			}
-			nomenclature.Width = float32(width)
-
-		}
-
-		if len(row) > 34 && row[34] != "" {
-			height, heightErr := strconv.ParseFloat(row[34], 32)
-			if heightErr != nil {
-				log.Errorf("float to parse height to float: %v", heightErr)
-			}
-			nomenclature.Height = float32(height)
-		}
-
-		if len(row) > 35 && row[35] != "" {
-			amountInPackage, amountErr := strconv.Atoi(row[35])
-			if amountErr != nil {
-				log.Errorf("failed to parse amount in package: %v", amountErr)
-			}
-			nomenclature.AmountInPackage = int8(amountInPackage)
-		}
-
-		if len(row) > 36 && row[36] != "" {
-			wNetto, wNettoErr := strconv.Atoi(row[36])
-			if wNettoErr != nil {
-				log.Errorf("failed to parse string to int: %v", wNettoErr)
-			}
-			nomenclature.WeightNetto = float32(wNetto)
-
-		}
-
-		if len(row) > 37 && row[37] != "" {
-			wBrutto, wBruttoErr := strconv.Atoi(row[37])
-			if wBruttoErr != nil {
-				log.Errorf("failed to parse string to int: %v", wBruttoErr)
-			}
-			nomenclature.WeightBrutto = float32(wBrutto)
-		}
-
-		if len(row) > 38 && row[38] != "" {
-			volume, volumeErr := strconv.Atoi(row[38])
-			if volumeErr != nil {
-				log.Errorf("failed to parse string to int: %v", volumeErr)
-			}
-			nomenclature.Volume = float32(volume)
+		if storageCol >= 0 && len(row) > storageCol {
+			nomenclature.StorageType = row[storageCol]
 		}
+		logisticsErrs := parseLogistics(nomenclature, row)
+		applyAttributes(nomenclature, extract.Extract(nomenclature.Name).Attributes)
 
 		if len(row) > 39 {
 			nomenclature.LoadingType = row[39]
@@ -236,37 +244,71 @@ func newSupplierNomenclature(rows [][]string, priceLists []string, repo reposito
 			nomenclature.DeliveryType = row[42]
 		}
 
+		var keyErrs []*models.UploadRowError
+		if len(currencyErrs) == 0 {
+			keyErrs = prices.check(nomenclature)
+		}
+
+		manufacturers.resolve(nomenclature)
+		rowErrs := append(append(append(append(currencyErrs, keyErrs...), vatErrs...), logisticsErrs...), tierErrs...)
+		reportSheetRow(ctx, repo, sheet, uploadId, "supplier_nomenclature", i, append(rowErrs, classifiers.check(ctx, nomenclature)...))
+		if len(currencyErrs) > 0 {
+			continue
+		}
+
 		saveErr := repo.SaveNomenclature(ctx, nomenclature, nil, userId, companyId)
 		if saveErr != nil {
 			repo.NewErrorNomenclatureId(ctx, i, "supplier_nomenclature")
+			continue
+		}
+		sheet.Saved++
+		if len(nomenclature.PriceTiers) > 0 {
+			if tierErr := repo.SavePriceTiers(ctx, nomenclature.Id, nomenclature.PriceTiers); tierErr != nil {
+				repo.NewErrorNomenclatureId(ctx, i, "supplier_nomenclature")
+			}
+		}
+		if stdErr := saveStandards(ctx, repo, nomenclature); stdErr != nil {
+			repo.NewErrorNomenclatureId(ctx, i, "supplier_nomenclature")
 		}
+		suggestCategory(ctx, repo, categories, nomenclature, sheet.CategoryHint)
+		prices.add(nomenclature, sheet.Sheet, i)
 	}
-	return nil
 
+	return nil
 }
 
-func NewMTRFile(rows [][]string, repo repository.ExcelRepository, ctx context.Context, userId, companyId string) error {
+func NewMTRFile(rows [][]string, repo repository.ExcelRepository, ctx context.Context, userId, companyId, uploadId string, sheet *models.SheetReport) error {
+	extract, extractErr := loadExtractor(ctx, repo)
+	if extractErr != nil {
+		return extractErr
+	}
+	classifiers := newClassifierChecker(ctx, repo)
+	categories, modelErr := loadCategoryModel(ctx, repo)
+	if modelErr != nil {
+		log.Warnf("category suggestions are off: %v", modelErr)
+	}
+	manufacturers := newManufacturerResolver(ctx, repo)
+	defer manufacturers.flush(ctx, repo, uploadId)
+	countries := newCountryResolver(ctx, repo)
+	categoryIndex := newCategoryResolver(ctx, repo)
+
 	for i, v := range rows {
 		fmt.Println("started")
-		if i == 0 {
+		if i+1 < sheet.DataRow {
 			continue
 		}
 		nomenclature := &models.Nomenclature{}
 		nomenclature.Id = uuid.New().String()
 		nomenclature.PackageId = uuid.New().String()
+		nomenclature.UploadId = uploadId
+		nomenclature.Source = sourceMtr
 		name := v[5]
 		if v[6] != "" {
 			name = strings.Replace(name, "("+v[6]+")", "", 1)
 		}
-		name, nomenclature.Length, nomenclature.Height, nomenclature.Width = takeVolume(name)
-
-		if len(v[28]) < 2 {
-			name, nomenclature.DrawingName = takeDraw(name)
-		} else {
+		if len(v[28]) >= 2 {
 			nomenclature.DrawingName = v[28]
 		}
-		fmt.Println("drawing name: ", nomenclature.DrawingName)
-		nomenclature.Name = name
 		nomenclature.CodeSkmtr = v[6]
 		nomenclature.Measurement = v[7]
 		nomenclature.OKPD2 = v[15]
@@ -288,13 +330,10 @@ func NewMTRFile(rows [][]string, repo repository.ExcelRepository, ctx context.Co
 				return echo.NewHTTPError(http.StatusBadRequest, "не правильный формат вес(брутто)"+v[30])
 			}
 			nomenclature.WeightBrutto = float32(wBrutto)
-		} else {
-			name, nomenclature.WeightBrutto = takeWeight(name, v[30])
 		}
 
-		name, nomenclature.WeightNetto = takeWeight(name, v[29])
-
-		nomenclature.Name = name
+		applyExtraction(nomenclature, extract.Extract(name))
+		collectStandards(nomenclature, v[42], v[5])
 
 		nomenclature.TmcMark = v[41]
 		nomenclature.Manufacturer = v[19]
@@ -302,10 +341,11 @@ func NewMTRFile(rows [][]string, repo repository.ExcelRepository, ctx context.Co
 		nomenclature.Representation = v[52]
 		nomenclature.Link = v[0]
 
-		if v[10] == "" {
+		nomenclature.CategoryName = unclassifiedCategory
+		if v[10] != "" {
 			nomenclature.CategoryName = v[10]
 		}
-		nomenclature.CategoryName = "He классифицированные"
+		rowErrs := categoryIndex.resolve(nomenclature, nomenclature.CategoryName)
 		//nomenclature.DeliveryAddress =
 
 		nomenclatureMTR := &models.Mtr{}
@@ -383,15 +423,27 @@ func NewMTRFile(rows [][]string, repo repository.ExcelRepository, ctx context.Co
 		nomenclature.Payload = nomenclatureMTR
 		nomenclature.WholesaleItems = wholesaleItems
 
+		manufacturers.resolve(nomenclature)
+		rowErrs = append(rowErrs, classifiers.check(ctx, nomenclature)...)
+		rowErrs = append(rowErrs, countries.resolve(nomenclature, nomenclatureMTR.SlManufacturerCountry)...)
+		rowErrs = append(rowErrs, checkLogistics(nomenclature)...)
+		reportSheetRow(ctx, repo, sheet, uploadId, "mtr", i, rowErrs)
+
 		err := repo.SaveNomenclature(ctx, nomenclature, nil, userId, companyId)
 		if err != nil {
 			repo.NewErrorNomenclatureId(ctx, i, "mtr")
+			continue
 		}
+		sheet.Saved++
+		if stdErr := saveStandards(ctx, repo, nomenclature); stdErr != nil {
+			repo.NewErrorNomenclatureId(ctx, i, "mtr")
+		}
+		suggestCategory(ctx, repo, categories, nomenclature, sheet.CategoryHint)
 	}
 	return nil
 }
 
-func (e ExcelServiceImpl) SaveMTRExcelFile(ctx context.Context, file *multipart.FileHeader) (*models.ResponseMsg, error) {
+func (e ExcelServiceImpl) SaveMTRExcelFile(ctx context.Context, file *multipart.FileHeader, sheet models.SheetSelector) (*models.ResponseMsg, error) {
 	src, err := file.Open()
 	if err != nil {
 		log.Errorf("failed ti open file: %v", err)
@@ -400,78 +452,44 @@ func (e ExcelServiceImpl) SaveMTRExcelFile(ctx context.Context, file *multipart.
 
 	defer src.Close()
 	fmt.Println("start")
-	excelFile, fileErr := excelize.OpenReader(src)
+	excelFile, fileErr := openWorkbook(src, e.cfg.Limits)
 	if fileErr != nil {
-		log.Errorf("failed to open reader: %v", fileErr)
-		return nil, echo.NewHTTPError(http.StatusBadRequest, fileErr)
+		return nil, fileErr
 	}
 
-	// Get all the rows in the Sheet1.
-	rows, rowsErr := excelFile.GetRows("TDSheet")
-	if rowsErr != nil {
-		log.Errorf("failed to read sheet: %v", rowsErr)
-		return nil, echo.NewHTTPError(http.StatusBadRequest, "Не правильный наименование страницы excel файла. Переименуйте на Лист1")
+	sheetNames, sheetErr := selectSheets(excelFile, sheet, "TDSheet", mtrTemplate)
+	if sheetErr != nil {
+		return nil, sheetErr
 	}
 
-	newMtrErr := NewMTRFile(rows, e.repo, ctx, "", "")
-	if newMtrErr != nil {
-		return nil, newMtrErr
+	uploadId, uploadErr := e.newDirectUpload(ctx)
+	if uploadErr != nil {
+		return nil, uploadErr
 	}
 
-	return &models.ResponseMsg{Message: "success"}, nil
-}
-
+	res := &models.ResponseMsg{Message: "success", UploadId: uploadId}
+	for _, sheetName := range sheetNames {
+		rows, rowsErr := readRows(excelFile, sheetName, e.cfg.Limits)
+		if rowsErr != nil {
+			e.finishDirectUpload(ctx, uploadId, rowsErr)
+			return nil, rowsErr
+		}
 
-func takeDraw(name string) (string, string) {
-	fmt.Println("takeDraw")
-	regDraw, dErr := regexp.Compile(`[а-яА-Я0-9]{1,4}([.][а-яА-Я0-9]{1,4}){2,5}`)
-	if dErr != nil {
-		log.Errorf("failed to regexp compile in takeDraw: %v", dErr)
-	}
-
-	names := regDraw.FindAllString(name, 5)
-	if len(names) == 1 {
-		draw := names[0]
-		name = strings.Replace(name, draw, "", 1)
-		fmt.Println("draw", draw)
-		return name, draw
-	} else if len(names) > 1 {
-		draw := names[len(names)-1]
-		for _, v := range names {
-			if v == draw {
-				name = strings.Replace(name, draw, "", 1)
-			}
+		report := newSheetReport(file.Filename, sheetName, templateLayout(excelFile, sheetName, rows, mtrTemplate))
+		newMtrErr := NewMTRFile(rows, e.repo, ctx, "", "", uploadId, report)
+		if newMtrErr != nil {
+			e.finishDirectUpload(ctx, uploadId, newMtrErr)
+			return nil, newMtrErr
 		}
-		name = strings.Replace(name, draw, "", 1)
-		fmt.Println("draw", draw)
-		return name, draw
+		res.Sheets = append(res.Sheets, report)
 	}
 
-	return name, ""
+	e.finishDirectUpload(ctx, uploadId, nil)
+	return res, nil
 }
 
-func takeWeight(name, weightCell string) (string, float32) {
-	regWei, wErr := regexp.Compile(`[0-9]{1,4}[,]*[0-9]{1,5}кг`)
-	if wErr != nil {
-		log.Errorf("failed to regexp compile in takeWeight: %v", wErr)
-	}
-	weight := regWei.FindString(name)
-	fmt.Println("weight", weight)
-	name = strings.Replace(name, weight, "", 1)
-	if len(weight) > 3 {
-		name = strings.Replace(name, weight, "", 1)
-		weight = strings.Replace(weight, "кг", "", 1)
-		weight = strings.Replace(weight, ",", ".", 1)
-		weiFloat, weErr := strconv.ParseFloat(weight, 8)
-		if weErr != nil {
-			log.Errorf("failed to parse float in takeWeight: %v", weErr)
-		}
-		return name, float32(weiFloat)
-	}
-	return name, 0
-}
 
-func (e ExcelServiceImpl) SaveCategory(ctx context.Context, file *multipart.FileHeader) (*models.ResponseMsg, error) {
+func (e ExcelServiceImpl) SaveCategory(ctx context.Context, file *multipart.FileHeader, sheet models.SheetSelector) (*models.ResponseMsg, error) {
 	src, err := file.Open()
 	if err != nil {
 		log.Errorf("failed ti open file: %v", err)
@@ -480,22 +498,24 @@ func (e ExcelServiceImpl) SaveCategory(ctx context.Context, file *multipart.File
 
 	defer src.Close()
 
-	excelFile, fileErr := excelize.OpenReader(src)
+	excelFile, fileErr := openWorkbook(src, e.cfg.Limits)
 	if fileErr != nil {
-		log.Errorf("failed to open reader: %v", fileErr)
-		return nil, echo.NewHTTPError(http.StatusBadRequest, fileErr)
+		return nil, fileErr
 	}
 	fmt.Println(excelFile.GetSheetList())
 	// Get all the rows in the Sheet1.
 	fmt.Println("1")
-	rows, rowsErr := excelFile.GetRows("TDSheet")
+	sheetName, sheetErr := selectSheet(excelFile, sheet, "TDSheet")
+	if sheetErr != nil {
+		return nil, sheetErr
+	}
+	rows, rowsErr := readRows(excelFile, sheetName, e.cfg.Limits)
 	fmt.Println("1")
 	if rowsErr != nil {
-		log.Errorf("failed to read sheet: %v", rowsErr)
-		return nil, echo.NewHTTPError(http.StatusBadRequest, "Не правильный наименование страницы excel файла. Переименуйте на Лист1")
+		return nil, rowsErr
 	}
 
-	tx, txErr := e.lb.CallPrimaryPreferred().PGxPool().Begin(ctx)
+	tx, txErr := e.db.Begin(ctx)
 	if txErr != nil {
 		log.Errorf("failed to begin tx: %v", txErr)
 		return nil, echo.NewHTTPError(http.StatusInternalServerError, txErr)
@@ -509,10 +529,11 @@ func (e ExcelServiceImpl) SaveCategory(ctx context.Context, file *multipart.File
 	}(ctx)
 
 	catMap := make(map[string]bool)
+	layout := detectLayout(excelFile, sheetName, rows, nil, 1)
 
 	fmt.Println("bасталды")
 	for i, v := range rows {
-		if i == 0 {
+		if i < layout.dataRow {
 			continue
 		}
 		if !catMap[v[10]] {
@@ -588,10 +609,11 @@ func (e ExcelServiceImpl) SaveCategory(ctx context.Context, file *multipart.File
 	//	}
 	//}
 
-	return &models.ResponseMsg{Message: "success"}, nil
+	invalidateCategoryModel()
+	return &models.ResponseMsg{Message: "success", Sheets: []*models.SheetReport{newSheetReport(file.Filename, sheetName, layout)}}, nil
 }
 
-func (e ExcelServiceImpl) CreateCompany(ctx context.Context, file *multipart.FileHeader) (*models.ResponseMsg, error) {
+func (e ExcelServiceImpl) CreateCompany(ctx context.Context, file *multipart.FileHeader, sheet models.SheetSelector) (*models.ResponseMsg, error) {
 	src, err := file.Open()
 	if err != nil {
 		log.Errorf("failed ti open file: %v", err)
@@ -600,22 +622,24 @@ func (e ExcelServiceImpl) CreateCompany(ctx context.Context, file *multipart.Fil
 
 	defer src.Close()
 
-	excelFile, fileErr := excelize.OpenReader(src)
+	excelFile, fileErr := openWorkbook(src, e.cfg.Limits)
 	if fileErr != nil {
-		log.Errorf("failed to open reader: %v", fileErr)
-		return nil, echo.NewHTTPError(http.StatusBadRequest, fileErr)
+		return nil, fileErr
 	}
 	fmt.Println(excelFile.GetSheetList())
 	// Get all the rows in the Sheet1.
 	fmt.Println("1")
-	rows, rowsErr := excelFile.GetRows("Лист1")
+	sheetName, sheetErr := selectSheet(excelFile, sheet, "Лист1")
+	if sheetErr != nil {
+		return nil, sheetErr
+	}
+	rows, rowsErr := readRows(excelFile, sheetName, e.cfg.Limits)
 	fmt.Println("1")
 	if rowsErr != nil {
-		log.Errorf("failed to read sheet: %v", rowsErr)
-		return nil, echo.NewHTTPError(http.StatusBadRequest, "Не правильный наименование страницы excel файла. Переименуйте на Лист1")
+		return nil, rowsErr
 	}
 
-	tx, txErr := e.lb.CallPrimaryPreferred().PGxPool().Begin(ctx)
+	tx, txErr := e.db.Begin(ctx)
 	if txErr != nil {
 		log.Errorf("failed to begin tx: %v", txErr)
 		return nil, echo.NewHTTPError(http.StatusInternalServerError, txErr)
@@ -629,9 +653,10 @@ func (e ExcelServiceImpl) CreateCompany(ctx context.Context, file *multipart.Fil
 	}(ctx)
 
 	comMap := make(map[string]bool)
+	layout := detectLayout(excelFile, sheetName, rows, nil, 10)
 
 	for i, row := range rows {
-		if i < 10 {
+		if i < layout.dataRow {
 			continue
 		}
 		if comMap[row[10]] {
@@ -656,10 +681,10 @@ func (e ExcelServiceImpl) CreateCompany(ctx context.Context, file *multipart.Fil
 		comMap[row[10]] = true
 		fmt.Println("inserted: ", company.Name)
 	}
-	return &models.ResponseMsg{Message: "success"}, nil
+	return &models.ResponseMsg{Message: "success", Sheets: []*models.SheetReport{newSheetReport(file.Filename, sheetName, layout)}}, nil
 }
 
-func (e ExcelServiceImpl) SaveOrganizerNomenclature(ctx context.Context, file *multipart.FileHeader) (*models.ResponseMsg, error) {
+func (e ExcelServiceImpl) SaveOrganizerNomenclature(ctx context.Context, file *multipart.FileHeader, sheet models.SheetSelector) (*models.ResponseMsg, error) {
 	src, err := file.Open()
 	if err != nil {
 		log.Errorf("failed ti open file: %v", err)
@@ -668,22 +693,19 @@ func (e ExcelServiceImpl) SaveOrganizerNomenclature(ctx context.Context, file *m
 
 	defer src.Close()
 
-	excelFile, fileErr := excelize.OpenReader(src)
+	excelFile, fileErr := openWorkbook(src, e.cfg.Limits)
 	if fileErr != nil {
-		log.Errorf("failed to open reader: %v", fileErr)
-		return nil, echo.NewHTTPError(http.StatusBadRequest, fileErr)
+		return nil, fileErr
 	}
 	fmt.Println(excelFile.GetSheetList())
 	// Get all the rows in the Sheet1.
 	fmt.Println("1")
-	rows, rowsErr := excelFile.GetRows("Лист1")
-	fmt.Println("1")
-	if rowsErr != nil {
-		log.Errorf("failed to read sheet: %v", rowsErr)
-		return nil, echo.NewHTTPError(http.StatusBadRequest, "Не правильный наименование страницы excel файла. Переименуйте на Лист1")
+	sheetNames, sheetErr := selectSheets(excelFile, sheet, "Лист1", organizerTemplate)
+	if sheetErr != nil {
+		return nil, sheetErr
 	}
 
-	tx, txErr := e.lb.CallPrimaryPreferred().PGxPool().Begin(ctx)
+	tx, txErr := e.db.Begin(ctx)
 	if txErr != nil {
 		log.Errorf("failed to begin tx: %v", txErr)
 		return nil, echo.NewHTTPError(http.StatusInternalServerError, txErr)
@@ -696,31 +718,59 @@ func (e ExcelServiceImpl) SaveOrganizerNomenclature(ctx context.Context, file *m
 		}
 	}(ctx)
 
-	if orgRepErr := newOrgranizerNomenclature(rows, e.repo, ctx, "", ""); orgRepErr != nil {
-		return nil, err
+	uploadId, uploadErr := e.newDirectUpload(ctx)
+	if uploadErr != nil {
+		return nil, uploadErr
 	}
+
+	res := &models.ResponseMsg{Message: "success", UploadId: uploadId}
+	for _, sheetName := range sheetNames {
+		rows, rowsErr := readRows(excelFile, sheetName, e.cfg.Limits)
+		if rowsErr != nil {
+			e.finishDirectUpload(ctx, uploadId, rowsErr)
+			return nil, rowsErr
+		}
+
+		report := newSheetReport(file.Filename, sheetName, templateLayout(excelFile, sheetName, rows, organizerTemplate))
+		if orgRepErr := newOrgranizerNomenclature(rows, e.repo, ctx, "", "", uploadId, report); orgRepErr != nil {
+			e.finishDirectUpload(ctx, uploadId, orgRepErr)
+			return nil, orgRepErr
+		}
+		res.Sheets = append(res.Sheets, report)
+	}
+	e.finishDirectUpload(ctx, uploadId, nil)
 	// saveErr := e.repo.SaveArrayNomenclature(ctx, nomenclatures, tx)
 	// if saveErr != nil {
 	// 	fmt.Println("save array nom errors: ", saveErr)
 	// 	return nil, saveErr
 	// }
-	return &models.ResponseMsg{Message: "success"}, nil
+	return res, nil
 }
 
-func newOrgranizerNomenclature(rows [][]string, repo repository.ExcelRepository, ctx context.Context, userId, companyId string) error {
+func newOrgranizerNomenclature(rows [][]string, repo repository.ExcelRepository, ctx context.Context, userId, companyId, uploadId string, sheet *models.SheetReport) error {
+	classifiers := newClassifierChecker(ctx, repo)
+	categories, modelErr := loadCategoryModel(ctx, repo)
+	if modelErr != nil {
+		log.Warnf("category suggestions are off: %v", modelErr)
+	}
+	manufacturers := newManufacturerResolver(ctx, repo)
+	defer manufacturers.flush(ctx, repo, uploadId)
+	countries := newCountryResolver(ctx, repo)
 	for i, row := range rows {
-		if i < 1 {
+		if i+1 < sheet.DataRow {
 			continue
 		}
 		fmt.Println("row #", i)
 		nomenclature := &models.Nomenclature{}
 		nomenclature.Id = uuid.New().String()
+		nomenclature.UploadId = uploadId
+		nomenclature.Source = sourceOrganizer
 		nomenclature.Name = row[65]
 		nomenclature.TmcCodeVendor = row[14]
 		nomenclature.Manufacturer = row[42]
 		nomenclature.CodeTnved = row[53]
 		nomenclature.CodeAmto = row[62]
-		nomenclature.GostTu = row[69]
+		collectStandards(nomenclature, row[69], row[65])
 		nomenclature.DrawingName = row[70]
 		nomenclature.CodeKsNsi = row[85]
 		nomenclature.OKPD2 = row[90]
@@ -729,16 +779,12 @@ func newOrgranizerNomenclature(rows [][]string, repo repository.ExcelRepository,
 		nomenclature.Representation = row[116]
 		nomenclature.Measurement = row[24]
 		nomenclature.Link = row[13]
-		if len(row[27]) > 1 {
-			taxPercentageString := strings.Replace(row[27], "%", "", 1)
-			taxPercentage, taxErr := strconv.ParseFloat(taxPercentageString, 8)
-			if taxErr != nil {
-				log.Errorf("failed to parse tax percentage: %v", taxErr)
-			} else {
-				nomenclature.TaxPercentage = float32(taxPercentage)
-				nomenclature.IsTax = true
-			}
+		vat, vatErr := parseVat(row[27])
+		var vatErrs []*models.UploadRowError
+		if vatErr != nil {
+			vatErrs = append(vatErrs, &models.UploadRowError{Field: mappingVat, Value: row[27], Message: "ставка НДС не распознана"})
 		}
+		applyVat(nomenclature, vat, true)
 
 		if len(row[11]) > 3 {
 			nomenclature.UserId = row[11]
@@ -813,11 +859,21 @@ func newOrgranizerNomenclature(rows [][]string, repo repository.ExcelRepository,
 
 		nomenclature.OrganizerNomenclature = orgNomenclature
 		//nomenclatures = append(nomenclatures, nomenclature)
+		manufacturers.resolve(nomenclature)
+		rowErrs := append(append(vatErrs, classifiers.check(ctx, nomenclature)...), countries.resolve(nomenclature, orgNomenclature.ManufacturerCountry)...)
+		reportSheetRow(ctx, repo, sheet, uploadId, "organizer_nomenclature", i, rowErrs)
+
 		err := repo.SaveNomenclature(ctx, nomenclature, nil, userId, companyId)
 		if err != nil {
 			log.Error(err)
 			repo.NewErrorNomenclatureId(ctx, i, "organizer_nomenclature")
+			continue
 		}
+		sheet.Saved++
+		if stdErr := saveStandards(ctx, repo, nomenclature); stdErr != nil {
+			repo.NewErrorNomenclatureId(ctx, i, "organizer_nomenclature")
+		}
+		suggestCategory(ctx, repo, categories, nomenclature, sheet.CategoryHint)
 	}
 	return nil
 }
@@ -829,7 +885,7 @@ func netFunc(is string) bool {
 	return true
 }
 
-func (e ExcelServiceImpl) SaveBanks(ctx context.Context, file *multipart.FileHeader) (*models.ResponseMsg, error) {
+func (e ExcelServiceImpl) SaveBanks(ctx context.Context, file *multipart.FileHeader, sheet models.SheetSelector) (*models.ResponseMsg, error) {
 	src, err := file.Open()
 	if err != nil {
 		log.Errorf("failed ti open file: %v", err)
@@ -838,23 +894,25 @@ func (e ExcelServiceImpl) SaveBanks(ctx context.Context, file *multipart.FileHea
 
 	defer src.Close()
 
-	excelFile, fileErr := excelize.OpenReader(src)
+	excelFile, fileErr := openWorkbook(src, e.cfg.Limits)
 	if fileErr != nil {
-		log.Errorf("failed to open reader: %v", fileErr)
-		return nil, echo.NewHTTPError(http.StatusBadRequest, fileErr)
+		return nil, fileErr
 	}
 
 	fmt.Println(excelFile.GetSheetList())
 	// Get all the rows in the Sheet1.
 
-	rows, rowsErr := excelFile.GetRows("Лист1")
+	sheetName, sheetErr := selectSheet(excelFile, sheet, "Лист1")
+	if sheetErr != nil {
+		return nil, sheetErr
+	}
+	rows, rowsErr := readRows(excelFile, sheetName, e.cfg.Limits)
 
 	if rowsErr != nil {
-		log.Errorf("failed to read sheet: %v", rowsErr)
-		return nil, echo.NewHTTPError(http.StatusBadRequest, "Не правильный наименование страницы excel файла. Переименуйте на Лист1")
+		return nil, rowsErr
 	}
 
-	tx, txErr := e.lb.CallPrimaryPreferred().PGxPool().Begin(ctx)
+	tx, txErr := e.db.Begin(ctx)
 	if txErr != nil {
 		log.Errorf("failed to begin tx: %v", txErr)
 		return nil, echo.NewHTTPError(http.StatusInternalServerError, txErr)
@@ -869,8 +927,9 @@ func (e ExcelServiceImpl) SaveBanks(ctx context.Context, file *multipart.FileHea
 
 	//var nomenclatures []*models.Nomenclature
 
+	layout := detectLayout(excelFile, sheetName, rows, nil, 4)
 	for i, row := range rows {
-		if i < 4 {
+		if i < layout.dataRow {
 			continue
 		}
 		if row[9] == "Банк" {
@@ -890,7 +949,7 @@ func (e ExcelServiceImpl) SaveBanks(ctx context.Context, file *multipart.FileHea
 		}
 
 	}
-	return &models.ResponseMsg{Message: "success"}, nil
+	return &models.ResponseMsg{Message: "success", Sheets: []*models.SheetReport{newSheetReport(file.Filename, sheetName, layout)}}, nil
 }
 
 func (e ExcelServiceImpl) GetExcelFromAwsByFileId(ctx context.Context, req *models.GetExcelFromAwsByFileIdReq) (*models.ResponseMsg, error) {
@@ -911,7 +970,7 @@ func (e ExcelServiceImpl) GetExcelFromAwsByFileId(ctx context.Context, req *mode
 	})
 	if err != nil {
 		log.Error("failed to connect to minio: ", err)
-		return nil, echo.NewHTTPError(http.StatusInternalServerError, err)
+		return nil, apperrors.Wrap(apperrors.StorageUnavailable, err)
 	}
 
 	//filePath := fmt.Sprintf("%s", req.FileId)
@@ -919,7 +978,7 @@ func (e ExcelServiceImpl) GetExcelFromAwsByFileId(ctx context.Context, req *mode
 	obj, err := minioClient.GetObject(ctx, bucket, req.FileId, minio.GetObjectOptions{})
 	if err != nil {
 		log.Error("get object err:", err)
-		return nil, err
+		return nil, apperrors.Wrap(apperrors.StorageUnavailable, err)
 	}
 	if obj == nil {
 		log.Warn("object is nil")
@@ -930,98 +989,122 @@ func (e ExcelServiceImpl) GetExcelFromAwsByFileId(ctx context.Context, req *mode
 }
 
 func (e ExcelServiceImpl) UploadExcelFile(ctx context.Context, file *multipart.FileHeader, companyName string) (*models.ResponseMsg, error) {
+	companyId, companyErr := e.repo.SelectCompanyIdByName(ctx, companyName)
+	if companyErr != nil {
+		return nil, companyErr
+	}
+	if authErr := authorizeCompany(ctx, companyId); authErr != nil {
+		return nil, authErr
+	}
+	// the items of the file are credited to the user who uploads it, a
+	// service token has nobody to credit
+	uploader := auth.PrincipalFromContext(ctx)
+	if uploader.UserId == "" {
+		log.Warnf("upload of %s by service %s has no user", file.Filename, uploader.Service)
+		return nil, echo.NewHTTPError(http.StatusForbidden, "files are uploaded by users")
+	}
+	if file.Size > e.cfg.Limits.MaxFileSize {
+		log.Warnf("file %s is larger than %d bytes", file.Filename, e.cfg.Limits.MaxFileSize)
+		return nil, apperrors.New(apperrors.FileTooLarge, fmt.Sprintf("файл больше %d байт", e.cfg.Limits.MaxFileSize))
+	}
+
 	src, err := file.Open()
 	if err != nil {
 		log.Errorf("failed ti open file: %v", err)
 		return nil, echo.NewHTTPError(http.StatusBadRequest, err)
 	}
-
-	endpoint := e.cfg.Aws.Host
-	accessKeyID := e.cfg.Aws.SecretKey
-	secretAccessKey := e.cfg.Aws.AccessKey
-	bucket := e.cfg.Aws.Bucket
-	useSSL := false
-
-	// Initialize minio client object.
-	minioClient, err := minio.New(endpoint, &minio.Options{
-		Creds:  credentials.NewStaticV4(secretAccessKey, accessKeyID, ""),
-		Secure: useSSL,
-	})
-	if err != nil {
-		log.Error("failed to connect to minio: ", err)
-		return nil, echo.NewHTTPError(http.StatusInternalServerError, err)
-	}
+	defer src.Close()
 
 	contentType := "application/vnd.ms-excel"
 	fileNameDisc := uuid.New().String() + file.Filename[len(file.Filename)-5:]
 
-	uploadInfo, uploadErr := minioClient.PutObject(ctx, bucket, fileNameDisc, src, file.Size, minio.PutObjectOptions{ContentType: contentType})
+	uploadErr := e.storage.Put(ctx, fileNameDisc, src, file.Size, contentType)
 	if uploadErr != nil {
 		log.Error("failed to upload file to s3:", uploadErr)
-		return nil, echo.NewHTTPError(http.StatusInternalServerError, uploadErr)
+		return nil, apperrors.Wrap(apperrors.StorageUnavailable, uploadErr)
 	}
 
-	log.Infof("file %s success uploaded, size: %d", file.Filename, uploadInfo.Size)
+	log.Infof("file %s success uploaded, size: %d", file.Filename, file.Size)
 
-	err = e.repo.NewUploadCatalogue(ctx, fileNameDisc, file.Filename, "", companyName, file.Size)
+	uploadId, err := e.repo.NewUploadCatalogue(ctx, fileNameDisc, file.Filename, uploader.UserId, companyName, file.Size)
 	if err != nil {
 		return nil, err
 	}
 
-	return &models.ResponseMsg{Message: "success"}, nil
+	return &models.ResponseMsg{Message: "success", UploadId: uploadId}, nil
 }
 
-func (e ExcelServiceImpl) SaveNomenclatureFromDirectus(ctx context.Context, req *models.DirectusModel) (*models.ResponseMsg, error) {
-	if req.Collection != "uploads" {
-		log.Warnf("collection is %s not uploads", req.Collection)
-		return nil, echo.NewHTTPError(http.StatusBadRequest, "collection is not uploads")
-	}
-	log.Infof("Start processing upload from directus: %v", req)
-	err := e.repo.SetUploadStatus(ctx, req.Key, "processing")
+// processUpload parses every file of the upload, runs once per hook
+// idempotency key. A failed upload is marked by finishHookRun.
+func (e ExcelServiceImpl) processUpload(ctx context.Context, req *models.DirectusModel) (*models.ResponseMsg, error) {
+	log.Infof("Start processing upload from directus: %s", req.Key)
+	err := e.repo.SetUploadStatus(ctx, req.Key, uploadStatusProcessing)
 	if err != nil {
-		log.Warnf("failed to set upload status", err)
+		log.Warnf("failed to set upload status: %v", err)
 		return nil, err
 	}
 	//time.Sleep(15 * time.Second) // todo удалить после демо 8.10
 	uploads, uploadErr := e.repo.GetFromUploadCatalogue(ctx, req.Key)
 	if uploadErr != nil {
-		log.Warnf("failed to get upload catalog", uploadErr)
+		log.Warnf("failed to get upload catalog: %v", uploadErr)
 		return nil, uploadErr
 	}
 
-	endpoint := e.cfg.Aws.Host
-	accessKeyID := e.cfg.Aws.SecretKey
-	secretAccessKey := e.cfg.Aws.AccessKey
-	bucket := e.cfg.Aws.Bucket
-	useSSL := false
-
-	// Initialize minio client object.
-	minioClient, err := minio.New(endpoint, &minio.Options{
-		Creds:  credentials.NewStaticV4(secretAccessKey, accessKeyID, ""),
-		Secure: useSSL,
-	})
-	if err != nil {
-		log.Error("failed to connect to minio: ", err)
-		return nil, echo.NewHTTPError(http.StatusInternalServerError, err)
+	if len(uploads) == 0 {
+		log.Warnf("upload %s has no files", req.Key)
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "upload has no files")
 	}
-
+	// items are owned by the user who uploaded the file, nobody else of the
+	// company is credited with them
+	for _, upload := range uploads {
+		if upload.UserId != "" {
+			continue
+		}
+		log.Warnf("file %s of upload %s has no uploader", upload.FileId, req.Key)
+		uploaderErr := apperrors.New(apperrors.ValidationFailed, "у файла нет загрузившего пользователя, загрузка отклонена")
+		reportFileError(ctx, e.repo, req.Key, upload.FileId, uploaderErr)
+		return nil, uploaderErr
+	}
+	mapping, mappingErr := e.repo.SelectUploadColumnMapping(ctx, req.Key)
+	if mappingErr != nil {
+		return nil, mappingErr
+	}
+	priceLists, priceListErr := e.repo.SelectPriceListsByUploadId(ctx, req.Key)
+	if priceListErr != nil {
+		log.Errorf("failed to get price lists: %v", priceListErr)
+		return nil, apperrors.Wrap(apperrors.Internal, priceListErr)
+	}
+	prices := newUploadPrices(priceLists)
+	res := &models.ResponseMsg{Message: "success"}
 	for _, upload := range uploads{
-		_, err := processFiles(minioClient, ctx, bucket, upload, req, e.repo)
+		fileRes, err := processFiles(e.storage, ctx, upload, req, mapping, prices, e.repo, e.cfg.Limits)
 		if err != nil{
 			return nil, err
 		}
+		res.Sheets = append(res.Sheets, fileRes.Sheets...)
 	}
-	// err = e.repo.SetUploadStatus(ctx, req.Key, "processed")
-	// if err != nil {
-	// 	return nil, err
-	// }
+	// one price list version per upload holds the items of every file
+	if prices.filled {
+		if versionErr := prices.save(ctx, e.repo, req.Key); versionErr != nil {
+			log.Errorf("failed to save price list versions: %v", versionErr)
+			return nil, apperrors.Wrap(apperrors.Internal, versionErr)
+		}
+	}
+	if err := e.repo.SetUploadStatus(ctx, req.Key, uploadStatusProcessed); err != nil {
+		log.Errorf("failed to set upload status: %v", err)
+		return nil, apperrors.Wrap(apperrors.Internal, err)
+	}
+	matchUpload(ctx, e.repo, req.Key)
 
-	log.Errorf("failed to find correct template")
-	return nil, echo.NewHTTPError(http.StatusBadRequest, "неправильный шаблон документа, обратитесь к нам")
+	return res, nil
 }
 
-func processFiles(minioClient *minio.Client, ctx context.Context, bucket string, upload *models.UploadsEntity, req *models.DirectusModel, repo repository.ExcelRepository) (*models.ResponseMsg, error){
-	minioObj, getObjErr := minioClient.GetObject(ctx, bucket, upload.FileId, minio.GetObjectOptions{})
+// processFiles imports the sheets of an upload file. Sheets fitting a
+// built-in template are read with its importer, the sheet of the column
+// mapping confirmed for this file with the mapping. Priced items are
+// collected into prices.
+func processFiles(storage fileStorage, ctx context.Context, upload *models.UploadsEntity, req *models.DirectusModel, mapping *models.ColumnMapping, prices *uploadPrices, repo repository.ExcelRepository, limits *configs.LimitsConfig) (*models.ResponseMsg, error){
+	minioObj, getObjErr := storage.Get(ctx, upload.FileId)
 	if getObjErr != nil {
 		log.Error("failed to get object:", getObjErr)
 		return nil, echo.NewHTTPError(http.StatusBadRequest, upload.FileId+"does not exists")
@@ -1029,136 +1112,80 @@ func processFiles(minioClient *minio.Client, ctx context.Context, bucket string,
 
 	defer minioObj.Close()
 
-	excelFile, fileErr := excelize.OpenReader(minioObj)
+	excelFile, fileErr := openWorkbook(minioObj, limits)
 	if fileErr != nil {
-		log.Errorf("failed to open reader: %v", fileErr)
-		return nil, echo.NewHTTPError(http.StatusBadRequest, fileErr)
+		reportFileError(ctx, repo, req.Key, upload.FileId, fileErr)
+		return nil, fileErr
 	}
 
-	rows, rowsErr := excelFile.GetRows(excelFile.GetSheetList()[0])
-
-	if rowsErr != nil {
-		log.Errorf("failed to read sheet: %v", rowsErr)
-		return nil, echo.NewHTTPError(http.StatusBadRequest, "Не правильный наименование страницы excel файла. Переименуйте на Лист1")
+	mapping = fileMapping(mapping, upload.FileId)
+	sheets, sheetErr := uploadSheets(excelFile, req.SheetSelector, mapping)
+	if sheetErr != nil {
+		reportFileError(ctx, repo, req.Key, upload.FileId, sheetErr)
+		return nil, sheetErr
 	}
 
-	if rows[0][10] == "ИНН" && rows[0][11] == "Поставщик" {
-		orgNomErr := newOrgranizerNomenclature(rows, repo, ctx, upload.UserId, upload.CompanyId)
-		if orgNomErr != nil {
-			log.Errorf("failed parse: %v", orgNomErr)
-			return nil, orgNomErr
-		}
-		return &models.ResponseMsg{Message: "success"}, nil
-
-	} else if rows[0][6] == "Наименование" && rows[0][7] == "Артикул" && rows[0][8] == "Идентификатор" {
-
-		mtrErr := NewMTRFile(rows, repo, ctx, upload.UserId, upload.CompanyId)
-		if mtrErr != nil {
-			log.Errorf("failed parse: %v", mtrErr)
-			return nil, mtrErr
-		}
-		err := repo.SetUploadStatus(ctx, req.Key, "processed")
-		if err != nil {
-			log.Errorf("failed to set upload status: %v", fileErr)
-			return nil, echo.NewHTTPError(http.StatusInternalServerError, "Внутренняя ошибка")
-		}
-		return &models.ResponseMsg{Message: "success"}, nil
-
-	} else if rows[0][0] == "Код СКМТР" && rows[0][1] == "КОД КС НСИ" && rows[0][2] == "Код АМТО" {
-
-		// inn, companyErr := e.repo.SelectCompanyInnById(ctx, req.Accounting.Company)
-		// if companyErr != nil {
-		// 	log.Errorf("failed to get company inn: %v", companyErr)
-		// 	return nil, echo.NewHTTPError(http.StatusBadRequest, "Внутренняя ошибка")
-		// }
+	// inn, companyErr := e.repo.SelectCompanyInnById(ctx, req.Accounting.Company)
+	// if companyErr != nil {
+	// 	log.Errorf("failed to get company inn: %v", companyErr)
+	// 	return nil, echo.NewHTTPError(http.StatusBadRequest, "Внутренняя ошибка")
+	// }
 
-		priceLists, priceListErr := repo.SelectPriceListsByUploadId(ctx, req.Key)
-		if priceListErr != nil {
-			log.Errorf("failed to get price lists: %v", priceListErr)
-			return  nil, echo.NewHTTPError(http.StatusBadRequest, "Внутренняя ошибка")
+	res := &models.ResponseMsg{Message: "success"}
+	for _, sheet := range sheets {
+		rows, rowsErr := readRows(excelFile, sheet, limits)
+		if rowsErr != nil {
+			reportFileError(ctx, repo, req.Key, upload.FileId, rowsErr)
+			return nil, rowsErr
 		}
 
-		suppErr := newSupplierNomenclature(rows, priceLists, repo, ctx, upload.CompanyId, upload.UserId)
-		if suppErr != nil {
-			log.Errorf("failed parse: %v", suppErr)
-			return nil, echo.NewHTTPError(http.StatusInternalServerError, "Внутренняя ошибка")
-		}
-		err := repo.SetUploadStatus(ctx, req.Key, "processed")
-		if err != nil {
-			log.Errorf("failed to set upload status: %v", fileErr)
-			return nil, echo.NewHTTPError(http.StatusInternalServerError, "Внутренняя ошибка")
+		layout := detectLayout(excelFile, sheet, rows, uploadTemplates, 0)
+		template := layout.template
+		mapped := template == nil && mapping != nil && sheet == mapping.Sheet
+		if mapped {
+			layout = mappedLayout(excelFile, sheet, rows, mapping.DataRow-1)
 		}
+		report := newSheetReport(upload.FileId, sheet, layout)
 
-		return &models.ResponseMsg{Message: "success"}, nil
-	}
-	log.Errorf("failed to find correct template")
-	return nil, echo.NewHTTPError(http.StatusBadRequest, "неправильный шаблон документа, обратитесь к нам")
-}
-
-func (e ExcelServiceImpl) GetFileColumns(ctx context.Context, req *models.DirectusModel) ([]*models.FileColumns, error){
-	if req.Collection != "uploads" {
-		log.Warnf("collection is %s not uploads", req.Collection)
-		return nil, echo.NewHTTPError(http.StatusBadRequest, "collection is not uploads")
-	}
-	log.Infof("Start processing upload from directus: %v", req)
-	// err := e.repo.SetUploadStatus(ctx, req.Key, "processing")
-	// if err != nil {
-	// 	log.Warnf("failed to set upload status", err)
-	// 	return nil, err
-	// }
-	//time.Sleep(15 * time.Second) // todo удалить после демо 8.10
-	_, uploadErr := e.repo.GetFromUploadCatalogue(ctx, req.Key)
-	if uploadErr != nil {
-		log.Warnf("failed to get upload catalog", uploadErr)
-		return nil, uploadErr
-	}
-
-	endpoint := e.cfg.Aws.Host
-	accessKeyID := e.cfg.Aws.SecretKey
-	secretAccessKey := e.cfg.Aws.AccessKey
-	bucket := e.cfg.Aws.Bucket
-	useSSL := false
-
-	// Initialize minio client object.
-	minioClient, err := minio.New(endpoint, &minio.Options{
-		Creds:  credentials.NewStaticV4(secretAccessKey, accessKeyID, ""),
-		Secure: useSSL,
-	})
-	if err != nil {
-		log.Error("failed to connect to minio: ", err)
-		return nil, echo.NewHTTPError(http.StatusInternalServerError, err)
-	}
-
-	minioObj, getObjErr := minioClient.GetObject(ctx, bucket, "upload.FileId", minio.GetObjectOptions{})
-	if getObjErr != nil {
-		log.Error("failed to get object:", getObjErr)
-		return nil, echo.NewHTTPError(http.StatusInternalServerError, getObjErr)
-	}
-
-	defer minioObj.Close()
+		if template == organizerTemplate {
+			orgNomErr := newOrgranizerNomenclature(rows, repo, ctx, upload.UserId, upload.CompanyId, req.Key, report)
+			if orgNomErr != nil {
+				log.Errorf("failed parse: %v", orgNomErr)
+				return nil, orgNomErr
+			}
 
-	excelFile, fileErr := excelize.OpenReader(minioObj)
-	if fileErr != nil {
-		log.Errorf("failed to open reader: %v", fileErr)
-		return nil, echo.NewHTTPError(http.StatusBadRequest, fileErr)
-	}
+		} else if template == mtrTemplate {
+			mtrErr := NewMTRFile(rows, repo, ctx, upload.UserId, upload.CompanyId, req.Key, report)
+			if mtrErr != nil {
+				log.Errorf("failed parse: %v", mtrErr)
+				return nil, mtrErr
+			}
 
-	rows, rowsErr := excelFile.GetRows(excelFile.GetSheetList()[0])
+		} else if template == supplierTemplate || mapped {
+			var suppErr error
+			if mapped {
+				report.Template = mappedTemplate
+				suppErr = newMappedNomenclature(rows, mapping, prices, repo, ctx, upload.CompanyId, upload.UserId, req.Key, report)
+			} else {
+				suppErr = newSupplierNomenclature(rows, prices, repo, ctx, upload.CompanyId, upload.UserId, req.Key, report)
+			}
+			if suppErr != nil {
+				log.Errorf("failed parse: %v", suppErr)
+				return nil, apperrors.Wrap(apperrors.Internal, suppErr)
+			}
+			prices.filled = true
 
-	if rowsErr != nil {
-		log.Errorf("failed to read sheet: %v", rowsErr)
-		return nil, echo.NewHTTPError(http.StatusBadRequest, "Не правильный наименование страницы excel файла. Переименуйте на Лист1")
-	}
-	
-	var fileRows []*models.FileColumns
-	for i, v := range rows[0]{
-		fileRow := &models.FileColumns{}
-		fileRow.RowId = int8(i)
-		fileRow.RowName = v
-		fileRows = append(fileRows, fileRow)
+		} else {
+			log.Errorf("failed to find correct template of sheet %s", sheet)
+			templateErr := apperrors.New(apperrors.TemplateNotRecognized, fmt.Sprintf("лист %q не подходит под шаблон", sheet))
+			reportFileError(ctx, repo, req.Key, upload.FileId, templateErr)
+			return nil, templateErr
+		}
+		log.Infof("sheet %s of %s: %d rows, %d saved, %d with errors", sheet, upload.FileId, report.Rows, report.Saved, report.Errors)
+		res.Sheets = append(res.Sheets, report)
 	}
 
-	return fileRows, nil
+	return res, nil
 }
 
 // func (e ExcelServiceImpl) SaveCargoCatalogue(ctx context.Context, file *multipart.FileHeader) (*models.ResponseMsg, error) {
@@ -0,0 +1,274 @@
+package service
+
+import (
+	"context"
+	"excel-service/internal/models"
+	"excel-service/internal/repository"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+const (
+	fieldDimensions = "dimensions"
+	fieldLength     = "length"
+	fieldWidth      = "width"
+	fieldHeight     = "height"
+	fieldDiameter   = "diameter"
+	fieldWeight     = "weight"
+	fieldVolume     = "volume"
+	fieldThread     = "thread"
+	fieldStandard   = "standard"
+	fieldDrawing    = "drawing"
+)
+
+// fieldQuantity tells which unit table a numeric field is normalized with,
+// text fields are missing here.
+var fieldQuantity = map[string]string{
+	fieldDimensions: "length",
+	fieldLength:     "length",
+	fieldWidth:      "length",
+	fieldHeight:     "length",
+	fieldDiameter:   "length",
+	fieldWeight:     "weight",
+	fieldVolume:     "volume",
+}
+
+var textFields = map[string]bool{fieldThread: true, fieldStandard: true, fieldDrawing: true}
+
+var baseUnits = map[string]string{"length": "мм", "weight": "кг", "volume": "л"}
+
+var unitScales = map[string]map[string]float64{
+	"length": {"мм": 1, "mm": 1, "см": 10, "cm": 10, "дм": 100, "м": 1000, "m": 1000},
+	"weight": {"мг": 0.000001, "г": 0.001, "гр": 0.001, "g": 0.001, "кг": 1, "kg": 1, "т": 1000, "t": 1000},
+	"volume": {"мл": 0.001, "ml": 0.001, "л": 1, "l": 1, "м3": 1000, "м³": 1000, "m3": 1000},
+}
+
+var spacesReg = regexp.MustCompile(`\s+`)
+
+type compiledRule struct {
+	rule *models.ExtractionRule
+	re   *regexp.Regexp
+}
+
+// extractor applies an ordered rule set to product names. Rules are compiled
+// once when the extractor is built.
+type extractor struct {
+	rules []*compiledRule
+}
+
+func newExtractor(rules []*models.ExtractionRule) (*extractor, error) {
+	x := &extractor{}
+	for _, rule := range rules {
+		compiled, err := compileRule(rule)
+		if err != nil {
+			return nil, err
+		}
+		x.rules = append(x.rules, compiled)
+	}
+	return x, nil
+}
+
+func compileRule(rule *models.ExtractionRule) (*compiledRule, error) {
+	re, err := regexp.Compile(rule.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("rule %d: %v", rule.Position, err)
+	}
+
+	quantity, numeric := fieldQuantity[rule.Field]
+	if !numeric && !textFields[rule.Field] {
+		return nil, fmt.Errorf("rule %d: unknown field %q", rule.Position, rule.Field)
+	}
+	if numeric {
+		if _, ok := unitScales[quantity][strings.ToLower(rule.Unit)]; !ok {
+			return nil, fmt.Errorf("rule %d: unknown %s unit %q", rule.Position, quantity, rule.Unit)
+		}
+		groups := []string{"value"}
+		if rule.Field == fieldDimensions {
+			groups = []string{"a", "b", "c"}
+		}
+		for _, group := range groups {
+			if re.SubexpIndex(group) < 0 {
+				return nil, fmt.Errorf("rule %d: pattern has no group %q", rule.Position, group)
+			}
+		}
+	}
+
+	return &compiledRule{rule: rule, re: re}, nil
+}
+
+// loadExtractor builds the extractor from the active rules in the DB.
+func loadExtractor(ctx context.Context, repo repository.ExcelRepository) (*extractor, error) {
+	rules, err := repo.SelectExtractionRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		log.Warn("no active extraction rules, names are saved as is")
+	}
+
+	x, compileErr := newExtractor(rules)
+	if compileErr != nil {
+		log.Errorf("failed to compile extraction rules: %v", compileErr)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, compileErr.Error())
+	}
+	return x, nil
+}
+
+// Extract returns the name without the matched fragments and the attributes
+// found in it. Every rule is applied at most once.
+func (x *extractor) Extract(name string) *models.ExtractionResult {
+	res := &models.ExtractionResult{Source: name, Attributes: []*models.ExtractedAttribute{}}
+	for _, rule := range x.rules {
+		loc := rule.re.FindStringSubmatchIndex(name)
+		if loc == nil {
+			continue
+		}
+
+		attrs, err := rule.attributes(name, loc)
+		if err != nil {
+			log.Warnf("extraction rule %d skipped for %q: %v", rule.rule.Position, name, err)
+			continue
+		}
+		res.Attributes = append(res.Attributes, attrs...)
+		name = name[:loc[0]] + " " + name[loc[1]:]
+	}
+	res.Name = strings.Trim(spacesReg.ReplaceAllString(name, " "), " ,;")
+	return res
+}
+
+func (r *compiledRule) group(name string, loc []int, group string) string {
+	i := r.re.SubexpIndex(group)
+	if i < 0 || loc[2*i] < 0 {
+		return ""
+	}
+	return strings.TrimSpace(name[loc[2*i]:loc[2*i+1]])
+}
+
+func (r *compiledRule) attributes(name string, loc []int) ([]*models.ExtractedAttribute, error) {
+	match := strings.Trim(name[loc[0]:loc[1]], " ,;")
+	field := r.rule.Field
+
+	if textFields[field] {
+		value := r.group(name, loc, "value")
+		if value == "" {
+			value = match
+		}
+		if field == fieldStandard {
+			value = canonicalStandard(value)
+		}
+		return []*models.ExtractedAttribute{{Field: field, Value: value, Match: match, RuleId: r.rule.Id}}, nil
+	}
+
+	unit := r.group(name, loc, "unit")
+	if unit == "" {
+		unit = r.rule.Unit
+	}
+	quantity := fieldQuantity[field]
+	scale, ok := unitScales[quantity][strings.ToLower(unit)]
+	if !ok {
+		return nil, fmt.Errorf("unknown unit %q", unit)
+	}
+
+	fields := map[string]string{field: "value"}
+	order := []string{field}
+	if field == fieldDimensions {
+		fields = map[string]string{fieldLength: "a", fieldWidth: "b", fieldHeight: "c"}
+		order = []string{fieldLength, fieldWidth, fieldHeight}
+	}
+
+	var attrs []*models.ExtractedAttribute
+	for _, target := range order {
+		raw := r.group(name, loc, fields[target])
+		value, err := parseDecimal(raw)
+		if err != nil {
+			return nil, fmt.Errorf("wrong number %q", raw)
+		}
+		// scales like 0.001 leave float noise, micrometres are enough
+		value = math.Round(value*scale*1e6) / 1e6
+		attrs = append(attrs, &models.ExtractedAttribute{
+			Field:  target,
+			Value:  strconv.FormatFloat(value, 'f', -1, 64),
+			Number: value,
+			Unit:   baseUnits[quantity],
+			Match:  match,
+			RuleId: r.rule.Id,
+		})
+	}
+	return attrs, nil
+}
+
+// applyExtraction replaces the name with the cleaned one and applies the
+// extracted attributes.
+func applyExtraction(n *models.Nomenclature, res *models.ExtractionResult) {
+	n.Name = res.Name
+	applyAttributes(n, res.Attributes)
+}
+
+// applyAttributes moves the extracted attributes to the nomenclature fields
+// that are still empty, values from the sheet columns win.
+func applyAttributes(n *models.Nomenclature, attrs []*models.ExtractedAttribute) {
+	for _, attr := range attrs {
+		switch attr.Field {
+		case fieldLength:
+			if n.Length == 0 {
+				n.Length = float32(attr.Number)
+			}
+		case fieldWidth:
+			if n.Width == 0 {
+				n.Width = float32(attr.Number)
+			}
+		case fieldHeight:
+			if n.Height == 0 {
+				n.Height = float32(attr.Number)
+			}
+		case fieldWeight:
+			if n.WeightNetto == 0 {
+				n.WeightNetto = float32(attr.Number)
+			}
+			if n.WeightBrutto == 0 {
+				n.WeightBrutto = float32(attr.Number)
+			}
+		case fieldDrawing:
+			if n.DrawingName == "" {
+				n.DrawingName = attr.Value
+			}
+		case fieldStandard:
+			if n.GostTu == "" {
+				n.GostTu = attr.Value
+			}
+		}
+	}
+	n.Attributes = attrs
+}
+
+// TestExtractionRules runs the given rules, or the rules from the DB when
+// none are given, on sample names.
+func (e ExcelServiceImpl) TestExtractionRules(ctx context.Context, req *models.ExtractionTestReq) ([]*models.ExtractionResult, error) {
+	var x *extractor
+	if len(req.Rules) > 0 {
+		var err error
+		x, err = newExtractor(req.Rules)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+	} else {
+		var err error
+		x, err = loadExtractor(ctx, e.repo)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	res := make([]*models.ExtractionResult, 0, len(req.Names))
+	for _, name := range req.Names {
+		res = append(res, x.Extract(name))
+	}
+	return res, nil
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"excel-service/internal/models"
+	"testing"
+)
+
+// seedRules are the rules migration 0006 installs.
+var seedRules = []*models.ExtractionRule{
+	{Position: 10, Field: fieldStandard, Pattern: `(?i)(?:^|[^\pL\d])(?P<value>(?:ГОСТ(?:\s*Р)?|ОСТ|ТУ|СТО)\s*\d[\d.\-–/]*\d)`},
+	{Position: 20, Field: fieldDrawing, Pattern: `(?:^|[^\pL\d])(?P<value>[\pL\d]{1,4}(?:\.[\pL\d]{1,4}){2,5})(?:[^\pL\d]|$)`},
+	{Position: 30, Field: fieldDimensions, Unit: "мм", Pattern: `(?i)(?P<a>\d+(?:[.,]\d+)?)\s*[xх×*]\s*(?P<b>\d+(?:[.,]\d+)?)\s*[xх×*]\s*(?P<c>\d+(?:[.,]\d+)?)(?:\s*(?P<unit>мм|см|м|mm|cm|m)(?:[^\pL\d]|$))?`},
+	{Position: 40, Field: fieldDiameter, Unit: "мм", Pattern: `(?i)(?:Ø|⌀|Ду|DN)\s*(?P<value>\d+(?:[.,]\d+)?)(?:\s*(?P<unit>мм|mm)(?:[^\pL\d]|$))?`},
+	{Position: 50, Field: fieldThread, Pattern: `(?:^|[^\pL\d])(?P<value>[MМ]\d+(?:[.,]\d+)?(?:[xх×]\d[.,]\d+)?|G\s?\d+(?:/\d+)?)(?:[^\pL\d]|$)`},
+	{Position: 60, Field: fieldWeight, Unit: "кг", Pattern: `(?i)(?P<value>\d+(?:[.,]\d+)?)\s*(?P<unit>мг|кг|гр|г|т|kg|g)(?:[^\pL\d]|$)`},
+	{Position: 70, Field: fieldVolume, Unit: "л", Pattern: `(?i)(?P<value>\d+(?:[.,]\d+)?)\s*(?P<unit>мл|л|м3|м³|ml)(?:[^\pL\d]|$)`},
+}
+
+func TestExtract(t *testing.T) {
+	x, err := newExtractor(seedRules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		source   string
+		wantName string
+		want     map[string]string
+	}{
+		{
+			name:     "dimensions in centimetres",
+			source:   "Коробка 60х40х30 см",
+			wantName: "Коробка",
+			want:     map[string]string{fieldLength: "600", fieldWidth: "400", fieldHeight: "300"},
+		},
+		{
+			name:     "weight in grams",
+			source:   "Болт М12х1,25 оцинк. 250 г",
+			wantName: "Болт оцинк.",
+			want:     map[string]string{fieldThread: "М12х1,25", fieldWeight: "0.25"},
+		},
+		{
+			name:     "standard and diameter",
+			source:   "Отвод Ду 50 гост 17375-2001",
+			wantName: "Отвод",
+			want:     map[string]string{fieldStandard: "ГОСТ 17375-2001", fieldDiameter: "50"},
+		},
+		{
+			name:     "volume in millilitres",
+			source:   "Герметик, 310 мл",
+			wantName: "Герметик",
+			want:     map[string]string{fieldVolume: "0.31"},
+		},
+		{
+			name:     "nothing to extract",
+			source:   "Перчатки рабочие",
+			wantName: "Перчатки рабочие",
+			want:     map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := x.Extract(tt.source)
+			if res.Name != tt.wantName {
+				t.Errorf("Extract(%q) name = %q, want %q", tt.source, res.Name, tt.wantName)
+			}
+			got := map[string]string{}
+			for _, attr := range res.Attributes {
+				got[attr.Field] = attr.Value
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("Extract(%q) attributes = %v, want %v", tt.source, got, tt.want)
+			}
+			for field, value := range tt.want {
+				if got[field] != value {
+					t.Errorf("Extract(%q) %s = %q, want %q", tt.source, field, got[field], value)
+				}
+			}
+		})
+	}
+}
+
+func TestNewExtractorRejectsBadRules(t *testing.T) {
+	tests := []struct {
+		name string
+		rule *models.ExtractionRule
+	}{
+		{"bad pattern", &models.ExtractionRule{Field: fieldThread, Pattern: `(`}},
+		{"unknown field", &models.ExtractionRule{Field: "color", Pattern: `red`}},
+		{"unknown unit", &models.ExtractionRule{Field: fieldWeight, Unit: "фунт", Pattern: `(?P<value>\d+)`}},
+		{"no value group", &models.ExtractionRule{Field: fieldWeight, Unit: "кг", Pattern: `\d+ кг`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newExtractor([]*models.ExtractionRule{tt.rule}); err == nil {
+				t.Errorf("newExtractor() accepted %+v", tt.rule)
+			}
+		})
+	}
+}
+
+func TestApplyAttributes(t *testing.T) {
+	x, err := newExtractor(seedRules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := &models.Nomenclature{Name: "Ящик 60х40х30 см 2 кг", Length: 650, WeightBrutto: 2.5}
+	applyAttributes(n, x.Extract(n.Name).Attributes)
+	if n.Name != "Ящик 60х40х30 см 2 кг" {
+		t.Errorf("name changed to %q", n.Name)
+	}
+	if n.Length != 650 || n.Width != 400 || n.Height != 300 {
+		t.Errorf("dimensions = %v x %v x %v, the column length must win", n.Length, n.Width, n.Height)
+	}
+	if n.WeightNetto != 2 || n.WeightBrutto != 2.5 {
+		t.Errorf("weights = %v / %v", n.WeightNetto, n.WeightBrutto)
+	}
+	if len(n.Attributes) != 4 {
+		t.Errorf("attributes = %d, want 4", len(n.Attributes))
+	}
+}
@@ -0,0 +1,223 @@
+package service
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/labstack/gommon/log"
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	// headerScanRows is how far title blocks and logos may push the header
+	// down.
+	headerScanRows = 30
+	// maxHeaderRows bounds the band of a merged multi-row header.
+	maxHeaderRows = 4
+	// headerPartSeparator joins the levels of a multi-row column name.
+	headerPartSeparator = " / "
+)
+
+// sheetLayout is where the header of a sheet is and what its columns are
+// called. Rows are 0-based, the header takes rows headerRow to dataRow-1.
+type sheetLayout struct {
+	template  *sheetTemplate
+	headerRow int
+	dataRow   int
+	columns   []string
+}
+
+// mergeRange is a merged cell range with 0-based bounds.
+type mergeRange struct {
+	firstCol, firstRow, lastCol, lastRow int
+	value                                string
+}
+
+// detectLayout finds the header band of the sheet. With templates the band
+// starts at the first row from which all header cells of a template are
+// found; without them at the first row mostly filled with text. Rows held
+// by merged cells of the band join it and the columns get composite names
+// like "Цена / с НДС". When no header is found the rows before
+// minDataRow are taken as the header and template is nil. Data never starts
+// before minDataRow, so sheets of the fixed layouts the legacy importers
+// read keep their offset when a title row looks like a header.
+func detectLayout(excelFile *excelize.File, sheet string, rows [][]string, templates []*sheetTemplate, minDataRow int) *sheetLayout {
+	layout := findLayout(excelFile, sheet, rows, templates, minDataRow)
+	if layout.dataRow < minDataRow {
+		layout.dataRow = minDataRow
+		if layout.dataRow > len(rows) {
+			layout.dataRow = len(rows)
+		}
+	}
+	return layout
+}
+
+func findLayout(excelFile *excelize.File, sheet string, rows [][]string, templates []*sheetTemplate, fallbackDataRow int) *sheetLayout {
+	merges := sheetMerges(excelFile, sheet)
+	scan := len(rows)
+	if scan > headerScanRows {
+		scan = headerScanRows
+	}
+
+	if len(templates) > 0 {
+		for first := 0; first < scan; first++ {
+			for _, t := range templates {
+				for depth := 1; depth <= maxHeaderRows && first+depth <= len(rows); depth++ {
+					end := first + depth
+					if t.score(flattenHeader(rows, merges, first, end)) < len(t.header) {
+						continue
+					}
+					// a title merged across the columns is not a header level
+					for first+1 < end && distinctValues(flattenHeader(rows, merges, first, first+1)) <= 1 && t.score(flattenHeader(rows, merges, first+1, end)) == len(t.header) {
+						first++
+					}
+					if end-first < t.headerRows {
+						end = first + t.headerRows
+					}
+					return newSheetLayout(rows, merges, t, first, end-first)
+				}
+			}
+		}
+	} else if first := textRow(rows[:scan]); first >= 0 {
+		return newSheetLayout(rows, merges, nil, first, 1)
+	}
+
+	if fallbackDataRow > len(rows) {
+		fallbackDataRow = len(rows)
+	}
+	return &sheetLayout{dataRow: fallbackDataRow, columns: flattenHeader(rows, merges, 0, fallbackDataRow)}
+}
+
+// templateLayout is the layout of a sheet the caller reads with a known
+// template, the blank template's header rows are the fallback.
+func templateLayout(excelFile *excelize.File, sheet string, rows [][]string, t *sheetTemplate) *sheetLayout {
+	layout := detectLayout(excelFile, sheet, rows, []*sheetTemplate{t}, t.headerRows)
+	layout.template = t
+	return layout
+}
+
+// newSheetLayout extends the band by the rows its merged cells reach down
+// to, within maxHeaderRows.
+func newSheetLayout(rows [][]string, merges []mergeRange, t *sheetTemplate, first, depth int) *sheetLayout {
+	limit := first + maxHeaderRows
+	if limit > len(rows) {
+		limit = len(rows)
+	}
+	end := first + depth
+	if end > limit {
+		end = limit
+	}
+	for grown := true; grown; {
+		grown = false
+		for _, m := range merges {
+			if m.firstRow >= first && m.firstRow < end && m.lastRow >= end && m.lastRow < limit {
+				end, grown = m.lastRow+1, true
+			}
+		}
+	}
+	return &sheetLayout{template: t, headerRow: first, dataRow: end, columns: flattenHeader(rows, merges, first, end)}
+}
+
+// flattenHeader names the columns of the header rows first to end-1. Merged
+// cells give their value to every cell they cover, the levels of a column
+// are joined top down and repeats are dropped.
+func flattenHeader(rows [][]string, merges []mergeRange, first, end int) []string {
+	width := 0
+	for r := first; r < end; r++ {
+		if len(rows[r]) > width {
+			width = len(rows[r])
+		}
+	}
+	for _, m := range merges {
+		if m.firstRow < end && m.lastRow >= first && m.lastCol+1 > width {
+			width = m.lastCol + 1
+		}
+	}
+
+	band := make([][]string, end-first)
+	for r := range band {
+		band[r] = make([]string, width)
+		copy(band[r], rows[first+r])
+	}
+	for _, m := range merges {
+		for r := m.firstRow; r <= m.lastRow; r++ {
+			if r < first || r >= end {
+				continue
+			}
+			for c := m.firstCol; c <= m.lastCol; c++ {
+				band[r-first][c] = m.value
+			}
+		}
+	}
+
+	columns := make([]string, width)
+	for c := range columns {
+		var parts []string
+		for r := range band {
+			part := strings.Join(strings.Fields(band[r][c]), " ")
+			if part != "" && (len(parts) == 0 || parts[len(parts)-1] != part) {
+				parts = append(parts, part)
+			}
+		}
+		columns[c] = strings.Join(parts, headerPartSeparator)
+	}
+	return columns
+}
+
+// textRow returns the first row that fills most of the sheet width with
+// text, -1 if there is none. Title rows hold a cell or two, data rows hold
+// numbers.
+func textRow(rows [][]string) int {
+	widest := 0
+	counts := make([][2]int, len(rows))
+	for i, row := range rows {
+		for _, cell := range row {
+			cell = strings.TrimSpace(cell)
+			if cell == "" {
+				continue
+			}
+			counts[i][0]++
+			if _, err := strconv.ParseFloat(strings.Replace(cell, ",", ".", 1), 64); err != nil {
+				counts[i][1]++
+			}
+		}
+		if counts[i][0] > widest {
+			widest = counts[i][0]
+		}
+	}
+	for i, count := range counts {
+		filled, text := count[0], count[1]
+		if filled >= 2 && filled*10 >= widest*6 && text*10 >= filled*8 {
+			return i
+		}
+	}
+	return -1
+}
+
+func distinctValues(cells []string) int {
+	seen := map[string]bool{}
+	for _, cell := range cells {
+		if cell != "" {
+			seen[cell] = true
+		}
+	}
+	return len(seen)
+}
+
+func sheetMerges(excelFile *excelize.File, sheet string) []mergeRange {
+	cells, err := excelFile.GetMergeCells(sheet)
+	if err != nil {
+		log.Warnf("failed to read merged cells of sheet %s: %v", sheet, err)
+		return nil
+	}
+	merges := make([]mergeRange, 0, len(cells))
+	for _, cell := range cells {
+		firstCol, firstRow, startErr := excelize.CellNameToCoordinates(cell.GetStartAxis())
+		lastCol, lastRow, endErr := excelize.CellNameToCoordinates(cell.GetEndAxis())
+		if startErr != nil || endErr != nil {
+			continue
+		}
+		merges = append(merges, mergeRange{firstCol - 1, firstRow - 1, lastCol - 1, lastRow - 1, cell.GetCellValue()})
+	}
+	return merges
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func newSheet(t *testing.T, rows [][]string, merges ...[2]string) *excelize.File {
+	t.Helper()
+	f := excelize.NewFile()
+	for r, row := range rows {
+		for c, value := range row {
+			if value == "" {
+				continue
+			}
+			cell, err := excelize.CoordinatesToCellName(c+1, r+1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := f.SetCellValue("Sheet1", cell, value); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	for _, m := range merges {
+		if err := f.MergeCell("Sheet1", m[0], m[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return f
+}
+
+func TestDetectLayout(t *testing.T) {
+	tests := []struct {
+		name       string
+		rows       [][]string
+		merges     [][2]string
+		minDataRow int
+		headerRow  int
+		dataRow    int
+		columns    []string
+	}{
+		{
+			name: "title block above the header",
+			rows: [][]string{
+				{"Прайс-лист ООО Ромашка"},
+				{"на 01.10.2026"},
+				{},
+				{"Наименование", "Ед. изм.", "Цена"},
+				{"Труба", "м", "120"},
+			},
+			minDataRow: 1,
+			headerRow:  3,
+			dataRow:    4,
+			columns:    []string{"Наименование", "Ед. изм.", "Цена"},
+		},
+		{
+			name: "merged two-row header",
+			rows: [][]string{
+				{"Наименование", "Цена", "", "Кол-во"},
+				{"", "без НДС", "с НДС", ""},
+				{"Труба", "100", "120", "5"},
+			},
+			merges:     [][2]string{{"A1", "A2"}, {"B1", "C1"}, {"D1", "D2"}},
+			minDataRow: 1,
+			headerRow:  0,
+			dataRow:    2,
+			columns:    []string{"Наименование", "Цена / без НДС", "Цена / с НДС", "Кол-во"},
+		},
+		{
+			name: "no header",
+			rows: [][]string{
+				{"1", "100"},
+				{"2", "200"},
+				{"3", "300"},
+			},
+			minDataRow: 1,
+			headerRow:  0,
+			dataRow:    1,
+			columns:    []string{"1", "100"},
+		},
+		{
+			name: "data never starts before the floor",
+			rows: [][]string{
+				{"Справочник банков"},
+				{"БИК", "Наименование", "Адрес"},
+				{"044525225", "Сбербанк", "Москва"},
+				{"Код", "Банк", "Город"},
+				{"044525593", "Альфа-Банк", "Москва"},
+			},
+			minDataRow: 4,
+			headerRow:  1,
+			dataRow:    4,
+			columns:    []string{"БИК", "Наименование", "Адрес"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newSheet(t, tt.rows, tt.merges...)
+			layout := detectLayout(f, "Sheet1", tt.rows, nil, tt.minDataRow)
+			if layout.headerRow != tt.headerRow || layout.dataRow != tt.dataRow {
+				t.Errorf("detectLayout() rows = %d..%d, want %d..%d", layout.headerRow, layout.dataRow, tt.headerRow, tt.dataRow)
+			}
+			if !reflect.DeepEqual(layout.columns, tt.columns) {
+				t.Errorf("detectLayout() columns = %q, want %q", layout.columns, tt.columns)
+			}
+		})
+	}
+}
+
+func TestFlattenHeader(t *testing.T) {
+	tests := []struct {
+		name   string
+		rows   [][]string
+		merges []mergeRange
+		first  int
+		end    int
+		want   []string
+	}{
+		{
+			name: "single row",
+			rows: [][]string{{"Наименование", "  Цена,\n руб. "}},
+			end:  1,
+			want: []string{"Наименование", "Цена, руб."},
+		},
+		{
+			name: "merged parent over two columns",
+			rows: [][]string{
+				{"Наименование", "Цена", ""},
+				{"", "без НДС", "с НДС"},
+			},
+			merges: []mergeRange{{0, 0, 0, 1, "Наименование"}, {1, 0, 2, 0, "Цена"}},
+			end:    2,
+			want:   []string{"Наименование", "Цена / без НДС", "Цена / с НДС"},
+		},
+		{
+			name: "merge wider than the rows",
+			rows: [][]string{
+				{"Итого"},
+				{"а", "б"},
+			},
+			merges: []mergeRange{{0, 0, 2, 0, "Итого"}},
+			end:    2,
+			want:   []string{"Итого / а", "Итого / б", "Итого"},
+		},
+		{
+			name:  "band below a title",
+			rows:  [][]string{{"Прайс"}, {"Код", "Товар"}},
+			first: 1,
+			end:   2,
+			want:  []string{"Код", "Товар"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := flattenHeader(tt.rows, tt.merges, tt.first, tt.end); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("flattenHeader() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
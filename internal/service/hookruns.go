@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"excel-service/internal/apperrors"
+	"excel-service/internal/models"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+const (
+	hookRunRunning = "running"
+	hookRunDone    = "done"
+	hookRunFailed  = "failed"
+	// hookRunStaleAfter is how long a running run may miss its heartbeat
+	// before it counts as abandoned by a stopped process.
+	hookRunStaleAfter = 2 * time.Minute
+	hookRunHeartbeat  = 20 * time.Second
+	hookRunWait       = 2 * time.Minute
+	hookRunPoll       = 500 * time.Millisecond
+)
+
+// SaveNomenclatureFromDirectus processes the upload once per idempotency
+// key, the upload key when the caller sends none. One run of an upload runs
+// at a time whatever the key: deliveries while it runs wait for it and get
+// its response, as do duplicates of a finished key. Once a run imported the
+// upload, deliveries under any key get the response of that run.
+func (e ExcelServiceImpl) SaveNomenclatureFromDirectus(ctx context.Context, req *models.DirectusModel, idempotencyKey string) (*models.ResponseMsg, error) {
+	if req.Collection != "uploads" {
+		log.Warnf("collection is %s not uploads", req.Collection)
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "collection is not uploads")
+	}
+	if authErr := e.authorizeUpload(ctx, req.Key); authErr != nil {
+		return nil, authErr
+	}
+
+	key := idempotencyKey
+	if key == "" {
+		key = req.Key
+	}
+	// a new key does not import the upload again, reimporting is asked for
+	// explicitly by confirming a column mapping
+	done, doneErr := e.repo.SelectDoneHookRun(ctx, req.Key)
+	if doneErr != nil {
+		return nil, doneErr
+	}
+	if done != nil {
+		log.Infof("upload %s was imported by hook run %s, %s is not started", req.Key, done.Key, key)
+		return done.Response, nil
+	}
+	run, started, err := e.repo.StartHookRun(ctx, key, req.Key, hookRunStaleAfter)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(run.UploadId, req.Key) {
+		log.Warnf("idempotency key %s belongs to upload %s, not %s", key, run.UploadId, req.Key)
+		return nil, echo.NewHTTPError(http.StatusUnprocessableEntity, "idempotency key is used for another upload")
+	}
+	if !started {
+		log.Infof("upload %s has hook run %s %s, %s is not started", req.Key, run.Key, run.Status, key)
+		return e.waitHookRun(ctx, run)
+	}
+
+	return e.runUpload(ctx, run, req)
+}
+
+// runUpload imports the upload under the claimed run. Rows of an earlier
+// attempt or of a delivery under another key would be saved twice, so they
+// are cleared first. The run keeps its heartbeat while the import works.
+func (e ExcelServiceImpl) runUpload(ctx context.Context, run *models.HookRun, req *models.DirectusModel) (*models.ResponseMsg, error) {
+	stop := make(chan struct{})
+	defer close(stop)
+	go e.heartbeat(ctx, run.Key, stop)
+
+	if clearErr := e.clearUpload(ctx, req.Key); clearErr != nil {
+		e.finishHookRun(ctx, run, nil, clearErr)
+		return nil, clearErr
+	}
+	res, procErr := e.processUpload(ctx, req)
+	e.finishHookRun(ctx, run, res, procErr)
+	return res, procErr
+}
+
+func (e ExcelServiceImpl) heartbeat(ctx context.Context, key string, stop <-chan struct{}) {
+	ticker := time.NewTicker(hookRunHeartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := e.repo.TouchHookRun(ctx, key); err != nil {
+				log.Warnf("hook run %s missed a heartbeat: %v", key, err)
+			}
+		}
+	}
+}
+
+func (e ExcelServiceImpl) waitHookRun(ctx context.Context, run *models.HookRun) (*models.ResponseMsg, error) {
+	deadline := time.Now().Add(hookRunWait)
+	for {
+		switch run.Status {
+		case hookRunDone:
+			return run.Response, nil
+		case hookRunFailed:
+			failed := apperrors.From(echo.NewHTTPError(run.ErrorCode, run.Error))
+			if run.ErrorType != "" {
+				failed.Code = apperrors.Code(run.ErrorType)
+			}
+			return nil, failed
+		}
+		if time.Now().After(deadline) {
+			log.Warnf("hook run %s is still running", run.Key)
+			return nil, echo.NewHTTPError(http.StatusConflict, "upload is still being processed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, echo.NewHTTPError(http.StatusRequestTimeout, ctx.Err())
+		case <-time.After(hookRunPoll):
+		}
+		next, err := e.repo.SelectHookRun(ctx, run.Key)
+		if err != nil {
+			return nil, err
+		}
+		run = next
+	}
+}
+
+// finishHookRun records the outcome, a failure is kept with its HTTP status
+// and error code so waiting duplicates answer the same way. The upload of a
+// failed run leaves processing and can be rolled back.
+func (e ExcelServiceImpl) finishHookRun(ctx context.Context, run *models.HookRun, res *models.ResponseMsg, procErr error) {
+	run.Status, run.Response = hookRunDone, res
+	if procErr != nil {
+		run.Status, run.Response = hookRunFailed, nil
+		appErr := apperrors.From(procErr)
+		run.ErrorCode, run.ErrorType, run.Error = appErr.Status, string(appErr.Code), procErr.Error()
+		if details, ok := appErr.Details.(string); ok {
+			run.Error = details
+		}
+		if err := e.repo.SetUploadStatus(ctx, run.UploadId, uploadStatusFailed); err != nil {
+			log.Errorf("upload %s failed but its status was not saved: %v", run.UploadId, err)
+		}
+	}
+	if err := e.repo.FinishHookRun(ctx, run); err != nil {
+		log.Errorf("hook run %s finished as %s but was not saved: %v", run.Key, run.Status, err)
+	}
+}
+
+// clearUpload removes what an earlier attempt saved for the upload.
+func (e ExcelServiceImpl) clearUpload(ctx context.Context, uploadId string) error {
+	tx, txErr := e.db.Begin(ctx)
+	if txErr != nil {
+		log.Errorf("failed to begin tx: %v", txErr)
+		return echo.NewHTTPError(http.StatusInternalServerError, txErr)
+	}
+	res, delErr := e.repo.DeleteUploadData(ctx, uploadId, tx)
+	if delErr != nil {
+		return delErr
+	}
+	if cErr := tx.Commit(ctx); cErr != nil {
+		log.Errorf("failed to commit tx in clearUpload: %v", cErr)
+		return echo.NewHTTPError(http.StatusInternalServerError, cErr)
+	}
+	log.Infof("upload %s cleared before retry: %+v", uploadId, res)
+	return e.repo.DeleteUploadRowErrors(ctx, uploadId)
+}
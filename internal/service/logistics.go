@@ -0,0 +1,172 @@
+package service
+
+import (
+	"excel-service/internal/models"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Logistics fields of the supplier template. Dimensions are in мм, weights
+// in кг and volume in л, the same units the extraction rules use.
+const (
+	logisticsLength       = "length"
+	logisticsWidth        = "width"
+	logisticsHeight       = "height"
+	logisticsAmount       = "amount_in_package"
+	logisticsWeightNetto  = "weight_netto"
+	logisticsWeightBrutto = "weight_brutto"
+	logisticsVolume       = "volume"
+)
+
+var supplierLogisticsColumns = []struct {
+	field string
+	col   int
+}{
+	{logisticsLength, 32},
+	{logisticsWidth, 33},
+	{logisticsHeight, 34},
+	{logisticsAmount, 35},
+	{logisticsWeightNetto, 36},
+	{logisticsWeightBrutto, 37},
+	{logisticsVolume, 38},
+}
+
+const (
+	maxDimension = 20000  // мм, longer than any truck or rail platform
+	maxWeight    = 100000 // кг
+	maxAmount    = 1000000
+	// maxDensity is in кг/л, osmium, the densest metal, has 22.6
+	maxDensity = 25
+	// volumeTolerance lets the declared volume exceed the box a bit for
+	// rounded dimensions.
+	volumeTolerance = 1.05
+)
+
+// storageHeaderReg finds the storage type column, "Тип хранения" or
+// "Условия хранения". Its position is not fixed by the template, column 32
+// is the length.
+var storageHeaderReg = regexp.MustCompile(`(?i)хранени`)
+
+// detectStorageColumn returns the storage type column of the header band, -1
+// when the sheet has none.
+func detectStorageColumn(header [][]string) int {
+	for _, row := range header {
+		for i, cell := range row {
+			if storageHeaderReg.MatchString(cell) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// parseLogistics fills dimensions, weights, volume and amount in package
+// from the supplier row and returns the problems found. Bad values are
+// reported and left empty, the row itself is still saved.
+func parseLogistics(n *models.Nomenclature, row []string) []*models.UploadRowError {
+	var rowErrs []*models.UploadRowError
+	for _, column := range supplierLogisticsColumns {
+		if len(row) <= column.col || strings.TrimSpace(row[column.col]) == "" {
+			continue
+		}
+		value := row[column.col]
+		number, err := parseDecimal(value)
+		if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+			rowErrs = append(rowErrs, &models.UploadRowError{Field: column.field, Value: value, Message: "не число"})
+			continue
+		}
+		if msg := checkLogisticsValue(column.field, number); msg != "" {
+			rowErrs = append(rowErrs, &models.UploadRowError{Field: column.field, Value: value, Message: msg})
+			continue
+		}
+
+		switch column.field {
+		case logisticsLength:
+			n.Length = float32(number)
+		case logisticsWidth:
+			n.Width = float32(number)
+		case logisticsHeight:
+			n.Height = float32(number)
+		case logisticsAmount:
+			n.AmountInPackage = int(number)
+		case logisticsWeightNetto:
+			n.WeightNetto = float32(number)
+		case logisticsWeightBrutto:
+			n.WeightBrutto = float32(number)
+		case logisticsVolume:
+			n.Volume = float32(number)
+		}
+	}
+	return append(rowErrs, checkLogistics(n)...)
+}
+
+func checkLogisticsValue(field string, number float64) string {
+	if number < 0 {
+		return "значение не может быть отрицательным"
+	}
+	switch field {
+	case logisticsLength, logisticsWidth, logisticsHeight:
+		if number > maxDimension {
+			return fmt.Sprintf("размер больше %d мм", maxDimension)
+		}
+	case logisticsWeightNetto, logisticsWeightBrutto:
+		if number > maxWeight {
+			return fmt.Sprintf("масса больше %d кг", maxWeight)
+		}
+	case logisticsAmount:
+		if number != math.Trunc(number) {
+			return "количество в упаковке должно быть целым"
+		}
+		if number > maxAmount {
+			return fmt.Sprintf("количество в упаковке больше %d", maxAmount)
+		}
+	}
+	return ""
+}
+
+// checkLogistics checks the values against each other: netto is not more
+// than brutto, the volume fits the box of the dimensions and the density is
+// physically possible. A missing volume is computed from the dimensions.
+func checkLogistics(n *models.Nomenclature) []*models.UploadRowError {
+	var rowErrs []*models.UploadRowError
+	if n.WeightNetto > 0 && n.WeightBrutto > 0 && n.WeightNetto > n.WeightBrutto {
+		rowErrs = append(rowErrs, &models.UploadRowError{
+			Field:   logisticsWeightNetto,
+			Value:   formatLogistics(n.WeightNetto),
+			Message: fmt.Sprintf("масса нетто больше массы брутто %s кг", formatLogistics(n.WeightBrutto)),
+		})
+	}
+
+	if n.Length > 0 && n.Width > 0 && n.Height > 0 {
+		box := float64(n.Length) * float64(n.Width) * float64(n.Height) / 1e6
+		if n.Volume == 0 {
+			n.Volume = float32(math.Round(box*1e6) / 1e6)
+		} else if float64(n.Volume) > box*volumeTolerance {
+			rowErrs = append(rowErrs, &models.UploadRowError{
+				Field:   logisticsVolume,
+				Value:   formatLogistics(n.Volume),
+				Message: fmt.Sprintf("объем больше габаритов %s л", strconv.FormatFloat(box, 'f', -1, 64)),
+			})
+		}
+	}
+
+	weight := n.WeightBrutto
+	if weight == 0 {
+		weight = n.WeightNetto
+	}
+	if weight > 0 && n.Volume > 0 && float64(weight)/float64(n.Volume) > maxDensity {
+		rowErrs = append(rowErrs, &models.UploadRowError{
+			Field:   logisticsVolume,
+			Value:   formatLogistics(n.Volume),
+			Message: fmt.Sprintf("плотность %s кг/л физически невозможна", strconv.FormatFloat(float64(weight)/float64(n.Volume), 'f', 1, 64)),
+		})
+	}
+	return rowErrs
+}
+
+func formatLogistics(v float32) string {
+	return strconv.FormatFloat(float64(v), 'f', -1, 32)
+}
@@ -0,0 +1,268 @@
+package service
+
+import (
+	"context"
+	"excel-service/internal/models"
+	"excel-service/internal/repository"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+// legalForms are dropped from manufacturer names before matching, long
+// forms go first so "акционерное общество" is not left half cut.
+var legalForms = []string{
+	"общество с ограниченной ответственностью",
+	"публичное акционерное общество",
+	"открытое акционерное общество",
+	"закрытое акционерное общество",
+	"непубличное акционерное общество",
+	"акционерное общество",
+	"индивидуальный предприниматель",
+	"ооо", "пао", "оао", "зао", "нао", "ао", "ип",
+	"ooo", "pao", "oao", "zao", "ao",
+	"llc", "ltd", "inc", "corp", "co", "gmbh", "ag", "plc", "sa", "spa", "bv", "oy", "ab",
+}
+
+var cyrillicToLatin = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ж': "zh", 'з': "z",
+	'и': "i", 'й': "i", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p",
+	'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch",
+	'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "iu", 'я': "ia",
+}
+
+// manufacturerKey reduces a manufacturer string to the form aliases are
+// stored in: legal forms dropped and Cyrillic transliterated, so
+// "ООО Северсталь", "Северсталь ПАО" and "SEVERSTAL" give "severstal".
+func manufacturerKey(s string) string {
+	name := " " + normalizeText(s) + " "
+	for _, form := range legalForms {
+		for strings.Contains(name, " "+form+" ") {
+			name = strings.Replace(name, " "+form+" ", " ", 1)
+		}
+	}
+
+	var key strings.Builder
+	for _, r := range strings.TrimSpace(name) {
+		if latin, ok := cyrillicToLatin[r]; ok {
+			key.WriteString(latin)
+			continue
+		}
+		key.WriteRune(r)
+	}
+	return key.String()
+}
+
+// manufacturerResolver maps import manufacturer strings to directory ids.
+// Strings without an alias are counted and queued for curation once the
+// import is over.
+type manufacturerResolver struct {
+	aliases    map[string]string
+	unresolved map[string]*models.UnresolvedManufacturer
+}
+
+func newManufacturerResolver(ctx context.Context, repo repository.ExcelRepository) *manufacturerResolver {
+	aliases, err := repo.SelectManufacturerAliases(ctx)
+	if err != nil {
+		log.Warnf("manufacturers are not resolved: %v", err)
+	}
+	return &manufacturerResolver{aliases: aliases, unresolved: map[string]*models.UnresolvedManufacturer{}}
+}
+
+func (r *manufacturerResolver) resolve(n *models.Nomenclature) {
+	key := manufacturerKey(n.Manufacturer)
+	if key == "" || r.aliases == nil {
+		return
+	}
+	if id, ok := r.aliases[key]; ok {
+		n.ManufacturerId = id
+		return
+	}
+
+	value := strings.TrimSpace(n.Manufacturer)
+	queued, ok := r.unresolved[key]
+	if !ok {
+		queued = &models.UnresolvedManufacturer{Key: key, LastSeen: time.Now()}
+		r.unresolved[key] = queued
+	}
+	queued.Occurrences++
+	for _, v := range queued.Values {
+		if v == value {
+			return
+		}
+	}
+	queued.Values = append(queued.Values, value)
+}
+
+func (r *manufacturerResolver) flush(ctx context.Context, repo repository.ExcelRepository, uploadId string) {
+	if len(r.unresolved) == 0 {
+		return
+	}
+	queue := make([]*models.UnresolvedManufacturer, 0, len(r.unresolved))
+	for _, item := range r.unresolved {
+		queue = append(queue, item)
+	}
+	if err := repo.QueueUnresolvedManufacturers(ctx, uploadId, queue); err != nil {
+		log.Errorf("failed to queue %d unresolved manufacturers: %v", len(queue), err)
+	}
+}
+
+// parseManufacturers reads the directory file: name, aliases separated by
+// ";", INN and country. The first row is the header.
+func parseManufacturers(rows [][]string) []*models.Manufacturer {
+	var manufacturers []*models.Manufacturer
+	for i, row := range rows {
+		if i == 0 || len(row) == 0 || strings.TrimSpace(row[0]) == "" {
+			continue
+		}
+		m := &models.Manufacturer{Name: strings.TrimSpace(row[0])}
+		if len(row) > 1 {
+			for _, alias := range strings.Split(row[1], ";") {
+				if alias = strings.TrimSpace(alias); alias != "" {
+					m.Aliases = append(m.Aliases, alias)
+				}
+			}
+		}
+		if len(row) > 2 {
+			m.Inn = strings.TrimSpace(row[2])
+		}
+		if len(row) > 3 {
+			m.Country = strings.TrimSpace(row[3])
+		}
+		manufacturers = append(manufacturers, m)
+	}
+	return manufacturers
+}
+
+// manufacturerAliasKeys returns the distinct keys of the name and aliases.
+func manufacturerAliasKeys(m *models.Manufacturer) []string {
+	var keys []string
+	seen := map[string]bool{}
+	for _, alias := range append([]string{m.Name}, m.Aliases...) {
+		key := manufacturerKey(alias)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func (e ExcelServiceImpl) ImportManufacturers(ctx context.Context, file *multipart.FileHeader) (*models.ManufacturerImport, error) {
+	src, err := file.Open()
+	if err != nil {
+		log.Errorf("failed to open file: %v", err)
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	defer src.Close()
+
+	excelFile, fileErr := openWorkbook(src, e.cfg.Limits)
+	if fileErr != nil {
+		return nil, fileErr
+	}
+	rows, rowsErr := readRows(excelFile, excelFile.GetSheetList()[0], e.cfg.Limits)
+	if rowsErr != nil {
+		return nil, rowsErr
+	}
+
+	manufacturers := parseManufacturers(rows)
+	if len(manufacturers) == 0 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "в файле нет производителей")
+	}
+
+	tx, txErr := e.db.Begin(ctx)
+	if txErr != nil {
+		log.Errorf("failed to begin tx: %v", txErr)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, txErr)
+	}
+
+	res := &models.ManufacturerImport{}
+	aliases := map[string]string{}
+	for _, m := range manufacturers {
+		keys := manufacturerAliasKeys(m)
+		id, saveErr := e.repo.UpsertManufacturer(ctx, m, keys, tx)
+		if saveErr != nil {
+			return nil, saveErr
+		}
+		for _, key := range keys {
+			aliases[key] = id
+		}
+		res.Manufacturers++
+		res.Aliases += len(keys)
+	}
+
+	// queued strings whose keys became aliases are linked right away, the
+	// queue is read in the tx so that keys resolved meanwhile are not
+	// resolved twice
+	keys := make([]string, 0, len(aliases))
+	for key := range aliases {
+		keys = append(keys, key)
+	}
+	queued, queueErr := e.repo.LockUnresolvedManufacturers(ctx, keys, tx)
+	if queueErr != nil {
+		return nil, queueErr
+	}
+	for _, key := range queued {
+		count, resolveErr := e.repo.ResolveManufacturerKey(ctx, key, aliases[key], tx)
+		if resolveErr != nil {
+			return nil, resolveErr
+		}
+		res.Resolved += count
+	}
+
+	if cErr := tx.Commit(ctx); cErr != nil {
+		log.Errorf("failed to commit tx: %v", cErr)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, cErr)
+	}
+	return res, nil
+}
+
+func (e ExcelServiceImpl) GetManufacturers(ctx context.Context) ([]*models.Manufacturer, error) {
+	return e.repo.SelectManufacturers(ctx)
+}
+
+func (e ExcelServiceImpl) GetUnresolvedManufacturers(ctx context.Context) ([]*models.UnresolvedManufacturer, error) {
+	return e.repo.SelectUnresolvedManufacturers(ctx)
+}
+
+// ResolveManufacturer makes the queued key an alias of an existing
+// manufacturer, or of a new one described in the request.
+func (e ExcelServiceImpl) ResolveManufacturer(ctx context.Context, req *models.ManufacturerResolveReq) (*models.ManufacturerResolve, error) {
+	if req.Key == "" {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "key is required")
+	}
+	if req.ManufacturerId == "" && (req.Manufacturer == nil || strings.TrimSpace(req.Manufacturer.Name) == "") {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "manufacturer_id or manufacturer name is required")
+	}
+
+	tx, txErr := e.db.Begin(ctx)
+	if txErr != nil {
+		log.Errorf("failed to begin tx: %v", txErr)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, txErr)
+	}
+
+	id := req.ManufacturerId
+	if id == "" {
+		req.Manufacturer.Name = strings.TrimSpace(req.Manufacturer.Name)
+		newId, err := e.repo.UpsertManufacturer(ctx, req.Manufacturer, manufacturerAliasKeys(req.Manufacturer), tx)
+		if err != nil {
+			return nil, err
+		}
+		id = newId
+	}
+
+	count, err := e.repo.ResolveManufacturerKey(ctx, req.Key, id, tx)
+	if err != nil {
+		return nil, err
+	}
+	if cErr := tx.Commit(ctx); cErr != nil {
+		log.Errorf("failed to commit tx: %v", cErr)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, cErr)
+	}
+	return &models.ManufacturerResolve{ManufacturerId: id, Nomenclature: count}, nil
+}
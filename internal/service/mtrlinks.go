@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"excel-service/internal/models"
+	"excel-service/internal/repository"
+	"math"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+// Nomenclature sources. Only supplier items are linked, the references
+// they are linked to come from organizer_catalogue.
+const (
+	sourceSupplier  = "supplier"
+	sourceMtr       = "mtr"
+	sourceOrganizer = "organizer"
+)
+
+const (
+	// minMtrLinkConfidence is the lowest fuzzy score linked automatically,
+	// the rest waits for manual mapping.
+	minMtrLinkConfidence = 0.5
+	mtrCandidateCap      = 20
+	mtrLinkBatch         = 1000
+	maxUnmatchedPage     = 500
+)
+
+// matchMtr scores a supplier item against an MTR reference. SKMTR, AMTO and
+// KS NSI codes are checked first and a match is certain. Otherwise the name
+// similarity decides, the same drawing, standard or manufacturer add to it
+// but a fuzzy match never reaches the certainty of a code.
+func matchMtr(item, ref *models.MatchItem) (float64, string) {
+	codes := []struct {
+		method string
+		a, b   string
+	}{
+		{"code_skmtr", item.CodeSkmtr, ref.CodeSkmtr},
+		{"code_amto", item.CodeAmto, ref.CodeAmto},
+		{"code_ks_nsi", item.CodeKsNsi, ref.CodeKsNsi},
+	}
+	for _, code := range codes {
+		if sameCode(code.a, code.b) {
+			return 1, code.method
+		}
+	}
+	for _, code := range codes {
+		if conflictingCode(code.a, code.b) {
+			return 0, ""
+		}
+	}
+
+	score := nameSimilarity(item.Name, ref.Name)
+	if score == 0 {
+		return 0, ""
+	}
+	if sameCode(item.DrawingName, ref.DrawingName) {
+		score += 0.2
+	}
+	if sameCode(item.GostTu, ref.GostTu) {
+		score += 0.1
+	}
+	if m := normalizeText(item.Manufacturer); m != "" && m == normalizeText(ref.Manufacturer) {
+		score += 0.05
+	}
+	return math.Round(math.Min(score, 0.95)*1000) / 1000, "name"
+}
+
+func bestMtrLink(item *models.MatchItem, refs []*models.MatchItem) *models.MtrLink {
+	var best *models.MtrLink
+	for _, ref := range refs {
+		score, method := matchMtr(item, ref)
+		if method == "" || (best != nil && score <= best.Confidence) {
+			continue
+		}
+		best = &models.MtrLink{NomenclatureId: item.Id, MtrId: ref.Id, Confidence: score, Method: method}
+	}
+	if best == nil || best.Confidence < minMtrLinkConfidence {
+		return nil
+	}
+	return best
+}
+
+// linkToMtr links unlinked supplier items of the upload, or of the whole
+// catalogue when uploadId is empty, batch by batch. Items left unmatched
+// stay out of later batches because the offset moves past them.
+func linkToMtr(ctx context.Context, repo repository.ExcelRepository, uploadId string) (*models.MtrLinkRun, error) {
+	res := &models.MtrLinkRun{}
+	offset := 0
+	for {
+		items, err := repo.SelectUnlinkedSupplierItems(ctx, uploadId, mtrLinkBatch, offset)
+		if err != nil {
+			return nil, err
+		}
+
+		var links []*models.MtrLink
+		for _, item := range items {
+			refs, refErr := repo.SelectMtrCandidates(ctx, item, mtrCandidateCap)
+			if refErr != nil {
+				return nil, refErr
+			}
+			if link := bestMtrLink(item, refs); link != nil {
+				links = append(links, link)
+			}
+		}
+		if len(links) > 0 {
+			if saveErr := repo.SaveMtrLinks(ctx, links); saveErr != nil {
+				return nil, saveErr
+			}
+		}
+
+		res.Checked += len(items)
+		res.Linked += len(links)
+		offset += len(items) - len(links)
+		if len(items) < mtrLinkBatch {
+			break
+		}
+	}
+
+	log.Infof("mtr linker: %d of %d supplier items linked", res.Linked, res.Checked)
+	return res, nil
+}
+
+// LinkSupplierItemsToMtr relinks the whole catalogue, links to references
+// removed from the organizer catalogue are dropped first.
+func (e ExcelServiceImpl) LinkSupplierItemsToMtr(ctx context.Context) (*models.MtrLinkRun, error) {
+	stale, err := e.repo.DeleteStaleMtrLinks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if stale > 0 {
+		log.Infof("mtr linker: %d links to removed references dropped", stale)
+	}
+	return linkToMtr(ctx, e.repo, "")
+}
+
+func (e ExcelServiceImpl) GetUnmatchedSupplierItems(ctx context.Context, limit, offset int) ([]*models.MatchItem, error) {
+	if limit <= 0 || limit > maxUnmatchedPage {
+		limit = maxUnmatchedPage
+	}
+	if offset < 0 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "offset must not be negative")
+	}
+	return e.repo.SelectUnlinkedSupplierItems(ctx, "", limit, offset)
+}
+
+func (e ExcelServiceImpl) SetMtrLink(ctx context.Context, nomenclatureId string, req *models.MtrLinkReq) (*models.MtrLink, error) {
+	if req.MtrId == "" {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "mtr_id is required")
+	}
+	return e.repo.SetManualMtrLink(ctx, nomenclatureId, req.MtrId)
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	digitRunReg   = regexp.MustCompile(`\d+(?:[ \x{00a0}]+\d+)*`)
+	digitGroupReg = regexp.MustCompile(`^\d{1,3}(?:[ \x{00a0}]+\d{3})+$`)
+)
+
+// joinDigitGroups removes the spaces of thousands groups: "1 500" becomes
+// "1500". Runs that are not valid grouping, like "10 1000", are kept as
+// written.
+func joinDigitGroups(s string) string {
+	return digitRunReg.ReplaceAllStringFunc(s, func(run string) string {
+		if !digitGroupReg.MatchString(run) {
+			return run
+		}
+		return strings.Join(strings.Fields(run), "")
+	})
+}
+
+// parseDecimal parses numbers written the way suppliers do: "1 500,75",
+// "1500.75", "12,5".
+func parseDecimal(s string) (float64, error) {
+	s = joinDigitGroups(strings.TrimSpace(s))
+	s = strings.Replace(s, ",", ".", 1)
+	return strconv.ParseFloat(s, 64)
+}
@@ -0,0 +1,254 @@
+package service
+
+import (
+	"context"
+	"excel-service/internal/models"
+	"excel-service/internal/repository"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+// priceItemKey identifies the same supplier item across price list versions.
+func priceItemKey(n *models.Nomenclature) string {
+	for _, code := range []string{n.TmcCodeVendor, n.CodeSkmtr, n.CodeKsNsi, n.CodeAmto} {
+		if code = strings.TrimSpace(code); code != "" {
+			return code
+		}
+	}
+	return strings.ToLower(strings.Join(strings.Fields(n.Name), " "))
+}
+
+// uploadPrices collects the items of the price lists an upload fills. The
+// rows of every file of the upload go into one version per price list,
+// which holds one price per item key: a row repeating the key of an
+// earlier row is imported but left out of the version.
+type uploadPrices struct {
+	lists []string
+	keys  map[string]string
+	items []*models.PriceListItem
+	// filled is set once a sheet of priced items was imported
+	filled bool
+}
+
+func newUploadPrices(lists []string) *uploadPrices {
+	return &uploadPrices{lists: lists, keys: map[string]string{}}
+}
+
+// check reports a row whose key an earlier row of the upload has, without
+// price lists there is no version to keep it out of.
+func (p *uploadPrices) check(n *models.Nomenclature) []*models.UploadRowError {
+	if len(p.lists) == 0 {
+		return nil
+	}
+	key := priceItemKey(n)
+	if first, ok := p.keys[key]; ok {
+		return []*models.UploadRowError{{Field: "item_key", Value: key, Message: "позиция повторяет " + first + ", в версию прайс-листа попадет только первая"}}
+	}
+	return nil
+}
+
+// add puts the saved row into the versions when its key is free.
+func (p *uploadPrices) add(n *models.Nomenclature, sheet string, row int) {
+	if len(p.lists) == 0 {
+		return
+	}
+	key := priceItemKey(n)
+	if _, ok := p.keys[key]; ok {
+		return
+	}
+	p.keys[key] = fmt.Sprintf("строку %d листа %q", row+1, sheet)
+	p.items = append(p.items, newPriceListItem(n))
+}
+
+func newPriceListItem(n *models.Nomenclature) *models.PriceListItem {
+	return &models.PriceListItem{
+		ItemKey:        priceItemKey(n),
+		NomenclatureId: n.Id,
+		Name:           n.Name,
+		PricePerUnit:   n.PricePerUnit,
+		Currency:       n.Currency,
+		WholesaleItems: n.WholesaleItems,
+		PriceTiers:     n.PriceTiers,
+	}
+}
+
+// save stores the collected items as a new version of every price list.
+func (p *uploadPrices) save(ctx context.Context, repo repository.ExcelRepository, uploadId string) error {
+	for _, priceId := range p.lists {
+		version, err := repo.NewPriceListVersion(ctx, priceId, uploadId, p.items)
+		if err != nil {
+			return err
+		}
+		log.Infof("price list %s saved as version %d with %d items", priceId, version.Version, version.Items)
+	}
+	return nil
+}
+
+func diffPriceLists(from, to []*models.PriceListItem) *models.PriceListDiff {
+	diff := &models.PriceListDiff{
+		Added:    []*models.PriceListItem{},
+		Removed:  []*models.PriceListItem{},
+		Repriced: []*models.PriceChange{},
+	}
+
+	old := make(map[string]*models.PriceListItem, len(from))
+	for _, item := range from {
+		old[item.ItemKey] = item
+	}
+
+	for _, item := range to {
+		prev, ok := old[item.ItemKey]
+		if !ok {
+			diff.Added = append(diff.Added, item)
+			continue
+		}
+		delete(old, item.ItemKey)
+		if prev.PricePerUnit != item.PricePerUnit || prev.Currency != item.Currency || !sameWholesale(prev.WholesaleItems, item.WholesaleItems) || !samePriceTiers(prev.PriceTiers, item.PriceTiers) {
+			diff.Repriced = append(diff.Repriced, &models.PriceChange{
+				ItemKey:         item.ItemKey,
+				Name:            item.Name,
+				OldPricePerUnit: prev.PricePerUnit,
+				NewPricePerUnit: item.PricePerUnit,
+				OldCurrency:     prev.Currency,
+				NewCurrency:     item.Currency,
+				OldWholesale:    prev.WholesaleItems,
+				NewWholesale:    item.WholesaleItems,
+				OldPriceTiers:   prev.PriceTiers,
+				NewPriceTiers:   item.PriceTiers,
+			})
+		}
+	}
+
+	for _, item := range old {
+		diff.Removed = append(diff.Removed, item)
+	}
+
+	sort.Slice(diff.Added, func(i, j int) bool { return diff.Added[i].ItemKey < diff.Added[j].ItemKey })
+	sort.Slice(diff.Removed, func(i, j int) bool { return diff.Removed[i].ItemKey < diff.Removed[j].ItemKey })
+	sort.Slice(diff.Repriced, func(i, j int) bool { return diff.Repriced[i].ItemKey < diff.Repriced[j].ItemKey })
+
+	return diff
+}
+
+func sameWholesale(a, b *models.WholesaleItems) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func samePriceTiers(a, b []*models.PriceTier) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].QuantityFrom != b[i].QuantityFrom || a[i].Price != b[i].Price {
+			return false
+		}
+		if (a[i].QuantityTo == nil) != (b[i].QuantityTo == nil) {
+			return false
+		}
+		if a[i].QuantityTo != nil && *a[i].QuantityTo != *b[i].QuantityTo {
+			return false
+		}
+	}
+	return true
+}
+
+func (e ExcelServiceImpl) GetPriceListVersions(ctx context.Context, priceId string) ([]*models.PriceListVersion, error) {
+	if authErr := e.authorizePriceList(ctx, priceId); authErr != nil {
+		return nil, authErr
+	}
+	return e.repo.SelectPriceListVersions(ctx, priceId)
+}
+
+// GetPriceListDiff compares two versions of a price list. Zero versions mean
+// the latest version and the one before it.
+func (e ExcelServiceImpl) GetPriceListDiff(ctx context.Context, priceId string, fromVersion, toVersion int) (*models.PriceListDiff, error) {
+	if authErr := e.authorizePriceList(ctx, priceId); authErr != nil {
+		return nil, authErr
+	}
+	versions, err := e.repo.SelectPriceListVersions(ctx, priceId)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "price list has no versions")
+	}
+
+	if toVersion == 0 {
+		toVersion = versions[len(versions)-1].Version
+	}
+	if fromVersion == 0 {
+		fromVersion = toVersion - 1
+	}
+
+	known := make(map[int]bool, len(versions))
+	for _, v := range versions {
+		known[v.Version] = true
+	}
+	if !known[toVersion] {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "price list version not found")
+	}
+
+	var fromItems []*models.PriceListItem
+	if known[fromVersion] {
+		fromItems, err = e.repo.SelectPriceListItems(ctx, priceId, fromVersion)
+		if err != nil {
+			return nil, err
+		}
+	} else if fromVersion != 0 {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "price list version not found")
+	}
+
+	toItems, err := e.repo.SelectPriceListItems(ctx, priceId, toVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := diffPriceLists(fromItems, toItems)
+	diff.PriceId = priceId
+	diff.FromVersion = fromVersion
+	diff.ToVersion = toVersion
+	return diff, nil
+}
+
+// GetNomenclaturePrice returns the price per unit that applies to the ordered
+// quantity together with its ruble equivalent on the date.
+func (e ExcelServiceImpl) GetNomenclaturePrice(ctx context.Context, nomenclatureId string, quantity float64, date time.Time) (*models.PriceQuote, error) {
+	base, currency, err := e.repo.SelectNomenclaturePrice(ctx, nomenclatureId)
+	if err != nil {
+		return nil, err
+	}
+
+	tiers, err := e.repo.SelectPriceTiers(ctx, nomenclatureId)
+	if err != nil {
+		return nil, err
+	}
+
+	rate, rateDate, err := e.rubRate(ctx, currency, date)
+	if err != nil {
+		return nil, err
+	}
+
+	price, tier := effectivePrice(base, tiers, quantity)
+	return &models.PriceQuote{
+		NomenclatureId:  nomenclatureId,
+		Quantity:        quantity,
+		Currency:        currency,
+		PricePerUnit:    base,
+		Price:           price,
+		Tier:            tier,
+		Date:            date,
+		Rate:            rate,
+		RateDate:        rateDate,
+		PricePerUnitRub: roundMoney(base * rate),
+		PriceRub:        roundMoney(price * rate),
+	}, nil
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"excel-service/internal/models"
+	"testing"
+)
+
+func TestUploadPricesAdd(t *testing.T) {
+	prices := newUploadPrices([]string{"price-1"})
+	rows := []struct {
+		item *models.Nomenclature
+		dup  bool
+	}{
+		{&models.Nomenclature{Name: "Труба 57х3,5", TmcCodeVendor: "A-1"}, false},
+		{&models.Nomenclature{Name: "Труба 57х3,5 ГОСТ", TmcCodeVendor: "A-1"}, true},
+		{&models.Nomenclature{Name: "Отвод  90"}, false},
+		{&models.Nomenclature{Name: "отвод 90"}, true},
+		{&models.Nomenclature{Name: "Отвод 90", CodeSkmtr: "123"}, false},
+	}
+	for i, row := range rows {
+		rowErrs := prices.check(row.item)
+		if (len(rowErrs) > 0) != row.dup {
+			t.Errorf("row %d: check() = %v, want duplicate %v", i, rowErrs, row.dup)
+		}
+		prices.add(row.item, "Лист1", i)
+	}
+	if len(prices.items) != 3 {
+		t.Errorf("add() collected %d items, want 3", len(prices.items))
+	}
+
+	prices = newUploadPrices(nil)
+	for i, row := range rows {
+		if rowErrs := prices.check(row.item); len(rowErrs) > 0 {
+			t.Errorf("row %d without price lists: check() = %v", i, rowErrs)
+		}
+		prices.add(row.item, "Лист1", i)
+	}
+	if len(prices.items) != 0 {
+		t.Errorf("add() without price lists collected %d items", len(prices.items))
+	}
+}
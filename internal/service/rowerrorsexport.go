@@ -0,0 +1,67 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"excel-service/internal/apperrors"
+	"excel-service/internal/export"
+	"excel-service/internal/models"
+	"strconv"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	exportFormatCSV  = "csv"
+	exportFormatXLSX = "xlsx"
+)
+
+// rowErrorsHeader are the columns of a row errors report.
+var rowErrorsHeader = []string{"Файл", "Лист", "Строка", "Поле", "Значение", "Код", "Сообщение"}
+
+// ExportUploadRowErrors writes the row errors of the upload as a CSV file or
+// a workbook. Values come from supplier files, so every cell is written
+// through export with the configured formula policy.
+func (e ExcelServiceImpl) ExportUploadRowErrors(ctx context.Context, uploadId, format string) (*models.ExportFile, error) {
+	if format != exportFormatCSV && format != exportFormatXLSX {
+		return nil, apperrors.New(apperrors.BadRequest, "format must be csv or xlsx")
+	}
+	rowErrs, err := e.GetUploadRowErrors(ctx, uploadId)
+	if err != nil {
+		return nil, err
+	}
+
+	records := [][]string{rowErrorsHeader}
+	for _, rowErr := range rowErrs {
+		records = append(records, []string{
+			rowErr.FileName, rowErr.Sheet, strconv.Itoa(rowErr.Row), rowErr.Field, rowErr.Value, rowErr.Code, rowErr.Message,
+		})
+	}
+
+	policy := export.ParsePolicy(e.cfg.Export.FormulaPolicy)
+	var buf bytes.Buffer
+	file := &models.ExportFile{Name: "upload-" + uploadId + "-errors." + format}
+	if format == exportFormatCSV {
+		if writeErr := export.NewCSVWriter(&buf).WriteAll(records); writeErr != nil {
+			return nil, apperrors.Wrap(apperrors.Internal, writeErr)
+		}
+		file.ContentType = "text/csv; charset=utf-8"
+		file.Data = buf.Bytes()
+		return file, nil
+	}
+
+	f := excelize.NewFile()
+	sheet := f.GetSheetName(0)
+	for i, record := range records {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		if setErr := export.SetRow(f, sheet, cell, record, policy); setErr != nil {
+			return nil, apperrors.Wrap(apperrors.Internal, setErr)
+		}
+	}
+	if writeErr := f.Write(&buf); writeErr != nil {
+		return nil, apperrors.Wrap(apperrors.Internal, writeErr)
+	}
+	file.ContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	file.Data = buf.Bytes()
+	return file, nil
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"excel-service/internal/apperrors"
+	"excel-service/internal/models"
+	"excel-service/internal/repository"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/labstack/gommon/log"
+	"github.com/xuri/excelize/v2"
+)
+
+// sheetTemplate is a layout an importer understands, told apart by the
+// header cells at fixed columns. headerRows is how many rows the header
+// of the blank template takes.
+type sheetTemplate struct {
+	name       string
+	header     map[int]string
+	headerRows int
+}
+
+var (
+	organizerTemplate = &sheetTemplate{name: "organizer", header: map[int]string{10: "ИНН", 11: "Поставщик"}, headerRows: 1}
+	mtrTemplate       = &sheetTemplate{name: "mtr", header: map[int]string{6: "Наименование", 7: "Артикул", 8: "Идентификатор"}, headerRows: 1}
+	supplierTemplate  = &sheetTemplate{name: "supplier", header: map[int]string{0: "Код СКМТР", 1: "КОД КС НСИ", 2: "Код АМТО"}, headerRows: 2}
+	// uploadTemplates are the layouts accepted by the Directus upload flow.
+	uploadTemplates = []*sheetTemplate{organizerTemplate, mtrTemplate, supplierTemplate}
+)
+
+// score counts the template header cells found in the flattened column
+// names, a composite name matches by any of its parts.
+func (t *sheetTemplate) score(columns []string) int {
+	score := 0
+	for col, name := range t.header {
+		if col >= len(columns) {
+			continue
+		}
+		for _, part := range strings.Split(columns[col], headerPartSeparator) {
+			if strings.EqualFold(strings.TrimSpace(part), name) {
+				score++
+				break
+			}
+		}
+	}
+	return score
+}
+
+// selectSheet resolves the one sheet to read, the first of selectSheets.
+func selectSheet(excelFile *excelize.File, sel models.SheetSelector, fallback string, templates ...*sheetTemplate) (string, error) {
+	sheets, err := selectSheets(excelFile, sel, fallback, templates...)
+	if err != nil {
+		return "", err
+	}
+	return sheets[0], nil
+}
+
+// selectSheets resolves the sheets to import. An explicit name or index
+// must exist and is the only sheet. Otherwise every sheet whose header fits
+// one of the templates is taken, then the fallback sheet if the workbook
+// has it. Without templates the first sheet is the last resort, with them
+// the workbook is TEMPLATE_NOT_RECOGNIZED.
+func selectSheets(excelFile *excelize.File, sel models.SheetSelector, fallback string, templates ...*sheetTemplate) ([]string, error) {
+	sheets := excelFile.GetSheetList()
+	switch {
+	case sel.Name != "":
+		if excelFile.GetSheetIndex(sel.Name) < 0 {
+			log.Warnf("sheet %s not found", sel.Name)
+			return nil, apperrors.New(apperrors.SheetNotFound, fmt.Sprintf("в файле нет листа %q, есть: %s", sel.Name, strings.Join(sheets, ", ")))
+		}
+		return []string{sel.Name}, nil
+	case sel.Index != 0:
+		if sel.Index < 1 || sel.Index > len(sheets) {
+			log.Warnf("sheet index %d out of %d sheets", sel.Index, len(sheets))
+			return nil, apperrors.New(apperrors.SheetNotFound, fmt.Sprintf("в файле нет листа с номером %d, листов: %d", sel.Index, len(sheets)))
+		}
+		return []string{sheets[sel.Index-1]}, nil
+	}
+
+	var matched []string
+	if len(templates) > 0 {
+		for _, sheet := range sheets {
+			if detectLayout(excelFile, sheet, sheetTop(excelFile, sheet), templates, 0).template != nil {
+				matched = append(matched, sheet)
+				continue
+			}
+			log.Infof("sheet %s fits no template and is skipped", sheet)
+		}
+	}
+	if len(matched) > 0 {
+		return matched, nil
+	}
+	if fallback != "" && excelFile.GetSheetIndex(fallback) >= 0 {
+		return []string{fallback}, nil
+	}
+	if len(templates) > 0 {
+		log.Warnf("no sheet of %s fits a template", strings.Join(sheets, ", "))
+		return nil, apperrors.New(apperrors.TemplateNotRecognized, fmt.Sprintf("ни один лист не подходит под шаблон: %s", strings.Join(sheets, ", ")))
+	}
+	return sheets[:1], nil
+}
+
+// sheetTop reads only the rows the header may be in.
+func sheetTop(excelFile *excelize.File, sheet string) [][]string {
+	rows, err := excelFile.Rows(sheet)
+	if err != nil {
+		return nil
+	}
+	defer rows.Close()
+
+	var top [][]string
+	for len(top) < headerScanRows+maxHeaderRows && rows.Next() {
+		row, _ := rows.Columns()
+		top = append(top, row)
+	}
+	return top
+}
+
+// defaultSheetName matches names spreadsheet programs give new sheets, they
+// say nothing about the products on the sheet.
+var defaultSheetName = regexp.MustCompile(`^(?i)(лист|sheet|tdsheet|шаблон)\s*\d*$`)
+
+// newSheetReport starts the report of a sheet. A sheet named by the
+// supplier, say "Трубы" or "Кабель", is the category hint of its rows.
+func newSheetReport(fileName, sheet string, layout *sheetLayout) *models.SheetReport {
+	report := &models.SheetReport{FileName: fileName, Sheet: sheet}
+	if layout != nil {
+		report.HeaderRow, report.DataRow = layout.headerRow+1, layout.dataRow+1
+		if layout.template != nil {
+			report.Template = layout.template.name
+		}
+	}
+	if name := strings.TrimSpace(sheet); !defaultSheetName.MatchString(name) {
+		report.CategoryHint = name
+	}
+	return report
+}
+
+// reportSheetRow counts a data row of the sheet and reports its errors.
+func reportSheetRow(ctx context.Context, repo repository.ExcelRepository, sheet *models.SheetReport, uploadId, fileName string, row int, rowErrs []*models.UploadRowError) {
+	sheet.Rows++
+	if len(rowErrs) > 0 {
+		sheet.Errors++
+	}
+	for _, rowErr := range rowErrs {
+		rowErr.Sheet = sheet.Sheet
+	}
+	reportRowErrors(ctx, repo, uploadId, fileName, row, rowErrs)
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"excel-service/internal/models"
+	"excel-service/internal/repository"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+// standardReg finds standard references in a cell or a product name. Longer
+// kinds go first so that "ГОСТ Р ИСО" is not read as "ГОСТ". Spaces are
+// allowed around dashes only, "ГОСТ 8732-78. 10 шт" ends at the year.
+var standardReg = regexp.MustCompile(`(?i)(?:^|[^\pL\d])(ГОСТ\s*Р\s*ИСО|ГОСТ\s*ИСО|ГОСТ\s*Р|ГОСТ|ОСТ|ТУ|СТО|ISO|ИСО)[\s№-]*(\d+(?:[.:/]\d+|\s*[\-–—]\s*\d+)*)`)
+
+var standardNumberReplacer = strings.NewReplacer("–", "-", "—", "-", " ", "", "\u00a0", "")
+
+var standardKinds = map[string]string{
+	"ГОСТРИСО": "ГОСТ Р ИСО",
+	"ГОСТИСО":  "ГОСТ ИСО",
+	"ГОСТР":    "ГОСТ Р",
+	"ГОСТ":     "ГОСТ",
+	"ОСТ":      "ОСТ",
+	"ТУ":       "ТУ",
+	"СТО":      "СТО",
+	"ISO":      "ISO",
+	"ИСО":      "ISO",
+}
+
+// parseStandards returns every standard referenced in s, "гост8732–78"
+// and "ГОСТ 8732 - 78" both become "ГОСТ 8732-78".
+func parseStandards(s string) []*models.Standard {
+	var res []*models.Standard
+	seen := map[string]bool{}
+	for _, m := range standardReg.FindAllStringSubmatch(s, -1) {
+		kind := standardKinds[strings.ToUpper(strings.Join(strings.Fields(m[1]), ""))]
+		number := standardNumberReplacer.Replace(m[2])
+		code := kind + " " + number
+		if seen[code] {
+			continue
+		}
+		seen[code] = true
+		res = append(res, &models.Standard{Kind: kind, Number: number, Code: code})
+	}
+	return res
+}
+
+// canonicalStandard returns the canonical form of the first reference in s
+// or s itself when it does not look like a standard.
+func canonicalStandard(s string) string {
+	if standards := parseStandards(s); len(standards) > 0 {
+		return standards[0].Code
+	}
+	return strings.TrimSpace(s)
+}
+
+// collectStandards links the nomenclature to the standards from its
+// standard column and its name. GostTu keeps the canonical codes from the
+// column, unrecognized text is left as written.
+func collectStandards(n *models.Nomenclature, column, name string) {
+	fromColumn := parseStandards(column)
+	if len(fromColumn) > 0 {
+		codes := make([]string, 0, len(fromColumn))
+		for _, standard := range fromColumn {
+			codes = append(codes, standard.Code)
+		}
+		n.GostTu = strings.Join(codes, "; ")
+	}
+
+	n.Standards = fromColumn
+	for _, standard := range parseStandards(name) {
+		if !hasStandard(n.Standards, standard.Code) {
+			n.Standards = append(n.Standards, standard)
+		}
+	}
+	if n.GostTu == "" && len(n.Standards) > 0 {
+		n.GostTu = n.Standards[0].Code
+	}
+}
+
+func hasStandard(standards []*models.Standard, code string) bool {
+	for _, standard := range standards {
+		if standard.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// saveStandards is called after the nomenclature row is saved, the link
+// table references it.
+func saveStandards(ctx context.Context, repo repository.ExcelRepository, n *models.Nomenclature) error {
+	if len(n.Standards) == 0 {
+		return nil
+	}
+	return repo.SaveNomenclatureStandards(ctx, n.Id, n.Standards)
+}
+
+func (e ExcelServiceImpl) GetNomenclatureByStandard(ctx context.Context, standard string) ([]*models.StandardNomenclature, error) {
+	standards := parseStandards(standard)
+	if len(standards) == 0 {
+		log.Warnf("not a standard reference: %s", standard)
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "не распознан стандарт: "+standard)
+	}
+	return e.repo.SelectNomenclatureByStandard(ctx, standards[0].Code)
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"excel-service/internal/models"
+	"reflect"
+	"testing"
+)
+
+func TestParseStandards(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want []string
+	}{
+		{"canonical", "ГОСТ 8732-78", []string{"ГОСТ 8732-78"}},
+		{"lower case without space", "гост8732-78", []string{"ГОСТ 8732-78"}},
+		{"en dash", "ГОСТ 8732–78", []string{"ГОСТ 8732-78"}},
+		{"spaced dash", "ГОСТ 8732 - 78", []string{"ГОСТ 8732-78"}},
+		{"number sign", "ГОСТ № 8732-78", []string{"ГОСТ 8732-78"}},
+		{"ГОСТ Р with part", "ГОСТ Р 52857.1-2007", []string{"ГОСТ Р 52857.1-2007"}},
+		{"ГОСТ Р ИСО", "гост р исо 9001-2015", []string{"ГОСТ Р ИСО 9001-2015"}},
+		{"ГОСТ ИСО", "ГОСТ ИСО 9001-2011", []string{"ГОСТ ИСО 9001-2011"}},
+		{"ТУ", "ТУ 14-3-190-2004", []string{"ТУ 14-3-190-2004"}},
+		{"ОСТ", "ОСТ 26-291-94", []string{"ОСТ 26-291-94"}},
+		{"СТО", "СТО 00220256-005-2005", []string{"СТО 00220256-005-2005"}},
+		{"ISO and ИСО", "ISO 4014; ИСО 4014", []string{"ISO 4014"}},
+		{"ISO with part", "ISO 6892:1", []string{"ISO 6892:1"}},
+		{"sentence after the year", "ГОСТ 8732-78. 10 шт", []string{"ГОСТ 8732-78"}},
+		{"in a product name", "Труба 57х3,5 ГОСТ 8732-78 ст.20, ТУ 14-3-190-2004", []string{"ГОСТ 8732-78", "ТУ 14-3-190-2004"}},
+		{"part of a word", "КОСТ 123, ПОСТ 5", nil},
+		{"no number", "ГОСТ", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, standard := range parseStandards(tt.s) {
+				got = append(got, standard.Code)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseStandards(%q) = %q, want %q", tt.s, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCollectStandards(t *testing.T) {
+	n := &models.Nomenclature{}
+	collectStandards(n, "гост 8732–78", "Труба ГОСТ 8732-78, ТУ 14-3-190-2004")
+	if n.GostTu != "ГОСТ 8732-78" {
+		t.Errorf("GostTu = %q, want %q", n.GostTu, "ГОСТ 8732-78")
+	}
+	if len(n.Standards) != 2 || n.Standards[1].Kind != "ТУ" || n.Standards[1].Number != "14-3-190-2004" {
+		t.Errorf("Standards = %+v", n.Standards)
+	}
+
+	n = &models.Nomenclature{GostTu: "по чертежу"}
+	collectStandards(n, "по чертежу", "Фланец")
+	if n.GostTu != "по чертежу" || len(n.Standards) != 0 {
+		t.Errorf("unrecognized column: GostTu = %q, Standards = %+v", n.GostTu, n.Standards)
+	}
+}
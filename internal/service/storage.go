@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"excel-service/internal/configs"
+	"io"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// fileStorage keeps the files of uploads, the bucket Directus serves them
+// from.
+type fileStorage interface {
+	Put(ctx context.Context, name string, src io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, name string) (io.ReadCloser, error)
+}
+
+// minioStorage is the S3 bucket of the service config.
+type minioStorage struct {
+	cfg *configs.AwsConfig
+}
+
+func (s minioStorage) client() (*minio.Client, error) {
+	return minio.New(s.cfg.Host, &minio.Options{
+		Creds:  credentials.NewStaticV4(s.cfg.SecretKey, s.cfg.AccessKey, ""),
+		Secure: false,
+	})
+}
+
+func (s minioStorage) Put(ctx context.Context, name string, src io.Reader, size int64, contentType string) error {
+	client, err := s.client()
+	if err != nil {
+		return err
+	}
+	_, err = client.PutObject(ctx, s.cfg.Bucket, name, src, size, minio.PutObjectOptions{ContentType: contentType})
+	return err
+}
+
+func (s minioStorage) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	client, err := s.client()
+	if err != nil {
+		return nil, err
+	}
+	return client.GetObject(ctx, s.cfg.Bucket, name, minio.GetObjectOptions{})
+}
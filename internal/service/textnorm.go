@@ -0,0 +1,59 @@
+package service
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	textReplacer = strings.NewReplacer("ё", "е", "Ё", "е", "\u00a0", " ")
+	nonWordReg   = regexp.MustCompile(`[^\pL\d]+`)
+)
+
+var stopWords = map[string]bool{
+	"для": true, "и": true, "в": true, "с": true, "со": true, "на": true, "из": true,
+	"по": true, "от": true, "без": true, "под": true, "шт": true, "тип": true,
+}
+
+// normalizeText lowercases s, turns ё into е and leaves only letters and
+// digits separated by single spaces.
+func normalizeText(s string) string {
+	s = strings.ToLower(textReplacer.Replace(s))
+	return strings.TrimSpace(nonWordReg.ReplaceAllString(s, " "))
+}
+
+// tokenize splits a product name into normalized words without stop words
+// and one-letter words.
+func tokenize(s string) []string {
+	var tokens []string
+	for _, word := range strings.Fields(normalizeText(s)) {
+		if utf8.RuneCountInString(word) < 2 || stopWords[word] {
+			continue
+		}
+		tokens = append(tokens, word)
+	}
+	return tokens
+}
+
+// stem cuts Russian endings the cheap way: long words keep their first six
+// letters, short ones lose trailing vowels, so "трубы", "трубой" and
+// "труба" all become "труб".
+func stem(word string) string {
+	if utf8.RuneCountInString(word) > 6 {
+		return string([]rune(word)[:6])
+	}
+	if trimmed := strings.TrimRight(word, "аяоеиыуюйь"); trimmed != "" {
+		return trimmed
+	}
+	return word
+}
+
+func isNumber(word string) bool {
+	for _, r := range word {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return word != ""
+}
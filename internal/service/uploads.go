@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"excel-service/internal/apperrors"
+	"excel-service/internal/auth"
+	"excel-service/internal/models"
+	"excel-service/internal/repository"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+const (
+	uploadStatusProcessing = "processing"
+	uploadStatusRolledBack = "rolled_back"
+	uploadStatusProcessed  = "processed"
+	uploadStatusFailed     = "failed"
+)
+
+func (e ExcelServiceImpl) RollbackUpload(ctx context.Context, uploadId string) (*models.UploadRollback, error) {
+	if authErr := e.authorizeUpload(ctx, uploadId); authErr != nil {
+		return nil, authErr
+	}
+	tx, txErr := e.db.Begin(ctx)
+	if txErr != nil {
+		log.Errorf("failed to begin tx: %v", txErr)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, txErr)
+	}
+
+	// the lock keeps an import from starting until the rollback is done
+	status, statusErr := e.repo.LockUploadStatus(ctx, uploadId, tx)
+	if statusErr != nil {
+		return nil, statusErr
+	}
+	if status == uploadStatusProcessing {
+		if rbErr := tx.Rollback(ctx); rbErr != nil {
+			log.Errorf("failed to roll back tx in RollbackUpload: %v", rbErr)
+		}
+		log.Warnf("upload %s is still processing", uploadId)
+		return nil, echo.NewHTTPError(http.StatusConflict, "upload is still processing")
+	}
+
+	res, delErr := e.repo.DeleteUploadData(ctx, uploadId, tx)
+	if delErr != nil {
+		return nil, delErr
+	}
+
+	if statusErr := e.repo.SetUploadStatusTx(ctx, uploadId, uploadStatusRolledBack, tx); statusErr != nil {
+		if rbErr := tx.Rollback(ctx); rbErr != nil {
+			log.Errorf("failed to roll back tx in RollbackUpload: %v", rbErr)
+		}
+		return nil, statusErr
+	}
+
+	if cErr := tx.Commit(ctx); cErr != nil {
+		log.Errorf("failed to commit tx in RollbackUpload: %v", cErr)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, cErr)
+	}
+
+	log.Infof("upload %s rolled back: %+v", uploadId, res)
+	return res, nil
+}
+
+// newDirectUpload records an upload for a file posted straight to an import
+// endpoint, the rows it saves are tagged with it like those of a Directus
+// upload. The upload belongs to the company of the caller.
+func (e ExcelServiceImpl) newDirectUpload(ctx context.Context) (string, error) {
+	var companyId string
+	if p := auth.PrincipalFromContext(ctx); p != nil {
+		companyId = p.CompanyId
+	}
+	return e.repo.NewDirectUpload(ctx, companyId)
+}
+
+// finishDirectUpload sets the final status of a direct upload. A failed one
+// can still be rolled back, rows saved before the failure are tagged.
+func (e ExcelServiceImpl) finishDirectUpload(ctx context.Context, uploadId string, importErr error) {
+	status := uploadStatusProcessed
+	if importErr != nil {
+		status = uploadStatusFailed
+	}
+	if err := e.repo.SetUploadStatus(ctx, uploadId, status); err != nil {
+		log.Errorf("failed to set status of direct upload %s: %v", uploadId, err)
+	}
+	if importErr == nil {
+		matchUpload(ctx, e.repo, uploadId)
+	}
+}
+
+// matchUpload queues duplicates of the imported items and links them to MTR
+// references. Failures are logged, the items are saved either way.
+func matchUpload(ctx context.Context, repo repository.ExcelRepository, uploadId string) {
+	if _, dupErr := findUploadDuplicates(ctx, repo, uploadId); dupErr != nil {
+		log.Errorf("failed to find duplicates of upload %s: %v", uploadId, dupErr)
+	}
+	if _, linkErr := linkToMtr(ctx, repo, uploadId); linkErr != nil {
+		log.Errorf("failed to link upload %s to mtr: %v", uploadId, linkErr)
+	}
+}
+
+// reportRowErrors saves the problems of one row, row is the 0-based index
+// in the sheet and is stored the way Excel numbers rows. Errors without a
+// code are ROW_INVALID.
+func reportRowErrors(ctx context.Context, repo repository.ExcelRepository, uploadId, fileName string, row int, rowErrs []*models.UploadRowError) {
+	if len(rowErrs) == 0 {
+		return
+	}
+	for _, rowErr := range rowErrs {
+		rowErr.UploadId = uploadId
+		rowErr.FileName = fileName
+		rowErr.Row = row + 1
+		if rowErr.Code == "" {
+			rowErr.Code = string(apperrors.RowInvalid)
+		}
+	}
+	if err := repo.NewUploadRowErrors(ctx, rowErrs); err != nil {
+		log.Errorf("failed to report errors of row %d: %v", row+1, err)
+	}
+}
+
+// reportFileError records an error that rejected the whole file, such as a
+// broken limit, as row 0 of the upload report.
+func reportFileError(ctx context.Context, repo repository.ExcelRepository, uploadId, fileName string, err error) {
+	appErr := apperrors.From(err)
+	message := appErr.Message("ru")
+	if details, ok := appErr.Details.(string); ok {
+		message = details
+	}
+	reportRowErrors(ctx, repo, uploadId, fileName, -1, []*models.UploadRowError{{Field: "file", Code: string(appErr.Code), Message: message}})
+}
+
+func (e ExcelServiceImpl) GetUploadRowErrors(ctx context.Context, uploadId string) ([]*models.UploadRowError, error) {
+	if authErr := e.authorizeUpload(ctx, uploadId); authErr != nil {
+		return nil, authErr
+	}
+	return e.repo.SelectUploadRowErrors(ctx, uploadId)
+}
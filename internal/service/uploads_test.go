@@ -0,0 +1,591 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"excel-service/internal/auth"
+	"excel-service/internal/configs"
+	"excel-service/internal/models"
+	"excel-service/internal/repository"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+// memStorage keeps the files in memory.
+type memStorage map[string][]byte
+
+func (s memStorage) Put(ctx context.Context, name string, src io.Reader, size int64, contentType string) error {
+	b, err := ioutil.ReadAll(src)
+	if err != nil {
+		return err
+	}
+	s[name] = b
+	return nil
+}
+
+func (s memStorage) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(s[name])), nil
+}
+
+// memTx records how the transaction ended.
+type memTx struct {
+	pgx.Tx
+	committed, rolledBack bool
+}
+
+func (tx *memTx) Commit(ctx context.Context) error {
+	tx.committed = true
+	return nil
+}
+
+func (tx *memTx) Rollback(ctx context.Context) error {
+	tx.rolledBack = true
+	return nil
+}
+
+// memDB hands out memTx, the last one begun is tx.
+type memDB struct {
+	tx *memTx
+}
+
+func (d *memDB) Begin(ctx context.Context) (pgx.Tx, error) {
+	d.tx = &memTx{}
+	return d.tx, nil
+}
+
+// uploadRepo keeps the uploads and the nomenclature they save in memory.
+type uploadRepo struct {
+	repository.ExcelRepository
+	companies map[string]string
+	files     map[string][]*models.UploadsEntity
+	status    map[string]string
+	saved     []*models.Nomenclature
+	owners    []string
+	rowErrs   []*models.UploadRowError
+
+	mapping    *models.ColumnMapping
+	priceLists []string
+	versions   map[string][][]*models.PriceListItem
+	runs       []*models.HookRun
+
+	links      map[string][]string
+	candidates map[string][2]string
+	merges     []*nomenclatureMerge
+}
+
+// nomenclatureMerge is a row of nomenclature_merge.
+type nomenclatureMerge struct {
+	merged, master string
+	priceIds       []string
+}
+
+func newUploadRepo() *uploadRepo {
+	return &uploadRepo{
+		companies: map[string]string{"Ромашка": "company-1"},
+		files:     map[string][]*models.UploadsEntity{},
+		status:    map[string]string{},
+		versions:  map[string][][]*models.PriceListItem{},
+		links:     map[string][]string{},
+	}
+}
+
+func (r *uploadRepo) SelectCompanyIdByName(ctx context.Context, name string) (string, error) {
+	return r.companies[name], nil
+}
+
+func (r *uploadRepo) NewUploadCatalogue(ctx context.Context, fileNameDisc, fileNameDl, uploadedBy, companyName string, fileSize int64) (string, error) {
+	uploadId := uuid.New().String()
+	r.files[uploadId] = append(r.files[uploadId], &models.UploadsEntity{CompanyId: r.companies[companyName], FileId: fileNameDisc, UserId: uploadedBy})
+	r.status[uploadId] = "wait_for_processing"
+	return uploadId, nil
+}
+
+func (r *uploadRepo) GetFromUploadCatalogue(ctx context.Context, id string) ([]*models.UploadsEntity, error) {
+	return r.files[id], nil
+}
+
+func (r *uploadRepo) SetUploadStatus(ctx context.Context, uploadId, status string) error {
+	r.status[uploadId] = status
+	return nil
+}
+
+func (r *uploadRepo) LockUploadStatus(ctx context.Context, uploadId string, tx pgx.Tx) (string, error) {
+	return r.status[uploadId], nil
+}
+
+func (r *uploadRepo) SetUploadStatusTx(ctx context.Context, uploadId, status string, tx pgx.Tx) error {
+	r.status[uploadId] = status
+	return nil
+}
+
+func (r *uploadRepo) SelectUploadCompany(ctx context.Context, uploadId string) (string, error) {
+	return r.files[uploadId][0].CompanyId, nil
+}
+
+// MergeDuplicate moves the links of the merged item to the master and
+// records them.
+func (r *uploadRepo) MergeDuplicate(ctx context.Context, candidateId string, tx pgx.Tx) (*models.DuplicateMerge, error) {
+	pair := r.candidates[candidateId]
+	res := &models.DuplicateMerge{CandidateId: candidateId, MasterId: pair[0], MergedId: pair[1]}
+	moved := r.links[res.MergedId]
+	r.links[res.MasterId] = append(r.links[res.MasterId], moved...)
+	delete(r.links, res.MergedId)
+	r.merges = append(r.merges, &nomenclatureMerge{merged: res.MergedId, master: res.MasterId, priceIds: moved})
+	res.PriceLinks = int64(len(moved))
+	return res, nil
+}
+
+// DeleteUploadData undoes the merges of the items the upload saved and
+// drops them with their links.
+func (r *uploadRepo) DeleteUploadData(ctx context.Context, uploadId string, tx pgx.Tx) (*models.UploadRollback, error) {
+	res := &models.UploadRollback{UploadId: uploadId}
+	own := map[string]bool{}
+	for _, n := range r.saved {
+		own[n.Id] = n.UploadId == uploadId
+	}
+
+	var merges []*nomenclatureMerge
+	for _, m := range r.merges {
+		if !own[m.merged] && !own[m.master] {
+			merges = append(merges, m)
+			continue
+		}
+		for _, priceId := range m.priceIds {
+			for i, linked := range r.links[m.master] {
+				if linked == priceId {
+					r.links[m.master] = append(r.links[m.master][:i], r.links[m.master][i+1:]...)
+					r.links[m.merged] = append(r.links[m.merged], priceId)
+					break
+				}
+			}
+		}
+		res.Unmerged++
+	}
+	r.merges = merges
+
+	var kept []*models.Nomenclature
+	for _, n := range r.saved {
+		if own[n.Id] {
+			res.Nomenclature++
+			res.PriceLinks += int64(len(r.links[n.Id]))
+			delete(r.links, n.Id)
+			continue
+		}
+		kept = append(kept, n)
+	}
+	r.saved = kept
+	return res, nil
+}
+
+func (r *uploadRepo) DeleteUploadRowErrors(ctx context.Context, uploadId string) error {
+	r.rowErrs = nil
+	return nil
+}
+
+// StartHookRun claims every key, runs do not overlap in the tests.
+func (r *uploadRepo) StartHookRun(ctx context.Context, key, uploadId string, staleAfter time.Duration) (*models.HookRun, bool, error) {
+	return &models.HookRun{Key: key, UploadId: uploadId, Status: hookRunRunning}, true, nil
+}
+
+func (r *uploadRepo) SelectDoneHookRun(ctx context.Context, uploadId string) (*models.HookRun, error) {
+	for i := len(r.runs) - 1; i >= 0; i-- {
+		if r.runs[i].UploadId == uploadId && r.runs[i].Status == hookRunDone {
+			return r.runs[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *uploadRepo) FinishHookRun(ctx context.Context, run *models.HookRun) error {
+	r.runs = append(r.runs, run)
+	return nil
+}
+
+func (r *uploadRepo) SelectUploadColumnMapping(ctx context.Context, uploadId string) (*models.ColumnMapping, error) {
+	return r.mapping, nil
+}
+
+func (r *uploadRepo) SelectPriceListsByUploadId(ctx context.Context, uploadId string) ([]string, error) {
+	return r.priceLists, nil
+}
+
+func (r *uploadRepo) NewPriceListVersion(ctx context.Context, priceId, uploadId string, items []*models.PriceListItem) (*models.PriceListVersion, error) {
+	r.versions[priceId] = append(r.versions[priceId], items)
+	return &models.PriceListVersion{}, nil
+}
+
+func (r *uploadRepo) SelectCurrencyCodes(ctx context.Context) (map[string]bool, error) {
+	return map[string]bool{"RUB": true}, nil
+}
+
+func (r *uploadRepo) HasClassifierCodes(ctx context.Context, kind string) (bool, error) {
+	return false, nil
+}
+
+func (r *uploadRepo) SelectCategorizedNomenclature(ctx context.Context, unclassified string, limit int) ([]*models.CategorySample, error) {
+	return nil, nil
+}
+
+func (r *uploadRepo) SelectExtractionRules(ctx context.Context) ([]*models.ExtractionRule, error) {
+	return nil, nil
+}
+
+func (r *uploadRepo) SelectManufacturerAliases(ctx context.Context) (map[string]string, error) {
+	return nil, nil
+}
+
+func (r *uploadRepo) QueueUnresolvedManufacturers(ctx context.Context, uploadId string, queue []*models.UnresolvedManufacturer) error {
+	return nil
+}
+
+func (r *uploadRepo) SelectCountries(ctx context.Context) ([]*models.Country, error) {
+	return nil, nil
+}
+
+func (r *uploadRepo) SaveNomenclatureStandards(ctx context.Context, nomenclatureId string, standards []*models.Standard) error {
+	return nil
+}
+
+func (r *uploadRepo) SelectUploadMatchItems(ctx context.Context, uploadId string) ([]*models.MatchItem, error) {
+	return nil, nil
+}
+
+func (r *uploadRepo) SelectUnlinkedSupplierItems(ctx context.Context, uploadId string, limit, offset int) ([]*models.MatchItem, error) {
+	return nil, nil
+}
+
+func (r *uploadRepo) SaveNomenclature(ctx context.Context, n *models.Nomenclature, tx pgx.Tx, userId, companyId string) error {
+	r.saved = append(r.saved, n)
+	r.owners = append(r.owners, userId+"/"+companyId)
+	return nil
+}
+
+func (r *uploadRepo) NewUploadRowErrors(ctx context.Context, rowErrs []*models.UploadRowError) error {
+	r.rowErrs = append(r.rowErrs, rowErrs...)
+	return nil
+}
+
+// newSupplierFile is an upload file of the supplier template with the
+// rows under its two header rows.
+func newSupplierFile(t *testing.T, rows ...[]string) *multipart.FileHeader {
+	t.Helper()
+	header := [][]string{
+		{"Код СКМТР", "КОД КС НСИ", "Код АМТО", "ОКПД2", "ТН ВЭД", "Наименование", "", "Код производителя", "Марка", "ГОСТ/ТУ", "Дата изготовления", "Производитель", "Партия", "НДС", "", "Цена за единицу", "Ед. изм."},
+		{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10", "11", "12", "13", "14", "15", "16", "17"},
+	}
+	return newUploadFile(t, append(header, rows...))
+}
+
+// newUploadFile is an upload file of one sheet with the rows.
+func newUploadFile(t *testing.T, rows [][]string) *multipart.FileHeader {
+	t.Helper()
+	f := newSheet(t, rows)
+	var xlsx bytes.Buffer
+	if err := f.Write(&xlsx); err != nil {
+		t.Fatal(err)
+	}
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, err := w.CreateFormFile("file", "price.xlsx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(xlsx.Bytes())
+	w.Close()
+	form, err := multipart.NewReader(&body, w.Boundary()).ReadForm(1 << 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return form.File["file"][0]
+}
+
+func newUploadService(repo repository.ExcelRepository) ExcelServiceImpl {
+	cfg := &configs.Configs{Limits: &configs.LimitsConfig{
+		MaxFileSize:   1 << 20,
+		MaxUnzipSize:  1 << 24,
+		MaxUnzipRatio: 100,
+		MaxSheets:     5,
+		MaxRows:       100,
+		MaxColumns:    50,
+		MaxCellLength: 1000,
+	}}
+	return ExcelServiceImpl{repo: repo, cfg: cfg, storage: memStorage{}, db: &memDB{}}
+}
+
+func TestUploadExcelFileIsProcessed(t *testing.T) {
+	repo := newUploadRepo()
+	e := newUploadService(repo)
+	ctx := auth.WithPrincipal(context.Background(), &models.Principal{UserId: "user-1", CompanyId: "company-1"})
+
+	file := newSupplierFile(t, []string{"", "", "", "", "", "Труба 57х3,5", "", "A-1", "", "ГОСТ 8732-78", "", "", "", "20%", "", "120", "м"})
+	res, err := e.UploadExcelFile(ctx, file, "Ромашка")
+	if err != nil {
+		t.Fatalf("UploadExcelFile() error = %v", err)
+	}
+	if res.UploadId == "" {
+		t.Fatal("UploadExcelFile() returned no upload id")
+	}
+
+	if _, err := e.processUpload(ctx, &models.DirectusModel{Key: res.UploadId, Collection: "uploads"}); err != nil {
+		t.Fatalf("processUpload() error = %v", err)
+	}
+	if len(repo.saved) != 1 {
+		t.Fatalf("processUpload() saved %d items, want 1", len(repo.saved))
+	}
+	if repo.saved[0].Name != "Труба 57х3,5" || repo.owners[0] != "user-1/company-1" {
+		t.Errorf("saved item = %q of %q", repo.saved[0].Name, repo.owners[0])
+	}
+	if status := repo.status[res.UploadId]; status != "processed" {
+		t.Errorf("upload status = %q, want processed", status)
+	}
+}
+
+func TestUploadExcelFileNeedsUser(t *testing.T) {
+	e := newUploadService(newUploadRepo())
+	ctx := auth.WithPrincipal(context.Background(), &models.Principal{Service: "directus", Admin: true})
+
+	if _, err := e.UploadExcelFile(ctx, newSupplierFile(t), "Ромашка"); err == nil {
+		t.Error("UploadExcelFile() by a service token error = nil")
+	}
+}
+
+func TestProcessUploadPriceListVersion(t *testing.T) {
+	repo := newUploadRepo()
+	repo.priceLists = []string{"price-1", "price-2"}
+	e := newUploadService(repo)
+	ctx := auth.WithPrincipal(context.Background(), &models.Principal{UserId: "user-1", CompanyId: "company-1"})
+
+	pipe := []string{"", "", "", "", "", "Труба 57х3,5", "", "A-1", "", "", "", "", "", "20%", "", "120", "м"}
+	elbow := []string{"", "", "", "", "", "Отвод 90", "", "B-7", "", "", "", "", "", "20%", "", "45", "шт"}
+	res, err := e.UploadExcelFile(ctx, newSupplierFile(t, pipe), "Ромашка")
+	if err != nil {
+		t.Fatalf("UploadExcelFile() error = %v", err)
+	}
+	// a second file of the upload repeats the pipe
+	second, err := e.UploadExcelFile(ctx, newSupplierFile(t, elbow, pipe), "Ромашка")
+	if err != nil {
+		t.Fatalf("UploadExcelFile() error = %v", err)
+	}
+	repo.files[res.UploadId] = append(repo.files[res.UploadId], repo.files[second.UploadId]...)
+
+	if _, err := e.processUpload(ctx, &models.DirectusModel{Key: res.UploadId, Collection: "uploads"}); err != nil {
+		t.Fatalf("processUpload() error = %v", err)
+	}
+	if len(repo.saved) != 3 {
+		t.Errorf("processUpload() saved %d items, want every row", len(repo.saved))
+	}
+	if len(repo.rowErrs) != 1 || repo.rowErrs[0].Field != "item_key" {
+		t.Errorf("row errors = %+v, want the repeated key", repo.rowErrs)
+	}
+	for _, priceId := range repo.priceLists {
+		versions := repo.versions[priceId]
+		if len(versions) != 1 || len(versions[0]) != 2 {
+			t.Errorf("price list %s versions = %v, want one of 2 items", priceId, versions)
+		}
+	}
+}
+
+func TestRunUploadFailureMarksUpload(t *testing.T) {
+	repo := newUploadRepo()
+	e := newUploadService(repo)
+	ctx := auth.WithPrincipal(context.Background(), &models.Principal{UserId: "user-1", CompanyId: "company-1"})
+
+	res, err := e.UploadExcelFile(ctx, newSupplierFile(t), "Ромашка")
+	if err != nil {
+		t.Fatalf("UploadExcelFile() error = %v", err)
+	}
+	repo.files[res.UploadId][0].UserId = ""
+
+	run := &models.HookRun{Key: res.UploadId, UploadId: res.UploadId, Status: hookRunRunning}
+	if _, err := e.runUpload(ctx, run, &models.DirectusModel{Key: res.UploadId, Collection: "uploads"}); err == nil {
+		t.Fatal("runUpload() of a file without uploader error = nil")
+	}
+	if status := repo.status[res.UploadId]; status != uploadStatusFailed {
+		t.Errorf("upload status = %q, want %q", status, uploadStatusFailed)
+	}
+	if len(repo.runs) != 1 || repo.runs[0].Status != hookRunFailed {
+		t.Errorf("hook runs = %+v, want one failed", repo.runs)
+	}
+
+	rollback, err := e.RollbackUpload(ctx, res.UploadId)
+	if err != nil {
+		t.Fatalf("RollbackUpload() of a failed upload error = %v", err)
+	}
+	if rollback.UploadId != res.UploadId || repo.status[res.UploadId] != uploadStatusRolledBack {
+		t.Errorf("RollbackUpload() = %+v, status %q", rollback, repo.status[res.UploadId])
+	}
+}
+
+func TestRollbackUploadWhileProcessing(t *testing.T) {
+	repo := newUploadRepo()
+	e := newUploadService(repo)
+	ctx := auth.WithPrincipal(context.Background(), &models.Principal{UserId: "user-1", CompanyId: "company-1"})
+
+	res, err := e.UploadExcelFile(ctx, newSupplierFile(t), "Ромашка")
+	if err != nil {
+		t.Fatalf("UploadExcelFile() error = %v", err)
+	}
+	repo.status[res.UploadId] = uploadStatusProcessing
+
+	if _, err := e.RollbackUpload(ctx, res.UploadId); err == nil {
+		t.Fatal("RollbackUpload() while processing error = nil")
+	}
+	if tx := e.db.(*memDB).tx; tx == nil || !tx.rolledBack || tx.committed {
+		t.Errorf("RollbackUpload() left the status lock tx %+v", tx)
+	}
+	if status := repo.status[res.UploadId]; status != uploadStatusProcessing {
+		t.Errorf("upload status = %q, want it untouched", status)
+	}
+}
+
+func TestRollbackUploadUndoesMerges(t *testing.T) {
+	tests := []struct {
+		name     string
+		rollback string
+		want     map[string][]string
+	}{
+		{
+			name:     "upload of the merged item",
+			rollback: "upload-new",
+			want:     map[string][]string{"master": {"price-1"}},
+		},
+		{
+			name:     "upload of the master",
+			rollback: "upload-catalogue",
+			want:     map[string][]string{"merged": {"price-2"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newUploadRepo()
+			repo.files["upload-catalogue"] = []*models.UploadsEntity{{CompanyId: "company-1"}}
+			repo.files["upload-new"] = []*models.UploadsEntity{{CompanyId: "company-1"}}
+			repo.saved = []*models.Nomenclature{
+				{Id: "master", UploadId: "upload-catalogue"},
+				{Id: "merged", UploadId: "upload-new"},
+			}
+			repo.links = map[string][]string{"master": {"price-1"}, "merged": {"price-2"}}
+			repo.candidates = map[string][2]string{"candidate-1": {"master", "merged"}}
+			e := newUploadService(repo)
+			ctx := auth.WithPrincipal(context.Background(), &models.Principal{UserId: "user-1", CompanyId: "company-1"})
+
+			merge, err := e.MergeDuplicate(ctx, "candidate-1")
+			if err != nil {
+				t.Fatalf("MergeDuplicate() error = %v", err)
+			}
+			if merge.PriceLinks != 1 || len(repo.links["master"]) != 2 {
+				t.Fatalf("MergeDuplicate() moved %d links, master has %v", merge.PriceLinks, repo.links["master"])
+			}
+
+			res, err := e.RollbackUpload(ctx, tt.rollback)
+			if err != nil {
+				t.Fatalf("RollbackUpload() error = %v", err)
+			}
+			if res.Unmerged != 1 || res.Nomenclature != 1 || res.PriceLinks != 1 {
+				t.Errorf("RollbackUpload() = %+v", res)
+			}
+			if !reflect.DeepEqual(repo.links, tt.want) {
+				t.Errorf("price links = %v, want %v", repo.links, tt.want)
+			}
+			if len(repo.merges) != 0 {
+				t.Errorf("merges left: %+v", repo.merges)
+			}
+		})
+	}
+}
+
+func TestProcessUploadReportsVat(t *testing.T) {
+	repo := newUploadRepo()
+	e := newUploadService(repo)
+	ctx := auth.WithPrincipal(context.Background(), &models.Principal{UserId: "user-1", CompanyId: "company-1"})
+
+	file := newSupplierFile(t, []string{"", "", "", "", "", "Труба 57х3,5", "", "A-1", "", "", "", "", "", "15%", "", "120", "м"})
+	res, err := e.UploadExcelFile(ctx, file, "Ромашка")
+	if err != nil {
+		t.Fatalf("UploadExcelFile() error = %v", err)
+	}
+	if _, err := e.processUpload(ctx, &models.DirectusModel{Key: res.UploadId, Collection: "uploads"}); err != nil {
+		t.Fatalf("processUpload() error = %v", err)
+	}
+	if len(repo.saved) != 1 {
+		t.Errorf("processUpload() saved %d items, want the row without VAT", len(repo.saved))
+	}
+	if len(repo.rowErrs) != 1 || repo.rowErrs[0].Field != mappingVat || repo.rowErrs[0].Value != "15%" {
+		t.Errorf("row errors = %+v, want the VAT", repo.rowErrs)
+	}
+}
+
+func TestSaveNomenclatureFromDirectusOnce(t *testing.T) {
+	repo := newUploadRepo()
+	e := newUploadService(repo)
+	ctx := auth.WithPrincipal(context.Background(), &models.Principal{UserId: "user-1", CompanyId: "company-1"})
+
+	file := newSupplierFile(t, []string{"", "", "", "", "", "Труба 57х3,5", "", "A-1", "", "", "", "", "", "20%", "", "120", "м"})
+	upload, err := e.UploadExcelFile(ctx, file, "Ромашка")
+	if err != nil {
+		t.Fatalf("UploadExcelFile() error = %v", err)
+	}
+	req := &models.DirectusModel{Key: upload.UploadId, Collection: "uploads"}
+	first, err := e.SaveNomenclatureFromDirectus(ctx, req, "delivery-1")
+	if err != nil {
+		t.Fatalf("SaveNomenclatureFromDirectus() error = %v", err)
+	}
+
+	again, err := e.SaveNomenclatureFromDirectus(ctx, req, "delivery-2")
+	if err != nil {
+		t.Fatalf("SaveNomenclatureFromDirectus() under a new key error = %v", err)
+	}
+	if again != first {
+		t.Errorf("SaveNomenclatureFromDirectus() under a new key = %+v, want the first result", again)
+	}
+	if len(repo.saved) != 1 || len(repo.runs) != 1 {
+		t.Errorf("upload imported again: %d items, %d runs", len(repo.saved), len(repo.runs))
+	}
+}
+
+func TestProcessUploadMappedRepeatedKey(t *testing.T) {
+	repo := newUploadRepo()
+	repo.priceLists = []string{"price-1"}
+	e := newUploadService(repo)
+	ctx := auth.WithPrincipal(context.Background(), &models.Principal{UserId: "user-1", CompanyId: "company-1"})
+
+	file := newUploadFile(t, [][]string{
+		{"Товар", "Артикул", "Стоимость"},
+		{"Труба 57х3,5", "A-1", "120"},
+		{"Труба 57х3,5 ГОСТ", "A-1", "125"},
+		{"Отвод 90", "B-7", "45"},
+	})
+	res, err := e.UploadExcelFile(ctx, file, "Ромашка")
+	if err != nil {
+		t.Fatalf("UploadExcelFile() error = %v", err)
+	}
+	repo.mapping = &models.ColumnMapping{
+		UploadId: res.UploadId,
+		FileName: repo.files[res.UploadId][0].FileId,
+		Sheet:    "Sheet1",
+		DataRow:  2,
+		Columns:  map[string]int{mappingName: 0, mappingVendorCode: 1, mappingPrice: 2},
+	}
+
+	if _, err := e.processUpload(ctx, &models.DirectusModel{Key: res.UploadId, Collection: "uploads"}); err != nil {
+		t.Fatalf("processUpload() error = %v", err)
+	}
+	if len(repo.saved) != 3 {
+		t.Errorf("processUpload() saved %d items, want every row", len(repo.saved))
+	}
+	if len(repo.rowErrs) != 1 || repo.rowErrs[0].Field != "item_key" {
+		t.Errorf("row errors = %+v, want the repeated key", repo.rowErrs)
+	}
+	versions := repo.versions["price-1"]
+	if len(versions) != 1 || len(versions[0]) != 2 || versions[0][0].PricePerUnit != 120 {
+		t.Errorf("price list versions = %v, want one with the first pipe and the elbow", versions)
+	}
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"excel-service/internal/models"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type vatInfo struct {
+	isTax bool
+	// rateKnown is false for "облагается" without a percentage.
+	rateKnown bool
+	rate      float64
+	// included is nil when the notation says nothing about the price.
+	included *bool
+}
+
+var (
+	vatRateReg     = regexp.MustCompile(`(\d+(?:[.,]\d+)?)\s*(?:%|/\s*1\d\d)?`)
+	vatExemptReg   = regexp.MustCompile(`без\s+ндс|ндс\s+не\s+облага|^не\s+облага|освобожд|не\s+предусмотрен`)
+	vatExcludedReg = regexp.MustCompile(`без\s+уч[её]та\s+ндс|ндс\s+сверху|плюс\s+ндс|\+\s*ндс`)
+	vatIncludedReg = regexp.MustCompile(`в\s*т\.?\s*ч\.?|в\s+том\s+числе|включ|\d\s*/\s*1\d\d`)
+)
+
+var vatRates = map[float64]bool{0: true, 10: true, 18: true, 20: true}
+
+// parseVat understands the usual Russian notations: "20%", "10", "0%",
+// "без НДС", "НДС не облагается", "облагается", "в т.ч. НДС 20%",
+// "20/120", "НДС сверху".
+func parseVat(s string) (*vatInfo, error) {
+	s = strings.ToLower(strings.Join(strings.Fields(s), " "))
+	if s == "" {
+		return nil, nil
+	}
+
+	vat := &vatInfo{isTax: true}
+	switch {
+	case vatExcludedReg.MatchString(s):
+		included := false
+		vat.included = &included
+	case vatExemptReg.MatchString(s):
+		return &vatInfo{rateKnown: true}, nil
+	case vatIncludedReg.MatchString(s):
+		included := true
+		vat.included = &included
+	}
+
+	if m := vatRateReg.FindStringSubmatch(s); m != nil {
+		rate, err := parseDecimal(m[1])
+		if err != nil {
+			return nil, err
+		}
+		if rate > 0 && rate < 1 {
+			rate *= 100
+		}
+		if !vatRates[rate] {
+			return nil, fmt.Errorf("unknown VAT rate %q", s)
+		}
+		vat.rate = rate
+		vat.rateKnown = true
+		return vat, nil
+	}
+
+	if strings.Contains(s, "облага") || strings.Contains(s, "ндс") {
+		return vat, nil
+	}
+	return nil, fmt.Errorf("unknown VAT notation %q", s)
+}
+
+// detectPriceIncludesVat reads "Цена с НДС" / "Цена без НДС" from the header
+// rows. Prices include VAT unless the header says otherwise.
+func detectPriceIncludesVat(header [][]string) bool {
+	for _, row := range header {
+		for _, cell := range row {
+			cell = strings.ToLower(cell)
+			if !strings.Contains(cell, "цена") || !strings.Contains(cell, "ндс") {
+				continue
+			}
+			return !vatExemptReg.MatchString(cell) && !vatExcludedReg.MatchString(cell)
+		}
+	}
+	return true
+}
+
+// applyVat fills the tax fields and splits PricePerUnit into net, VAT and
+// gross amounts.
+func applyVat(n *models.Nomenclature, vat *vatInfo, includedByDefault bool) {
+	n.PriceIncludesVat = includedByDefault
+	if vat != nil {
+		n.IsTax = vat.isTax
+		n.TaxPercentage = float32(vat.rate)
+		if vat.included != nil {
+			n.PriceIncludesVat = *vat.included
+		}
+	}
+
+	price := float64(n.PricePerUnit)
+	if vat == nil || !vat.isTax || !vat.rateKnown {
+		n.PriceNet, n.VatAmount, n.PriceGross = float32(price), 0, float32(price)
+		return
+	}
+
+	var net, gross float64
+	if n.PriceIncludesVat {
+		gross = roundMoney(price)
+		net = roundMoney(gross * 100 / (100 + vat.rate))
+	} else {
+		net = roundMoney(price)
+		gross = roundMoney(net * (100 + vat.rate) / 100)
+	}
+	n.PriceNet, n.VatAmount, n.PriceGross = float32(net), float32(roundMoney(gross-net)), float32(gross)
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"excel-service/internal/models"
+	"testing"
+)
+
+func TestParseVat(t *testing.T) {
+	yes, no := true, false
+	tests := []struct {
+		s       string
+		want    *vatInfo
+		wantErr bool
+	}{
+		{s: "", want: nil},
+		{s: "20%", want: &vatInfo{isTax: true, rateKnown: true, rate: 20}},
+		{s: "10", want: &vatInfo{isTax: true, rateKnown: true, rate: 10}},
+		{s: "0%", want: &vatInfo{isTax: true, rateKnown: true}},
+		{s: "0,2", want: &vatInfo{isTax: true, rateKnown: true, rate: 20}},
+		{s: "без НДС", want: &vatInfo{rateKnown: true}},
+		{s: "НДС не облагается", want: &vatInfo{rateKnown: true}},
+		{s: "Освобождено", want: &vatInfo{rateKnown: true}},
+		{s: "облагается", want: &vatInfo{isTax: true}},
+		{s: "в т.ч. НДС 20%", want: &vatInfo{isTax: true, rateKnown: true, rate: 20, included: &yes}},
+		{s: "20/120", want: &vatInfo{isTax: true, rateKnown: true, rate: 20, included: &yes}},
+		{s: "НДС сверху", want: &vatInfo{isTax: true, included: &no}},
+		{s: "без учёта НДС 20%", want: &vatInfo{isTax: true, rateKnown: true, rate: 20, included: &no}},
+		{s: "15%", wantErr: true},
+		{s: "по договору", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, err := parseVat(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseVat(%q) error = %v, wantErr %v", tt.s, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if (got == nil) != (tt.want == nil) {
+				t.Fatalf("parseVat(%q) = %+v, want %+v", tt.s, got, tt.want)
+			}
+			if got == nil {
+				return
+			}
+			if got.isTax != tt.want.isTax || got.rateKnown != tt.want.rateKnown || got.rate != tt.want.rate ||
+				(got.included == nil) != (tt.want.included == nil) || (got.included != nil && *got.included != *tt.want.included) {
+				t.Errorf("parseVat(%q) = %+v, want %+v", tt.s, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyVat(t *testing.T) {
+	tests := []struct {
+		name              string
+		price             float32
+		vat               string
+		includedByDefault bool
+		net, amount       float32
+		gross             float32
+		included          bool
+	}{
+		{"included by default", 120, "20%", true, 100, 20, 120, true},
+		{"excluded by default", 100, "20%", false, 100, 20, 120, false},
+		{"notation overrides the header", 100, "НДС сверху 20%", true, 100, 20, 120, false},
+		{"rounded to kopecks", 99.99, "20%", true, 83.33, 16.66, 99.99, true},
+		{"exempt", 100, "без НДС", true, 100, 0, 100, true},
+		{"rate unknown", 100, "облагается", true, 100, 0, 100, true},
+		{"no notation", 100, "", false, 100, 0, 100, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vat, err := parseVat(tt.vat)
+			if err != nil {
+				t.Fatal(err)
+			}
+			n := &models.Nomenclature{PricePerUnit: tt.price}
+			applyVat(n, vat, tt.includedByDefault)
+			if n.PriceNet != tt.net || n.VatAmount != tt.amount || n.PriceGross != tt.gross || n.PriceIncludesVat != tt.included {
+				t.Errorf("applyVat() = net %v, vat %v, gross %v, included %v", n.PriceNet, n.VatAmount, n.PriceGross, n.PriceIncludesVat)
+			}
+		})
+	}
+}
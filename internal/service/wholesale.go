@@ -0,0 +1,135 @@
+package service
+
+import (
+	"excel-service/internal/models"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	tierNumberReg    = regexp.MustCompile(`\d+(?:[.,]\d+)?`)
+	tierSeparatorReg = regexp.MustCompile(`[;\n]+`)
+	tierUpToReg      = regexp.MustCompile(`^(до|не более|<)`)
+	tierBareReg      = regexp.MustCompile(`^\d+(?:\s+\d+)+$`)
+)
+
+var tierReplacer = strings.NewReplacer(
+	"≥", ">=",
+	"≤", "<=",
+	"–", "-",
+	"—", "-",
+	"\u00a0", " ",
+)
+
+// parseQuantityRange understands "от 10 до 100", "10-100", "10 и 100",
+// "≥500", "от 500", "500+", "до 100" and "<=100". A nil upper bound means the
+// tier has no upper limit. Both bounds are inclusive. Bare numbers like
+// "10 100" are refused, they read both as a range and as 10100.
+func parseQuantityRange(s string) (float64, *float64, error) {
+	s = strings.ToLower(strings.TrimSpace(tierReplacer.Replace(s)))
+	if tierBareReg.MatchString(s) {
+		return 0, nil, fmt.Errorf("ambiguous quantity range %q", s)
+	}
+	s = joinDigitGroups(s)
+
+	nums := tierNumberReg.FindAllString(s, -1)
+	switch len(nums) {
+	case 1:
+		n, err := parseDecimal(nums[0])
+		if err != nil {
+			return 0, nil, err
+		}
+		if tierUpToReg.MatchString(s) {
+			return 0, &n, nil
+		}
+		return n, nil, nil
+	case 2:
+		from, fromErr := parseDecimal(nums[0])
+		if fromErr != nil {
+			return 0, nil, fromErr
+		}
+		to, toErr := parseDecimal(nums[1])
+		if toErr != nil {
+			return 0, nil, toErr
+		}
+		if to < from {
+			return 0, nil, fmt.Errorf("quantity range %q ends before it starts", s)
+		}
+		return from, &to, nil
+	}
+	return 0, nil, fmt.Errorf("unknown quantity range %q", s)
+}
+
+// parsePriceTiers pairs the wholesale prices with the quantity ranges. Several
+// tiers are written one per line or separated by ";" in both cells.
+func parsePriceTiers(prices, ranges string) ([]*models.PriceTier, error) {
+	priceParts := splitTiers(prices)
+	rangeParts := splitTiers(ranges)
+	if len(priceParts) == 0 && len(rangeParts) == 0 {
+		return nil, nil
+	}
+	if len(priceParts) != len(rangeParts) {
+		return nil, fmt.Errorf("%d wholesale prices for %d quantity ranges", len(priceParts), len(rangeParts))
+	}
+
+	tiers := make([]*models.PriceTier, 0, len(priceParts))
+	for i := range priceParts {
+		price, priceErr := parseDecimal(priceParts[i])
+		if priceErr != nil {
+			return nil, fmt.Errorf("wrong wholesale price %q: %v", priceParts[i], priceErr)
+		}
+		from, to, rangeErr := parseQuantityRange(rangeParts[i])
+		if rangeErr != nil {
+			return nil, rangeErr
+		}
+		tiers = append(tiers, &models.PriceTier{QuantityFrom: from, QuantityTo: to, Price: price})
+	}
+
+	sort.Slice(tiers, func(i, j int) bool { return tiers[i].QuantityFrom < tiers[j].QuantityFrom })
+	return tiers, nil
+}
+
+func splitTiers(s string) []string {
+	var parts []string
+	for _, part := range tierSeparatorReg.Split(s, -1) {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+// effectivePrice returns the price of the most specific tier containing the
+// quantity, or the base price when no tier applies.
+func effectivePrice(base float64, tiers []*models.PriceTier, quantity float64) (float64, *models.PriceTier) {
+	var best *models.PriceTier
+	for _, tier := range tiers {
+		if quantity < tier.QuantityFrom || (tier.QuantityTo != nil && quantity > *tier.QuantityTo) {
+			continue
+		}
+		if best == nil || tier.QuantityFrom > best.QuantityFrom {
+			best = tier
+		}
+	}
+	if best == nil {
+		return base, nil
+	}
+	return best.Price, best
+}
+
+// legacyWholesaleItems keeps the wholesale_items column filled for the
+// consumers that still read it.
+func legacyWholesaleItems(price string, tiers []*models.PriceTier) *models.WholesaleItems {
+	items := &models.WholesaleItems{WholesalePricePerUnit: price}
+	if len(tiers) == 0 {
+		return items
+	}
+	items.WholesaleOrderFrom = strconv.FormatFloat(tiers[0].QuantityFrom, 'f', -1, 64)
+	if tiers[0].QuantityTo != nil {
+		items.WholesaleOrderTo = strconv.FormatFloat(*tiers[0].QuantityTo, 'f', -1, 64)
+	}
+	return items
+}
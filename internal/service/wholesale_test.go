@@ -0,0 +1,115 @@
+package service
+
+import (
+	"testing"
+)
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    float64
+		wantErr bool
+	}{
+		{s: "1500.75", want: 1500.75},
+		{s: "12,5", want: 12.5},
+		{s: " 1 500,75 ", want: 1500.75},
+		{s: "1 500", want: 1500},
+		{s: "12 345 678", want: 12345678},
+		{s: "10 100", want: 10100},
+		{s: "10 1000", wantErr: true},
+		{s: "1 50", wantErr: true},
+		{s: "abc", wantErr: true},
+		{s: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, err := parseDecimal(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseDecimal(%q) error = %v, wantErr %v", tt.s, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("parseDecimal(%q) = %v, want %v", tt.s, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseQuantityRange(t *testing.T) {
+	bound := func(f float64) *float64 { return &f }
+	tests := []struct {
+		s       string
+		from    float64
+		to      *float64
+		wantErr bool
+	}{
+		{s: "от 10 до 100", from: 10, to: bound(100)},
+		{s: "10-100", from: 10, to: bound(100)},
+		{s: "10 – 100", from: 10, to: bound(100)},
+		{s: "10 и 100", from: 10, to: bound(100)},
+		{s: "от 1 000 до 5 000", from: 1000, to: bound(5000)},
+		{s: "≥500", from: 500},
+		{s: "от 500", from: 500},
+		{s: "500+", from: 500},
+		{s: "от 1 500", from: 1500},
+		{s: "до 100", to: bound(100)},
+		{s: "<=100", to: bound(100)},
+		{s: "не более 2,5", to: bound(2.5)},
+		{s: "10 100", wantErr: true},
+		{s: "100-10", wantErr: true},
+		{s: "много", wantErr: true},
+		{s: "1-2-3", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			from, to, err := parseQuantityRange(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseQuantityRange(%q) error = %v, wantErr %v", tt.s, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if from != tt.from || (to == nil) != (tt.to == nil) || (to != nil && *to != *tt.to) {
+				t.Errorf("parseQuantityRange(%q) = %v, %v, want %v, %v", tt.s, from, to, tt.from, tt.to)
+			}
+		})
+	}
+}
+
+func TestParsePriceTiers(t *testing.T) {
+	tests := []struct {
+		name    string
+		prices  string
+		ranges  string
+		want    []float64
+		wantErr bool
+	}{
+		{name: "empty", want: nil},
+		{name: "one tier", prices: "95,50", ranges: "от 10", want: []float64{10, 95.5}},
+		{name: "sorted by quantity", prices: "80\n90", ranges: "от 100\n10-99", want: []float64{10, 90, 100, 80}},
+		{name: "semicolons", prices: "1 200; 1 100", ranges: "10-99; от 100", want: []float64{10, 1200, 100, 1100}},
+		{name: "count mismatch", prices: "90;80", ranges: "от 10", wantErr: true},
+		{name: "bad price", prices: "дешево", ranges: "от 10", wantErr: true},
+		{name: "bad range", prices: "90", ranges: "10 100", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tiers, err := parsePriceTiers(tt.prices, tt.ranges)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePriceTiers() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var got []float64
+			for _, tier := range tiers {
+				got = append(got, tier.QuantityFrom, tier.Price)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("parsePriceTiers() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("parsePriceTiers() = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"excel-service/internal/apperrors"
+	"excel-service/internal/configs"
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	"github.com/labstack/gommon/log"
+	"github.com/xuri/excelize/v2"
+)
+
+// openWorkbook reads a spreadsheet within the upload limits. Files over the
+// size limit get FILE_TOO_LARGE, archives that unpack to too much or with a
+// suspicious compression ratio and workbooks with too many sheets get
+// LIMIT_EXCEEDED.
+func openWorkbook(src io.Reader, limits *configs.LimitsConfig) (*excelize.File, error) {
+	data, err := io.ReadAll(io.LimitReader(src, limits.MaxFileSize+1))
+	if err != nil {
+		log.Errorf("failed to read file: %v", err)
+		return nil, apperrors.New(apperrors.FileUnreadable, "не удалось прочитать файл")
+	}
+	if int64(len(data)) > limits.MaxFileSize {
+		log.Warnf("file is larger than %d bytes", limits.MaxFileSize)
+		return nil, apperrors.New(apperrors.FileTooLarge, fmt.Sprintf("файл больше %d байт", limits.MaxFileSize))
+	}
+	if archiveErr := checkArchive(data, limits); archiveErr != nil {
+		return nil, archiveErr
+	}
+
+	excelFile, fileErr := excelize.OpenReader(bytes.NewReader(data), excelize.Options{UnzipSizeLimit: limits.MaxUnzipSize})
+	if fileErr != nil {
+		log.Errorf("failed to open reader: %v", fileErr)
+		return nil, apperrors.New(apperrors.FileUnreadable, fileErr.Error())
+	}
+	if sheets := excelFile.SheetCount; sheets > limits.MaxSheets {
+		log.Warnf("workbook has %d sheets", sheets)
+		return nil, apperrors.New(apperrors.LimitExceeded, fmt.Sprintf("в файле %d листов, допустимо не больше %d", sheets, limits.MaxSheets))
+	}
+	return excelFile, nil
+}
+
+// checkArchive guards against zip bombs: xlsx and ods files are zip
+// archives and their declared unpacked size is checked before anything is
+// unpacked. Files that are not zip archives are left to the reader.
+func checkArchive(data []byte, limits *configs.LimitsConfig) error {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil
+	}
+
+	var unpacked uint64
+	for _, f := range archive.File {
+		unpacked += f.UncompressedSize64
+	}
+	if unpacked > uint64(limits.MaxUnzipSize) {
+		log.Warnf("archive unpacks to %d bytes", unpacked)
+		return apperrors.New(apperrors.LimitExceeded, fmt.Sprintf("файл распаковывается больше чем в %d байт", limits.MaxUnzipSize))
+	}
+	if ratio := float64(unpacked) / float64(len(data)); ratio > limits.MaxUnzipRatio {
+		log.Warnf("archive compression ratio is %.0f", ratio)
+		return apperrors.New(apperrors.LimitExceeded, fmt.Sprintf("степень сжатия файла %.0f выше допустимой %.0f", ratio, limits.MaxUnzipRatio))
+	}
+	return nil
+}
+
+// readRows streams the sheet the way GetRows does, trailing empty rows are
+// dropped, and stops with LIMIT_EXCEEDED at the first row, column or cell
+// over the limits.
+func readRows(excelFile *excelize.File, sheet string, limits *configs.LimitsConfig) ([][]string, error) {
+	if excelFile.GetSheetIndex(sheet) < 0 {
+		log.Warnf("sheet %s not found", sheet)
+		return nil, apperrors.New(apperrors.SheetNotFound, fmt.Sprintf("в файле нет листа %q", sheet))
+	}
+	rows, err := excelFile.Rows(sheet)
+	if err != nil {
+		log.Errorf("failed to read sheet: %v", err)
+		return nil, apperrors.New(apperrors.FileUnreadable, err.Error())
+	}
+	defer rows.Close()
+
+	results, cur, last := make([][]string, 0, 64), 0, 0
+	for rows.Next() {
+		cur++
+		row, colErr := rows.Columns()
+		if colErr != nil {
+			log.Errorf("failed to read row %d: %v", cur, colErr)
+			return nil, apperrors.New(apperrors.FileUnreadable, colErr.Error())
+		}
+		if len(row) == 0 {
+			results = append(results, row)
+			continue
+		}
+
+		if cur > limits.MaxRows {
+			log.Warnf("sheet %s has more than %d rows", sheet, limits.MaxRows)
+			return nil, apperrors.New(apperrors.LimitExceeded, fmt.Sprintf("на листе больше %d строк", limits.MaxRows))
+		}
+		if len(row) > limits.MaxColumns {
+			log.Warnf("row %d has %d columns", cur, len(row))
+			return nil, apperrors.New(apperrors.LimitExceeded, fmt.Sprintf("в строке %d больше %d столбцов", cur, limits.MaxColumns))
+		}
+		for i, cell := range row {
+			if utf8.RuneCountInString(cell) > limits.MaxCellLength {
+				name, _ := excelize.CoordinatesToCellName(i+1, cur)
+				log.Warnf("cell %s is longer than %d characters", name, limits.MaxCellLength)
+				return nil, apperrors.New(apperrors.LimitExceeded, fmt.Sprintf("в ячейке %s больше %d символов", name, limits.MaxCellLength))
+			}
+		}
+		results = append(results, row)
+		last = cur
+	}
+	return results[:last], nil
+}
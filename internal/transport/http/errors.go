@@ -0,0 +1,51 @@
+package http
+
+import (
+	"excel-service/internal/apperrors"
+	"excel-service/internal/models"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"github.com/labstack/gommon/random"
+)
+
+// requestID reuses the caller's X-Request-ID or makes a new one and echoes
+// it in the response, error bodies carry it too.
+func requestID(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		id := c.Request().Header.Get(echo.HeaderXRequestID)
+		if id == "" || len(id) > 64 {
+			id = random.String(32)
+		}
+		c.Response().Header().Set(echo.HeaderXRequestID, id)
+		return next(c)
+	}
+}
+
+// errorHandler renders every error as models.ErrorResponse with a stable
+// code, the message follows Accept-Language.
+func errorHandler(err error, c echo.Context) {
+	appErr := apperrors.From(err)
+	requestId := c.Response().Header().Get(echo.HeaderXRequestID)
+	if appErr.Status >= http.StatusInternalServerError {
+		log.Errorf("request %s %s %s failed: %v", requestId, c.Request().Method, c.Request().URL.Path, err)
+	}
+	if c.Response().Committed {
+		return
+	}
+
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(appErr.Status)
+	} else {
+		err = c.JSON(appErr.Status, &models.ErrorResponse{
+			Code:      string(appErr.Code),
+			Message:   appErr.Message(apperrors.Lang(c.Request().Header.Get("Accept-Language"))),
+			Details:   appErr.Details,
+			RequestId: requestId,
+		})
+	}
+	if err != nil {
+		log.Errorf("failed to send error response: %v", err)
+	}
+}
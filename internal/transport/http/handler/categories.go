@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"excel-service/internal/models"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+// SuggestCategories godoc
+// @Summary      suggest categories
+// @Description  trains the category model on categorized nomenclature and suggests categories for unclassified items
+// @Produce      json
+// @Success      200  {object}  models.CategorySuggestionRun
+// @Failure      409  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Router       /api/v1/categories/suggestions [post]
+func (h *Handler) SuggestCategories(c echo.Context) error {
+	res, err := h.excelService.SuggestCategories(c.Request().Context())
+	if err != nil {
+		return err
+	}
+
+	log.Infof("success response: %v", res)
+	return c.JSON(http.StatusOK, res)
+}
+
+// GetCategorySuggestions godoc
+// @Summary      category suggestions
+// @Description  returns suggestions with the given status, the most confident first
+// @Produce      json
+// @Param        status query string false "pending (default), accepted or rejected"
+// @Success      200  {array}   models.CategorySuggestion
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Router       /api/v1/categories/suggestions [get]
+func (h *Handler) GetCategorySuggestions(c echo.Context) error {
+	res, err := h.excelService.GetCategorySuggestions(c.Request().Context(), c.QueryParam("status"))
+	if err != nil {
+		return err
+	}
+
+	log.Infof("success response: %d suggestions", len(res))
+	return c.JSON(http.StatusOK, res)
+}
+
+// ReviewCategorySuggestions godoc
+// @Summary      accept or reject category suggestions
+// @Description  accepted suggestions move the nomenclature to the suggested category
+// @Accept       json
+// @Produce      json
+// @Param        req body      models.CategoryReviewReq true "suggestion ids"
+// @Success      200  {object}  models.CategoryReview
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Router       /api/v1/categories/suggestions/review [post]
+func (h *Handler) ReviewCategorySuggestions(c echo.Context) error {
+	var req models.CategoryReviewReq
+	if bErr := c.Bind(&req); bErr != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, bErr)
+	}
+
+	res, err := h.excelService.ReviewCategorySuggestions(c.Request().Context(), &req)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("success response: %v", res)
+	return c.JSON(http.StatusOK, res)
+}
+
+// ImportCategoryTree godoc
+// @Summary      import category tree
+// @Description  first column is a dotted code ("01.02.03") or a name path ("Родитель / Дочерняя"), second is the name, third is an optional parent code. The tree is saved in one transaction, files with missing parents or cycles are rejected
+// @Accept       mpfd
+// @Produce      json
+// @Param        file formData file true "category tree xlsx"
+// @Success      200  {object}  models.CategoryTreeImport
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      422  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Router       /api/v1/upload/category/tree [post]
+func (h *Handler) ImportCategoryTree(c echo.Context) error {
+	file, err := c.FormFile("file")
+	if err != nil {
+		log.Errorf("failed to read file: %v", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "failed to read file by key file")
+	}
+
+	res, resErr := h.excelService.ImportCategoryTree(c.Request().Context(), file)
+	if resErr != nil {
+		return resErr
+	}
+
+	log.Infof("success response: %v", res)
+	return c.JSON(http.StatusOK, res)
+}
+
+// GetCategoryTree godoc
+// @Summary      category tree
+// @Description  returns root categories with their children
+// @Produce      json
+// @Success      200  {array}   models.CategoryNode
+// @Failure      500  {object}  models.ErrorResponse
+// @Router       /api/v1/categories/tree [get]
+func (h *Handler) GetCategoryTree(c echo.Context) error {
+	res, err := h.excelService.GetCategoryTree(c.Request().Context())
+	if err != nil {
+		return err
+	}
+
+	log.Infof("success response: %d root categories", len(res))
+	return c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+// ImportClassifier godoc
+// @Summary      import OKPD2 or TN VED
+// @Description  saves the official classifier with its hierarchy, code in the first column and name in the second
+// @Accept       mpfd
+// @Produce      json
+// @Param        kind path     string true "okpd2 or tnved"
+// @Param        file formData file   true "classifier xlsx"
+// @Success      200  {object}  models.ClassifierImport
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Router       /api/v1/upload/classifier/{kind} [post]
+func (h *Handler) ImportClassifier(c echo.Context) error {
+	file, err := c.FormFile("file")
+	if err != nil {
+		log.Errorf("failed to read file: %v", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "failed to read file by key file")
+	}
+
+	res, resErr := h.excelService.ImportClassifier(c.Request().Context(), c.Param("kind"), file)
+	if resErr != nil {
+		return resErr
+	}
+
+	log.Infof("success response: %v", res)
+	return c.JSON(http.StatusOK, res)
+}
+
+// GetClassifierCode godoc
+// @Summary      classifier code with parents
+// @Description  normalizes the code and returns it with all parent sections
+// @Produce      json
+// @Param        kind path string true "okpd2 or tnved"
+// @Param        code path string true "code, e.g. 25.99.29.190 or 25992919"
+// @Success      200  {object}  models.ClassifierLookup
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      404  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Router       /api/v1/classifiers/{kind}/{code} [get]
+func (h *Handler) GetClassifierCode(c echo.Context) error {
+	res, err := h.excelService.GetClassifierCode(c.Request().Context(), c.Param("kind"), c.Param("code"))
+	if err != nil {
+		return err
+	}
+
+	log.Infof("success response: %v", res)
+	return c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"excel-service/internal/models"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+// SetColumnMapping godoc
+// @Summary      confirm column mapping of upload
+// @Description  columns map target fields to 0-based column indexes of the sheet, name is required. The upload is imported again with the mapping, with save_as the mapping is also saved as a template of the company
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "upload id"
+// @Param        order body models.ColumnMappingReq true "req"
+// @Success      200  {object}  models.ColumnMappingRun
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      404  {object}  models.ErrorResponse
+// @Failure      409  {object}  models.ErrorResponse
+// @Failure      422  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Router       /api/v1/uploads/{id}/mapping [put]
+func (h *Handler) SetColumnMapping(c echo.Context) error {
+	var req models.ColumnMappingReq
+	if bErr := c.Bind(&req); bErr != nil {
+		log.Warn("bad request")
+		return echo.NewHTTPError(http.StatusBadRequest, bErr)
+	}
+
+	res, err := h.excelService.SetColumnMapping(c.Request().Context(), c.Param("id"), &req)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("success response: %v", res.Result)
+	return c.JSON(http.StatusOK, res)
+}
+
+// GetColumnTemplates godoc
+// @Summary      saved column templates
+// @Description  returns the column mappings the company saved, the company of the caller when none is given
+// @Produce      json
+// @Param        company query string false "company id"
+// @Success      200  {array}   models.ColumnTemplate
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      403  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Router       /api/v1/column-templates [get]
+func (h *Handler) GetColumnTemplates(c echo.Context) error {
+	res, err := h.excelService.GetColumnTemplates(c.Request().Context(), c.QueryParam("company"))
+	if err != nil {
+		return err
+	}
+
+	log.Infof("success response: %d column templates", len(res))
+	return c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+// ImportCountries godoc
+// @Summary      import OKSM countries
+// @Description  columns are the numeric OKSM code, short name, full name, alpha-2, alpha-3 and an optional English name. Rows without a numeric code are skipped
+// @Accept       mpfd
+// @Produce      json
+// @Param        file formData file true "OKSM xlsx"
+// @Success      200  {object}  models.CountryImport
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Router       /api/v1/upload/countries [post]
+func (h *Handler) ImportCountries(c echo.Context) error {
+	file, err := c.FormFile("file")
+	if err != nil {
+		log.Errorf("failed to read file: %v", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "failed to read file by key file")
+	}
+
+	res, resErr := h.excelService.ImportCountries(c.Request().Context(), file)
+	if resErr != nil {
+		return resErr
+	}
+
+	log.Infof("success response: %v", res)
+	return c.JSON(http.StatusOK, res)
+}
+
+// GetCountries godoc
+// @Summary      country reference
+// @Description  returns OKSM countries with the aliases used to recognize them in uploads
+// @Produce      json
+// @Success      200  {array}   models.Country
+// @Failure      500  {object}  models.ErrorResponse
+// @Router       /api/v1/countries [get]
+func (h *Handler) GetCountries(c echo.Context) error {
+	res, err := h.excelService.GetCountries(c.Request().Context())
+	if err != nil {
+		return err
+	}
+
+	log.Infof("success response: %d countries", len(res))
+	return c.JSON(http.StatusOK, res)
+}
+
+// GetCountrySummary godoc
+// @Summary      nomenclature by country of origin
+// @Description  counts nomenclature by OKSM country for import substitution reports, items without a recognized country have an empty code
+// @Produce      json
+// @Success      200  {array}   models.CountrySummary
+// @Failure      500  {object}  models.ErrorResponse
+// @Router       /api/v1/countries/summary [get]
+func (h *Handler) GetCountrySummary(c echo.Context) error {
+	res, err := h.excelService.GetCountrySummary(c.Request().Context())
+	if err != nil {
+		return err
+	}
+
+	log.Infof("success response: %d countries", len(res))
+	return c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+// ImportCurrencyRates godoc
+// @Summary      import CBR exchange rates
+// @Description  saves daily rates of the Central Bank of Russia from the uploaded XML_daily.asp document or downloads them for the date
+// @Accept       mpfd
+// @Produce      json
+// @Param        date query    string false "rates date YYYY-MM-DD, today by default"
+// @Param        file formData file   false "XML_daily.asp document"
+// @Success      200  {object}  models.CurrencyRates
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      502  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Router       /api/v1/currency/rates [post]
+func (h *Handler) ImportCurrencyRates(c echo.Context) error {
+	date, dateErr := queryDate(c, "date")
+	if dateErr != nil {
+		return dateErr
+	}
+
+	var src io.Reader
+	if file, err := c.FormFile("file"); err == nil {
+		f, openErr := file.Open()
+		if openErr != nil {
+			log.Errorf("failed to open file: %v", openErr)
+			return echo.NewHTTPError(http.StatusBadRequest, "failed to read file by key file")
+		}
+		defer f.Close()
+		src = f
+	}
+
+	res, err := h.excelService.ImportCurrencyRates(c.Request().Context(), date, src)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("success response: %d rates", len(res.Rates))
+	return c.JSON(http.StatusOK, res)
+}
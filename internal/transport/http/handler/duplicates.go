@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"excel-service/internal/models"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+// FindUploadDuplicates godoc
+// @Summary      find duplicates of upload
+// @Description  matches nomenclature of the upload with the catalogue and queues probable duplicates, runs after every upload automatically
+// @Produce      json
+// @Param        id path string true "upload id"
+// @Success      200  {object}  models.DuplicateRun
+// @Failure      404  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Router       /api/v1/uploads/{id}/duplicates [post]
+func (h *Handler) FindUploadDuplicates(c echo.Context) error {
+	res, err := h.excelService.FindUploadDuplicates(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	log.Infof("success response: %v", res)
+	return c.JSON(http.StatusOK, res)
+}
+
+// GetDuplicateCandidates godoc
+// @Summary      duplicate review queue
+// @Description  returns duplicate candidates with the given status, the best matches first
+// @Produce      json
+// @Param        status query string false "pending (default), confirmed, rejected or merged"
+// @Success      200  {array}   models.DuplicateCandidate
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Router       /api/v1/duplicates [get]
+func (h *Handler) GetDuplicateCandidates(c echo.Context) error {
+	res, err := h.excelService.GetDuplicateCandidates(c.Request().Context(), c.QueryParam("status"))
+	if err != nil {
+		return err
+	}
+
+	log.Infof("success response: %d candidates", len(res))
+	return c.JSON(http.StatusOK, res)
+}
+
+// ReviewDuplicates godoc
+// @Summary      confirm or reject duplicates
+// @Accept       json
+// @Produce      json
+// @Param        req body      models.DuplicateReviewReq true "candidate ids"
+// @Success      200  {object}  models.DuplicateReview
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Router       /api/v1/duplicates/review [post]
+func (h *Handler) ReviewDuplicates(c echo.Context) error {
+	var req models.DuplicateReviewReq
+	if bErr := c.Bind(&req); bErr != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, bErr)
+	}
+
+	res, err := h.excelService.ReviewDuplicates(c.Request().Context(), &req)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("success response: %v", res)
+	return c.JSON(http.StatusOK, res)
+}
+
+// MergeDuplicate godoc
+// @Summary      merge duplicate
+// @Description  moves price list links of the new item to the catalogue item and marks the new item as merged into it
+// @Produce      json
+// @Param        id path string true "duplicate candidate id"
+// @Success      200  {object}  models.DuplicateMerge
+// @Failure      404  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Router       /api/v1/duplicates/{id}/merge [post]
+func (h *Handler) MergeDuplicate(c echo.Context) error {
+	res, err := h.excelService.MergeDuplicate(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	log.Infof("success response: %v", res)
+	return c.JSON(http.StatusOK, res)
+}
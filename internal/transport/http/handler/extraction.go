@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"excel-service/internal/models"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+// TestExtractionRules godoc
+// @Summary      try attribute extraction rules
+// @Description  runs the given rules, or the active rules from the DB when none are given, on sample product names
+// @Accept       json
+// @Produce      json
+// @Param        req body      models.ExtractionTestReq true "sample names and optional rules"
+// @Success      200  {array}   models.ExtractionResult
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Router       /api/v1/extraction/test [post]
+func (h *Handler) TestExtractionRules(c echo.Context) error {
+	var req models.ExtractionTestReq
+	if bErr := c.Bind(&req); bErr != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, bErr)
+	}
+
+	res, err := h.excelService.TestExtractionRules(c.Request().Context(), &req)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("success response: %v", res)
+	return c.JSON(http.StatusOK, res)
+}
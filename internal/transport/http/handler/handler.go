@@ -23,9 +23,11 @@ func NewHandler(excelService service.ExcelService) *Handler {
 //@Accept mpfd
 //@Produce json
 //@Param excel file formData file true "file"
+//@Param sheet formData string false "sheet name, every sheet that fits the template by default"
+//@Param sheet_index formData int false "sheet number from 1, used when sheet is empty"
 //@Success 200 {object} models.ResponseMsg
-//@Failure 400 {object} models.ResponseMsg
-//@Failure 500 {object} models.ResponseMsg
+//@Failure 400 {object} models.ErrorResponse
+//@Failure 500 {object} models.ErrorResponse
 //@Router /api/v1/upload/excel [post]
 func (h *Handler) SaveExcelFile(c echo.Context) error {
 	file, err := c.FormFile("file")
@@ -34,7 +36,12 @@ func (h *Handler) SaveExcelFile(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "failed to read file by key file")
 	}
 
-	res, resErr := h.excelService.SaveExcelFile(c.Request().Context(), file)
+	sheet, sheetErr := sheetSelector(c)
+	if sheetErr != nil {
+		return sheetErr
+	}
+
+	res, resErr := h.excelService.SaveExcelFile(c.Request().Context(), file, sheet)
 	if resErr != nil {
 		return resErr
 	}
@@ -50,7 +57,12 @@ func (h *Handler) SaveMtr(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "failed to read file by key file")
 	}
 
-	res, resErr := h.excelService.SaveMTRExcelFile(c.Request().Context(), file)
+	sheet, sheetErr := sheetSelector(c)
+	if sheetErr != nil {
+		return sheetErr
+	}
+
+	res, resErr := h.excelService.SaveMTRExcelFile(c.Request().Context(), file, sheet)
 	if resErr != nil {
 		return resErr
 	}
@@ -66,7 +78,12 @@ func (h *Handler) NewCategory(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "failed to read file by key file")
 	}
 
-	res, resErr := h.excelService.SaveCategory(c.Request().Context(), file)
+	sheet, sheetErr := sheetSelector(c)
+	if sheetErr != nil {
+		return sheetErr
+	}
+
+	res, resErr := h.excelService.SaveCategory(c.Request().Context(), file, sheet)
 	if resErr != nil {
 		return resErr
 	}
@@ -82,7 +99,12 @@ func (h *Handler) NewCompany(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "failed to read file by key file")
 	}
 
-	res, resErr := h.excelService.CreateCompany(c.Request().Context(), file)
+	sheet, sheetErr := sheetSelector(c)
+	if sheetErr != nil {
+		return sheetErr
+	}
+
+	res, resErr := h.excelService.CreateCompany(c.Request().Context(), file, sheet)
 	if resErr != nil {
 		return resErr
 	}
@@ -98,7 +120,12 @@ func (h *Handler) SaveOrganizerNomenclature(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "failed to read file by key file")
 	}
 
-	res, resErr := h.excelService.SaveOrganizerNomenclature(c.Request().Context(), file)
+	sheet, sheetErr := sheetSelector(c)
+	if sheetErr != nil {
+		return sheetErr
+	}
+
+	res, resErr := h.excelService.SaveOrganizerNomenclature(c.Request().Context(), file, sheet)
 	if resErr != nil {
 		return resErr
 	}
@@ -114,7 +141,12 @@ func (h *Handler) SaveBanks(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "failed to read file by key file")
 	}
 
-	res, resErr := h.excelService.SaveBanks(c.Request().Context(), file)
+	sheet, sheetErr := sheetSelector(c)
+	if sheetErr != nil {
+		return sheetErr
+	}
+
+	res, resErr := h.excelService.SaveBanks(c.Request().Context(), file, sheet)
 	if resErr != nil {
 		return resErr
 	}
@@ -144,7 +176,7 @@ func (h *Handler) ProcessDirectusUploads(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, bErr)
 	}
 
-	res, resErr := h.excelService.SaveNomenclatureFromDirectus(c.Request().Context(), &req)
+	res, resErr := h.excelService.SaveNomenclatureFromDirectus(c.Request().Context(), &req, "")
 	if resErr != nil {
 		return resErr
 	}
@@ -181,10 +213,16 @@ func (h *Handler) UploadExcelFile(c echo.Context) error {
 // @Accept       json
 // @Produce      json
 // @Param        order body models.DirectusModel true "req"
+// @Param        X-Hook-Timestamp header string true "unix time the signature was made at, 5 minutes of skew are allowed"
+// @Param        X-Hook-Signature header string true "sha256= and hex HMAC-SHA256 of timestamp, a dot and the raw body"
+// @Param        Idempotency-Key  header string false "processing run key, the upload key by default. Repeated calls return the result of the first run, a new key for an imported upload returns the result of its import"
 // @Success      200  {object}  models.ResponseMsg
-// @Failure      400  {object}  models.ResponseMsg
-// @Failure      404  {object}  models.ResponseMsg
-// @Failure      500  {object}  models.ResponseMsg
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      401  {object}  models.ErrorResponse
+// @Failure      404  {object}  models.ErrorResponse
+// @Failure      409  {object}  models.ErrorResponse
+// @Failure      422  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
 // @Router       /api/v1/hook [post]
 func (h *Handler) SaveNomenclatureFromDirectus(c echo.Context) error {
 	var req models.DirectusModel
@@ -193,7 +231,7 @@ func (h *Handler) SaveNomenclatureFromDirectus(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, bErr)
 	}
 
-	res, err := h.excelService.SaveNomenclatureFromDirectus(c.Request().Context(), &req)
+	res, err := h.excelService.SaveNomenclatureFromDirectus(c.Request().Context(), &req, c.Request().Header.Get("Idempotency-Key"))
 	if err != nil {
 		return err
 	}
@@ -202,15 +240,15 @@ func (h *Handler) SaveNomenclatureFromDirectus(c echo.Context) error {
 }
 
 // GetFileColumns godoc
-// @Summary      porecess excel file from directus
-// @Description  accepts and returns json object
+// @Summary      columns of upload sheet
+// @Description  returns the columns of the sheet to map with sample values and suggested target fields, saved company templates are suggested first
 // @Accept       json
 // @Produce      json
 // @Param        order body models.DirectusModel true "req"
-// @Success      200  {object}  models.FileColumns
-// @Failure      400  {object}  models.ResponseMsg
-// @Failure      404  {object}  models.ResponseMsg
-// @Failure      500  {object}  models.ResponseMsg
+// @Success      200  {object}  models.ColumnDraft
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      404  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
 // @Router       /api/v1/getColumns [post]
 func (h *Handler) GetFileColumns(c echo.Context) error {
 	var req models.DirectusModel
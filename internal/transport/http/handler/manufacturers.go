@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"excel-service/internal/models"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+// ImportManufacturers godoc
+// @Summary      import manufacturer directory
+// @Description  columns are name, aliases separated by ";", INN and country, the first row is the header. Queued manufacturer strings matching the new aliases are linked to the directory
+// @Accept       mpfd
+// @Produce      json
+// @Param        file formData file true "manufacturer directory xlsx"
+// @Success      200  {object}  models.ManufacturerImport
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Router       /api/v1/upload/manufacturers [post]
+func (h *Handler) ImportManufacturers(c echo.Context) error {
+	file, err := c.FormFile("file")
+	if err != nil {
+		log.Errorf("failed to read file: %v", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "failed to read file by key file")
+	}
+
+	res, resErr := h.excelService.ImportManufacturers(c.Request().Context(), file)
+	if resErr != nil {
+		return resErr
+	}
+
+	log.Infof("success response: %v", res)
+	return c.JSON(http.StatusOK, res)
+}
+
+// GetManufacturers godoc
+// @Summary      manufacturer directory
+// @Description  returns manufacturers with their alias keys, for marketplace filters
+// @Produce      json
+// @Success      200  {array}   models.Manufacturer
+// @Failure      500  {object}  models.ErrorResponse
+// @Router       /api/v1/manufacturers [get]
+func (h *Handler) GetManufacturers(c echo.Context) error {
+	res, err := h.excelService.GetManufacturers(c.Request().Context())
+	if err != nil {
+		return err
+	}
+
+	log.Infof("success response: %d manufacturers", len(res))
+	return c.JSON(http.StatusOK, res)
+}
+
+// GetUnresolvedManufacturers godoc
+// @Summary      unresolved manufacturers
+// @Description  returns manufacturer strings from uploads no alias matched, most frequent first
+// @Produce      json
+// @Success      200  {array}   models.UnresolvedManufacturer
+// @Failure      500  {object}  models.ErrorResponse
+// @Router       /api/v1/manufacturers/unresolved [get]
+func (h *Handler) GetUnresolvedManufacturers(c echo.Context) error {
+	res, err := h.excelService.GetUnresolvedManufacturers(c.Request().Context())
+	if err != nil {
+		return err
+	}
+
+	log.Infof("success response: %d unresolved manufacturers", len(res))
+	return c.JSON(http.StatusOK, res)
+}
+
+// ResolveManufacturer godoc
+// @Summary      resolve manufacturer string
+// @Description  makes the queued key an alias of manufacturer_id, or of a new manufacturer when only manufacturer is given, and links the items saved with it
+// @Accept       json
+// @Produce      json
+// @Param        req body models.ManufacturerResolveReq true "queued key and manufacturer"
+// @Success      200  {object}  models.ManufacturerResolve
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      404  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Router       /api/v1/manufacturers/unresolved/resolve [post]
+func (h *Handler) ResolveManufacturer(c echo.Context) error {
+	var req models.ManufacturerResolveReq
+	if bErr := c.Bind(&req); bErr != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, bErr)
+	}
+
+	res, err := h.excelService.ResolveManufacturer(c.Request().Context(), &req)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("success response: %v", res)
+	return c.JSON(http.StatusOK, res)
+}
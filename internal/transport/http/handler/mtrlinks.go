@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"excel-service/internal/models"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+// LinkSupplierItemsToMtr godoc
+// @Summary      link supplier items to MTR
+// @Description  links every unlinked supplier item to the organizer MTR reference by code, then by name and attributes. Runs for new supplier items after every upload automatically
+// @Produce      json
+// @Success      200  {object}  models.MtrLinkRun
+// @Failure      500  {object}  models.ErrorResponse
+// @Router       /api/v1/mtr/links [post]
+func (h *Handler) LinkSupplierItemsToMtr(c echo.Context) error {
+	res, err := h.excelService.LinkSupplierItemsToMtr(c.Request().Context())
+	if err != nil {
+		return err
+	}
+
+	log.Infof("success response: %v", res)
+	return c.JSON(http.StatusOK, res)
+}
+
+// GetUnmatchedSupplierItems godoc
+// @Summary      supplier items without MTR
+// @Description  returns supplier items the linker could not map to an MTR reference
+// @Produce      json
+// @Param        limit  query int false "page size, 500 at most"
+// @Param        offset query int false "items to skip"
+// @Success      200  {array}   models.MatchItem
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Router       /api/v1/mtr/unmatched [get]
+func (h *Handler) GetUnmatchedSupplierItems(c echo.Context) error {
+	limit, limitErr := queryInt(c, "limit")
+	if limitErr != nil {
+		return limitErr
+	}
+	offset, offsetErr := queryInt(c, "offset")
+	if offsetErr != nil {
+		return offsetErr
+	}
+
+	res, err := h.excelService.GetUnmatchedSupplierItems(c.Request().Context(), limit, offset)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("success response: %d unmatched items", len(res))
+	return c.JSON(http.StatusOK, res)
+}
+
+// SetMtrLink godoc
+// @Summary      map supplier item to MTR manually
+// @Accept       json
+// @Produce      json
+// @Param        id  path string            true "supplier nomenclature id"
+// @Param        req body models.MtrLinkReq true "organizer_catalogue id of the MTR reference"
+// @Success      200  {object}  models.MtrLink
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      404  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Router       /api/v1/mtr/links/{id} [put]
+func (h *Handler) SetMtrLink(c echo.Context) error {
+	var req models.MtrLinkReq
+	if bErr := c.Bind(&req); bErr != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, bErr)
+	}
+
+	res, err := h.excelService.SetMtrLink(c.Request().Context(), c.Param("id"), &req)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("success response: %v", res)
+	return c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+// GetPriceListVersions godoc
+// @Summary      price list versions
+// @Description  returns all stored versions of the price list
+// @Produce      json
+// @Param        id path string true "price list id"
+// @Success      200  {array}   models.PriceListVersion
+// @Failure      403  {object}  models.ErrorResponse
+// @Failure      404  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Router       /api/v1/prices/{id}/versions [get]
+func (h *Handler) GetPriceListVersions(c echo.Context) error {
+	res, err := h.excelService.GetPriceListVersions(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
+
+// GetPriceListDiff godoc
+// @Summary      diff between price list versions
+// @Description  returns added, removed and repriced items, by default between the two latest versions
+// @Produce      json
+// @Param        id   path  string true  "price list id"
+// @Param        from query int    false "old version"
+// @Param        to   query int    false "new version"
+// @Success      200  {object}  models.PriceListDiff
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      403  {object}  models.ErrorResponse
+// @Failure      404  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Router       /api/v1/prices/{id}/diff [get]
+func (h *Handler) GetPriceListDiff(c echo.Context) error {
+	from, fromErr := queryInt(c, "from")
+	if fromErr != nil {
+		return fromErr
+	}
+	to, toErr := queryInt(c, "to")
+	if toErr != nil {
+		return toErr
+	}
+
+	res, err := h.excelService.GetPriceListDiff(c.Request().Context(), c.Param("id"), from, to)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("success response: %d added, %d removed, %d repriced", len(res.Added), len(res.Removed), len(res.Repriced))
+	return c.JSON(http.StatusOK, res)
+}
+
+func queryInt(c echo.Context, name string) (int, error) {
+	value := c.QueryParam(name)
+	if value == "" {
+		return 0, nil
+	}
+	res, err := strconv.Atoi(value)
+	if err != nil {
+		log.Warnf("failed to parse query param %s: %v", name, err)
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "query param "+name+" must be a number")
+	}
+	return res, nil
+}
+
+// GetNomenclaturePrice godoc
+// @Summary      effective price for quantity
+// @Description  returns the wholesale tier price that applies to the quantity or the base price, converted to RUB
+// @Produce      json
+// @Param        id       path  string true  "nomenclature id"
+// @Param        quantity query number false "ordered quantity, 1 by default"
+// @Param        date     query string false "exchange rate date YYYY-MM-DD, today by default"
+// @Success      200  {object}  models.PriceQuote
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      404  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Router       /api/v1/nomenclature/{id}/price [get]
+func (h *Handler) GetNomenclaturePrice(c echo.Context) error {
+	quantity := 1.0
+	if value := c.QueryParam("quantity"); value != "" {
+		q, err := strconv.ParseFloat(value, 64)
+		if err != nil || q <= 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "query param quantity must be a positive number")
+		}
+		quantity = q
+	}
+
+	date, dateErr := queryDate(c, "date")
+	if dateErr != nil {
+		return dateErr
+	}
+
+	res, err := h.excelService.GetNomenclaturePrice(c.Request().Context(), c.Param("id"), quantity, date)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
+
+// queryDate parses a YYYY-MM-DD query param, today is used when it is empty.
+func queryDate(c echo.Context, name string) (time.Time, error) {
+	value := c.QueryParam(name)
+	if value == "" {
+		now := time.Now()
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC), nil
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		log.Warnf("failed to parse query param %s: %v", name, err)
+		return time.Time{}, echo.NewHTTPError(http.StatusBadRequest, "query param "+name+" must be a date YYYY-MM-DD")
+	}
+	return date, nil
+}
@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"excel-service/internal/models"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+// sheetSelector reads the optional sheet and sheet_index form fields of an
+// upload.
+func sheetSelector(c echo.Context) (models.SheetSelector, error) {
+	sel := models.SheetSelector{Name: c.FormValue("sheet")}
+	if value := c.FormValue("sheet_index"); value != "" {
+		index, err := strconv.Atoi(value)
+		if err != nil || index < 1 {
+			log.Warnf("failed to parse sheet_index %q: %v", value, err)
+			return sel, echo.NewHTTPError(http.StatusBadRequest, "sheet_index must be a number from 1")
+		}
+		sel.Index = index
+	}
+	return sel, nil
+}
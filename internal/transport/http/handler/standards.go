@@ -0,0 +1,27 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+// GetNomenclatureByStandard godoc
+// @Summary      nomenclature by standard
+// @Description  returns nomenclature linked to the GOST, TU, OST, STO or ISO standard, the reference is normalized before the search
+// @Produce      json
+// @Param        standard query string true "standard reference, e.g. ГОСТ 8732-78"
+// @Success      200  {array}   models.StandardNomenclature
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Router       /api/v1/standards/nomenclature [get]
+func (h *Handler) GetNomenclatureByStandard(c echo.Context) error {
+	res, err := h.excelService.GetNomenclatureByStandard(c.Request().Context(), c.QueryParam("standard"))
+	if err != nil {
+		return err
+	}
+
+	log.Infof("success response: %d nomenclature", len(res))
+	return c.JSON(http.StatusOK, res)
+}
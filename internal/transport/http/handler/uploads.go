@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+// RollbackUpload godoc
+// @Summary      rollback data created by upload
+// @Description  deletes nomenclature, packages and price list links created by the upload and restores the rows it changed, direct imports return their upload id for this
+// @Produce      json
+// @Param        id path string true "upload id"
+// @Success      200  {object}  models.UploadRollback
+// @Failure      404  {object}  models.ErrorResponse
+// @Failure      409  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Router       /api/v1/uploads/{id}/data [delete]
+func (h *Handler) RollbackUpload(c echo.Context) error {
+	uploadId := c.Param("id")
+	if uploadId == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "upload id is required")
+	}
+
+	res, err := h.excelService.RollbackUpload(c.Request().Context(), uploadId)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("success response: %v", res)
+	return c.JSON(http.StatusOK, res)
+}
+
+// GetUploadRowErrors godoc
+// @Summary      row errors of upload
+// @Description  returns problems found in single rows of the upload, such rows are imported with the field left empty. With format csv or xlsx the report is returned as a file
+// @Produce      json
+// @Produce      text/csv
+// @Produce      application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param        id path string true "upload id"
+// @Param        format query string false "csv or xlsx"
+// @Success      200  {array}   models.UploadRowError
+// @Failure      400  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Router       /api/v1/uploads/{id}/errors [get]
+func (h *Handler) GetUploadRowErrors(c echo.Context) error {
+	if format := c.QueryParam("format"); format != "" {
+		file, err := h.excelService.ExportUploadRowErrors(c.Request().Context(), c.Param("id"), format)
+		if err != nil {
+			return err
+		}
+
+		log.Infof("success response: %s", file.Name)
+		c.Response().Header().Set(echo.HeaderContentDisposition, "attachment; filename=\""+file.Name+"\"")
+		return c.Blob(http.StatusOK, file.ContentType, file.Data)
+	}
+
+	res, err := h.excelService.GetUploadRowErrors(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	log.Infof("success response: %d row errors", len(res))
+	return c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,124 @@
+package http
+
+import (
+	"bytes"
+	"excel-service/internal/auth"
+	"excel-service/internal/models"
+	"excel-service/internal/service"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+const (
+	hookPath            = "/api/v1/hook"
+	hookSignatureHeader = "X-Hook-Signature"
+	hookTimestampHeader = "X-Hook-Timestamp"
+	hookTolerance       = 5 * time.Minute
+	hookBodyLimit       = 1 << 20
+	hookPrincipal       = "directus-hook"
+	// multipart headers and form fields on top of the file itself
+	multipartOverhead = 1 << 20
+)
+
+// bodyLimit rejects request bodies larger than the upload limit with 413
+// before they are read, chunked bodies are cut off by MaxBytesReader.
+func bodyLimit(maxFileSize int64) echo.MiddlewareFunc {
+	limit := maxFileSize + multipartOverhead
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			if req.ContentLength > limit {
+				log.Warnf("request body of %d bytes to %s", req.ContentLength, c.Path())
+				return echo.NewHTTPError(http.StatusRequestEntityTooLarge, "request body is too large")
+			}
+			req.Body = http.MaxBytesReader(c.Response(), req.Body, limit)
+			return next(c)
+		}
+	}
+}
+
+// authMiddleware authenticates every request by its bearer token and puts
+// the caller into the request context. The swagger UI is open and the hook
+// is authenticated by its signature instead.
+func authMiddleware(excelService service.ExcelService) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if strings.HasPrefix(c.Path(), "/api/v1/swagger") || c.Path() == hookPath {
+				return next(c)
+			}
+
+			header := c.Request().Header.Get(echo.HeaderAuthorization)
+			token := ""
+			if len(header) > 7 && strings.EqualFold(header[:7], "bearer ") {
+				token = strings.TrimSpace(header[7:])
+			}
+
+			ctx := c.Request().Context()
+			p, err := excelService.Authenticate(ctx, token)
+			if err != nil {
+				return err
+			}
+			c.SetRequest(c.Request().WithContext(auth.WithPrincipal(ctx, p)))
+			return next(c)
+		}
+	}
+}
+
+// adminOnly limits reference data endpoints to admin roles and service
+// clients.
+func adminOnly(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		p := auth.PrincipalFromContext(c.Request().Context())
+		if p == nil || !p.Admin {
+			log.Warnf("non admin call to %s %s", c.Request().Method, c.Path())
+			return echo.NewHTTPError(http.StatusForbidden, "admin role is required")
+		}
+		return next(c)
+	}
+}
+
+// hookSignature verifies the HMAC signature of Directus hook calls. The
+// signature covers the timestamp and the raw body, stale timestamps and
+// repeated signatures are rejected. Without a secret every call is refused.
+func hookSignature(secret string) echo.MiddlewareFunc {
+	replays := auth.NewReplayGuard()
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if secret == "" {
+				log.Errorf("HOOK_SECRET is not set, hook calls are rejected")
+				return echo.NewHTTPError(http.StatusUnauthorized, "invalid signature")
+			}
+
+			body, err := io.ReadAll(io.LimitReader(c.Request().Body, hookBodyLimit+1))
+			if err != nil {
+				log.Warnf("failed to read hook body: %v", err)
+				return echo.NewHTTPError(http.StatusBadRequest, "failed to read body")
+			}
+			if len(body) > hookBodyLimit {
+				return echo.NewHTTPError(http.StatusRequestEntityTooLarge, "hook body is too large")
+			}
+			c.Request().Body = io.NopCloser(bytes.NewReader(body))
+
+			now := time.Now()
+			signature := c.Request().Header.Get(hookSignatureHeader)
+			timestamp := c.Request().Header.Get(hookTimestampHeader)
+			if verifyErr := auth.VerifySignature([]byte(secret), timestamp, signature, body, now, hookTolerance); verifyErr != nil {
+				log.Warnf("rejected hook call: %v", verifyErr)
+				return echo.NewHTTPError(http.StatusUnauthorized, "invalid signature")
+			}
+			if replayErr := replays.Check(signature, now, 2*hookTolerance); replayErr != nil {
+				log.Warnf("rejected hook call: %v", replayErr)
+				return echo.NewHTTPError(http.StatusUnauthorized, "invalid signature")
+			}
+
+			ctx := auth.WithPrincipal(c.Request().Context(), &models.Principal{Service: hookPrincipal, Admin: true})
+			c.SetRequest(c.Request().WithContext(ctx))
+			return next(c)
+		}
+	}
+}
@@ -7,9 +7,9 @@ import (
 	"excel-service/internal/service"
 	"excel-service/internal/transport/http/handler"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 	_ "excel-service/docs"
 	echoSwagger "github.com/swaggo/echo-swagger"
@@ -60,27 +60,56 @@ func StartHTTPServer(ctx context.Context, errCh chan<- error) {
 
 	srvHandler := handler.NewHandler(excelService)
 
-	app.POST("api/v1/upload/excel", srvHandler.SaveExcelFile)
-	app.POST("api/v1/upload/mtr", srvHandler.SaveMtr)
-	app.POST("api/v1/upload/category", srvHandler.NewCategory)
-	app.POST("api/v1/upload/company", srvHandler.NewCompany)
-	app.POST("api/v1/upload/orgNomenclature", srvHandler.SaveOrganizerNomenclature)
-	app.POST("api/v1/upload/bank", srvHandler.SaveBanks)
-	app.POST("api/v1/upload/aws/object", srvHandler.GetExcelFromAwsByFileId)
+	app.HTTPErrorHandler = errorHandler
+	app.Use(requestID)
+	app.Use(bodyLimit(cfg.Limits.MaxFileSize))
+	app.Use(authMiddleware(excelService))
+
+	app.POST("api/v1/upload/excel", srvHandler.SaveExcelFile, adminOnly)
+	app.POST("api/v1/upload/mtr", srvHandler.SaveMtr, adminOnly)
+	app.POST("api/v1/upload/category", srvHandler.NewCategory, adminOnly)
+	app.POST("api/v1/upload/company", srvHandler.NewCompany, adminOnly)
+	app.POST("api/v1/upload/orgNomenclature", srvHandler.SaveOrganizerNomenclature, adminOnly)
+	app.POST("api/v1/upload/bank", srvHandler.SaveBanks, adminOnly)
+	app.POST("api/v1/upload/aws/object", srvHandler.GetExcelFromAwsByFileId, adminOnly)
 	app.POST("api/v1/upload/file/excel", srvHandler.UploadExcelFile)
-	app.POST("api/v1/hook", srvHandler.SaveNomenclatureFromDirectus)
+	app.POST("api/v1/hook", srvHandler.SaveNomenclatureFromDirectus, hookSignature(cfg.Auth.HookSecret))
+	app.POST("api/v1/getColumns", srvHandler.GetFileColumns)
+	app.PUT("api/v1/uploads/:id/mapping", srvHandler.SetColumnMapping)
+	app.GET("api/v1/column-templates", srvHandler.GetColumnTemplates)
+	app.DELETE("api/v1/uploads/:id/data", srvHandler.RollbackUpload)
+	app.GET("api/v1/prices/:id/versions", srvHandler.GetPriceListVersions)
+	app.GET("api/v1/prices/:id/diff", srvHandler.GetPriceListDiff)
+	app.GET("api/v1/nomenclature/:id/price", srvHandler.GetNomenclaturePrice)
+	app.POST("api/v1/currency/rates", srvHandler.ImportCurrencyRates, adminOnly)
+	app.POST("api/v1/extraction/test", srvHandler.TestExtractionRules, adminOnly)
+	app.GET("api/v1/standards/nomenclature", srvHandler.GetNomenclatureByStandard)
+	app.POST("api/v1/upload/classifier/:kind", srvHandler.ImportClassifier, adminOnly)
+	app.GET("api/v1/classifiers/:kind/:code", srvHandler.GetClassifierCode)
+	app.GET("api/v1/uploads/:id/errors", srvHandler.GetUploadRowErrors)
+	app.POST("api/v1/categories/suggestions", srvHandler.SuggestCategories, adminOnly)
+	app.GET("api/v1/categories/suggestions", srvHandler.GetCategorySuggestions, adminOnly)
+	app.POST("api/v1/categories/suggestions/review", srvHandler.ReviewCategorySuggestions, adminOnly)
+	app.POST("api/v1/upload/category/tree", srvHandler.ImportCategoryTree, adminOnly)
+	app.GET("api/v1/categories/tree", srvHandler.GetCategoryTree)
+	app.POST("api/v1/uploads/:id/duplicates", srvHandler.FindUploadDuplicates)
+	app.GET("api/v1/duplicates", srvHandler.GetDuplicateCandidates, adminOnly)
+	app.POST("api/v1/duplicates/review", srvHandler.ReviewDuplicates, adminOnly)
+	app.POST("api/v1/duplicates/:id/merge", srvHandler.MergeDuplicate, adminOnly)
+	app.POST("api/v1/mtr/links", srvHandler.LinkSupplierItemsToMtr, adminOnly)
+	app.PUT("api/v1/mtr/links/:id", srvHandler.SetMtrLink, adminOnly)
+	app.GET("api/v1/mtr/unmatched", srvHandler.GetUnmatchedSupplierItems, adminOnly)
+	app.POST("api/v1/upload/manufacturers", srvHandler.ImportManufacturers, adminOnly)
+	app.GET("api/v1/manufacturers", srvHandler.GetManufacturers)
+	app.GET("api/v1/manufacturers/unresolved", srvHandler.GetUnresolvedManufacturers, adminOnly)
+	app.POST("api/v1/manufacturers/unresolved/resolve", srvHandler.ResolveManufacturer, adminOnly)
+	app.POST("api/v1/upload/countries", srvHandler.ImportCountries, adminOnly)
+	app.GET("api/v1/countries", srvHandler.GetCountries)
+	app.GET("api/v1/countries/summary", srvHandler.GetCountrySummary)
 	app.GET("api/v1/swagger/*", echoSwagger.WrapHandler)
-	
-	app.POST("dimeken", dimeken)
 
 	errCh <- app.Start(cfg.Port)
 }
-func dimeken(c echo.Context) error {
-	res, _ := io.ReadAll(c.Request().Body)
-	fmt.Println(string(res))
-	return c.JSON(http.StatusOK, nil)
-}
-
 func InitDBX(ctx context.Context, url string) (*pgxpool.Pool, error) {
 	conf, cfgErr := pgxpool.ParseConfig(url)
 	if cfgErr != nil {
@@ -126,5 +155,59 @@ func newConfig() *configs.Configs {
 			SecretKey: getEnv("XCLOUD_DIRECTUS_S3_SECRET", "postgres"),
 			Bucket:    getEnv("XCLOUD_DIRECTUS_S3_BUCKET", "postgres"),
 		},
+		Cbr: &configs.CbrConfig{
+			RatesUrl: getEnv("CBR_RATES_URL", "https://www.cbr.ru/scripts/XML_daily.asp"),
+		},
+		Auth: &configs.AuthConfig{
+			JwtSecret:     getEnv("AUTH_JWT_SECRET", ""),
+			ServiceTokens: getServiceTokens("AUTH_SERVICE_TOKENS"),
+			AdminRoles:    getEnvList("AUTH_ADMIN_ROLES"),
+			HookSecret:    getEnv("HOOK_SECRET", ""),
+		},
+		Limits: &configs.LimitsConfig{
+			MaxFileSize:   getEnvInt("UPLOAD_MAX_FILE_SIZE", 50<<20),
+			MaxUnzipSize:  getEnvInt("UPLOAD_MAX_UNZIP_SIZE", 1<<30),
+			MaxUnzipRatio: float64(getEnvInt("UPLOAD_MAX_UNZIP_RATIO", 100)),
+			MaxSheets:     int(getEnvInt("UPLOAD_MAX_SHEETS", 50)),
+			MaxRows:       int(getEnvInt("UPLOAD_MAX_ROWS", 200000)),
+			MaxColumns:    int(getEnvInt("UPLOAD_MAX_COLUMNS", 300)),
+			MaxCellLength: int(getEnvInt("UPLOAD_MAX_CELL_LENGTH", 32767)),
+		},
+		Export: &configs.ExportConfig{
+			FormulaPolicy: getEnv("EXPORT_FORMULA_POLICY", "quote"),
+		},
+	}
+}
+
+func getEnvInt(key string, defaultValue int64) int64 {
+	value, err := strconv.ParseInt(getEnv(key, ""), 10, 64)
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+	return value
+}
+
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(getEnv(key, ""), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// getServiceTokens reads "name:token" pairs separated by commas and maps
+// every token to its client name.
+func getServiceTokens(key string) map[string]string {
+	tokens := map[string]string{}
+	for _, pair := range getEnvList(key) {
+		sep := strings.Index(pair, ":")
+		if sep <= 0 || sep == len(pair)-1 {
+			log.Warnf("service token %q is not in name:token form, skipped", pair)
+			continue
+		}
+		tokens[pair[sep+1:]] = pair[:sep]
 	}
+	return tokens
 }
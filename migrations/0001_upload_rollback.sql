@@ -0,0 +1,7 @@
+-- Rows written by the parser are tagged with the upload that created them,
+-- so DELETE api/v1/uploads/:id/data can remove them.
+alter table nomenclature add column if not exists upload uuid references uploads (id) on delete set null;
+alter table package add column if not exists upload uuid references uploads (id) on delete set null;
+
+create index if not exists nomenclature_upload_idx on nomenclature (upload);
+create index if not exists package_upload_idx on package (upload);
@@ -0,0 +1,22 @@
+-- Every price list upload is stored as a new version so that prices of two
+-- uploads can be compared.
+create table if not exists price_list_version (
+    id         uuid primary key default uuid_generate_v4(),
+    price_id   uuid        not null,
+    upload     uuid references uploads (id) on delete set null,
+    version    integer     not null,
+    created_at timestamptz not null default now(),
+    unique (price_id, version)
+);
+
+create index if not exists price_list_version_upload_idx on price_list_version (upload);
+
+create table if not exists price_list_version_item (
+    version_id      uuid not null references price_list_version (id) on delete cascade,
+    item_key        text not null,
+    nomenclature_id uuid references nomenclature (id) on delete set null,
+    name            text,
+    price_per_unit  numeric(15, 2),
+    wholesale_items jsonb,
+    primary key (version_id, item_key)
+);
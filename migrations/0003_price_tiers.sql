@@ -0,0 +1,14 @@
+-- Wholesale quantity breaks parsed from the supplier template. A null
+-- quantity_to means the tier has no upper bound.
+create table if not exists nomenclature_price_tier (
+    id              uuid primary key default uuid_generate_v4(),
+    nomenclature_id uuid           not null references nomenclature (id) on delete cascade,
+    quantity_from   numeric(15, 3) not null,
+    quantity_to     numeric(15, 3),
+    price           numeric(15, 2) not null,
+    check (quantity_to is null or quantity_to >= quantity_from)
+);
+
+create index if not exists nomenclature_price_tier_nomenclature_idx on nomenclature_price_tier (nomenclature_id);
+
+alter table price_list_version_item add column if not exists price_tiers jsonb;
@@ -0,0 +1,14 @@
+-- Daily exchange rates of the Central Bank of Russia: value rubles for
+-- nominal units of the currency.
+create table if not exists currency_rate (
+    currency_code text           not null,
+    rate_date     date           not null,
+    nominal       integer        not null,
+    value         numeric(15, 4) not null,
+    primary key (currency_code, rate_date)
+);
+
+-- Nomenclature prices reference currency by code, the dictionary has to
+-- contain every code suppliers use (RUB, USD, EUR, CNY).
+
+alter table price_list_version_item add column if not exists currency text;
@@ -0,0 +1,6 @@
+-- Prices split by VAT. price_per_unit stays as written by the supplier,
+-- price_includes_vat tells whether it is the gross or the net amount.
+alter table nomenclature add column if not exists price_includes_vat boolean not null default true;
+alter table nomenclature add column if not exists price_net numeric(15, 2);
+alter table nomenclature add column if not exists vat_amount numeric(15, 2);
+alter table nomenclature add column if not exists price_gross numeric(15, 2);
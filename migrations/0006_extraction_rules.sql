@@ -0,0 +1,30 @@
+-- Rules for extracting attributes from product names. Rules run in position
+-- order and every match is cut from the name before the next rule runs.
+-- Numeric rules need a named group "value" ("a", "b", "c" for dimensions
+-- in length x width x height order) and may capture "unit"; unit is the
+-- default when the name has none.
+create table if not exists extraction_rule (
+    id       uuid primary key default uuid_generate_v4(),
+    position integer not null,
+    pattern  text    not null,
+    field    text    not null,
+    unit     text,
+    active   boolean not null default true
+);
+
+create index if not exists extraction_rule_position_idx on extraction_rule (position) where active;
+
+alter table nomenclature add column if not exists attributes jsonb;
+
+insert into extraction_rule (position, field, unit, pattern)
+select v.position, v.field, v.unit, v.pattern
+from (values
+    (10, 'standard', null, '(?i)(?:^|[^\pL\d])(?P<value>(?:ГОСТ(?:\s*Р)?|ОСТ|ТУ|СТО)\s*\d[\d.\-–/]*\d)'),
+    (20, 'drawing', null, '(?:^|[^\pL\d])(?P<value>[\pL\d]{1,4}(?:\.[\pL\d]{1,4}){2,5})(?:[^\pL\d]|$)'),
+    (30, 'dimensions', 'мм', '(?i)(?P<a>\d+(?:[.,]\d+)?)\s*[xх×*]\s*(?P<b>\d+(?:[.,]\d+)?)\s*[xх×*]\s*(?P<c>\d+(?:[.,]\d+)?)(?:\s*(?P<unit>мм|см|м|mm|cm|m)(?:[^\pL\d]|$))?'),
+    (40, 'diameter', 'мм', '(?i)(?:Ø|⌀|Ду|DN)\s*(?P<value>\d+(?:[.,]\d+)?)(?:\s*(?P<unit>мм|mm)(?:[^\pL\d]|$))?'),
+    (50, 'thread', null, '(?:^|[^\pL\d])(?P<value>[MМ]\d+(?:[.,]\d+)?(?:[xх×]\d[.,]\d+)?|G\s?\d+(?:/\d+)?)(?:[^\pL\d]|$)'),
+    (60, 'weight', 'кг', '(?i)(?P<value>\d+(?:[.,]\d+)?)\s*(?P<unit>мг|кг|гр|г|т|kg|g)(?:[^\pL\d]|$)'),
+    (70, 'volume', 'л', '(?i)(?P<value>\d+(?:[.,]\d+)?)\s*(?P<unit>мл|л|м3|м³|ml)(?:[^\pL\d]|$)')
+) as v (position, field, unit, pattern)
+where not exists (select 1 from extraction_rule);
@@ -0,0 +1,20 @@
+-- Dictionary of normative documents. code is the canonical reference
+-- ("ГОСТ 8732-78", "ГОСТ Р 52857.1-2007", "ТУ 14-3-1128-2000"), nomenclature
+-- keeps the text in gost_tu and is linked to the dictionary for search.
+create table if not exists standard (
+    id     uuid primary key default uuid_generate_v4(),
+    kind   text not null,
+    number text not null,
+    code   text not null unique,
+    title  text
+);
+
+create index if not exists standard_code_pattern_idx on standard (code text_pattern_ops);
+
+create table if not exists nomenclature_standard (
+    nomenclature_id uuid not null references nomenclature (id) on delete cascade,
+    standard_id     uuid not null references standard (id) on delete cascade,
+    primary key (nomenclature_id, standard_id)
+);
+
+create index if not exists nomenclature_standard_standard_idx on nomenclature_standard (standard_id);
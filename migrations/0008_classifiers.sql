@@ -0,0 +1,27 @@
+-- OKPD2 and TN VED EAEU with their hierarchy. okpd2 already exists and is
+-- referenced by nomenclature.okpd2, so it only gets the missing columns.
+alter table okpd2 add column if not exists name text;
+alter table okpd2 add column if not exists parent_code text;
+alter table okpd2 add column if not exists level integer;
+create unique index if not exists okpd2_code_idx on okpd2 (code);
+
+create table if not exists tnved (
+    code        text primary key,
+    name        text,
+    parent_code text,
+    level       integer
+);
+
+-- Problems found in single rows of an upload, rows are imported anyway.
+create table if not exists upload_row_error (
+    id         bigserial primary key,
+    upload     uuid references uploads (id) on delete cascade,
+    file_name  text        not null,
+    row_number integer     not null,
+    field      text        not null,
+    value      text,
+    message    text        not null,
+    created_at timestamptz not null default now()
+);
+
+create index if not exists upload_row_error_upload_idx on upload_row_error (upload, row_number);
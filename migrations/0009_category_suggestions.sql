@@ -0,0 +1,14 @@
+-- Categories suggested for unclassified nomenclature. Categories are
+-- referenced by name, like everywhere in the parser.
+create table if not exists category_suggestion (
+    id              uuid primary key default uuid_generate_v4(),
+    nomenclature_id uuid             not null references nomenclature (id) on delete cascade,
+    category_name   text             not null,
+    confidence      double precision not null,
+    status          text             not null default 'pending',
+    created_at      timestamptz      not null default now(),
+    reviewed_at     timestamptz
+);
+
+create index if not exists category_suggestion_status_idx on category_suggestion (status, confidence desc);
+create index if not exists category_suggestion_nomenclature_idx on category_suggestion (nomenclature_id);
@@ -0,0 +1,27 @@
+-- Probable duplicates between new nomenclature and the catalogue. Names are
+-- compared with pg_trgm to pick candidates, the score is computed by the
+-- parser.
+create extension if not exists pg_trgm;
+
+create index if not exists nomenclature_name_trgm_idx on nomenclature using gin (name gin_trgm_ops);
+create index if not exists nomenclature_code_skmtr_idx on nomenclature (code_skmtr);
+create index if not exists nomenclature_code_ks_nsi_idx on nomenclature (code_ks_nsi);
+create index if not exists nomenclature_code_amto_idx on nomenclature (code_amto);
+
+-- Merged nomenclature stays for history and points to the item it was
+-- merged into.
+alter table nomenclature add column if not exists merged_into uuid references nomenclature (id) on delete set null;
+
+create table if not exists duplicate_candidate (
+    id              uuid primary key default uuid_generate_v4(),
+    nomenclature_id uuid             not null references nomenclature (id) on delete cascade,
+    duplicate_id    uuid             not null references nomenclature (id) on delete cascade,
+    score           double precision not null,
+    reasons         text[]           not null,
+    status          text             not null default 'pending',
+    created_at      timestamptz      not null default now(),
+    reviewed_at     timestamptz,
+    unique (nomenclature_id, duplicate_id)
+);
+
+create index if not exists duplicate_candidate_status_idx on duplicate_candidate (status, score desc);
@@ -0,0 +1,18 @@
+-- Where nomenclature came from: supplier price lists or the organizer MTR
+-- catalogue (MTR and organizer templates). Old rows are told apart by the
+-- payload only the organizer templates fill.
+alter table nomenclature add column if not exists source text;
+update nomenclature set source = case when payload is null then 'supplier' else 'mtr' end where source is null;
+create index if not exists nomenclature_source_idx on nomenclature (source);
+
+-- One organizer MTR reference per supplier item. Manual links are never
+-- replaced by the automatic linker.
+create table if not exists mtr_link (
+    nomenclature_id uuid primary key references nomenclature (id) on delete cascade,
+    mtr_id          uuid             not null references nomenclature (id) on delete cascade,
+    confidence      double precision not null,
+    method          text             not null,
+    created_at      timestamptz      not null default now()
+);
+
+create index if not exists mtr_link_mtr_idx on mtr_link (mtr_id);
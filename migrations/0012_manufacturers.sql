@@ -0,0 +1,25 @@
+-- Manufacturer directory. Aliases are normalized keys: lowercase, legal
+-- forms dropped, Cyrillic transliterated, so "ООО Северсталь",
+-- "Северсталь ПАО" and "SEVERSTAL" share the key "severstal".
+create table if not exists manufacturer (
+    id      uuid primary key default uuid_generate_v4(),
+    name    text not null unique,
+    inn     text,
+    country text
+);
+
+create table if not exists manufacturer_alias (
+    alias           text primary key,
+    manufacturer_id uuid not null references manufacturer (id) on delete cascade
+);
+
+alter table nomenclature add column if not exists manufacturer_id uuid references manufacturer (id) on delete set null;
+create index if not exists nomenclature_manufacturer_idx on nomenclature (manufacturer_id);
+
+-- Manufacturer strings waiting for curation.
+create table if not exists manufacturer_unresolved (
+    key         text primary key,
+    raw_values  text[]      not null,
+    occurrences integer     not null default 0,
+    last_seen   timestamptz not null default now()
+);
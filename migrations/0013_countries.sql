@@ -0,0 +1,113 @@
+-- Country of origin reference based on OKSM (ISO 3166). Codes are the
+-- three digit OKSM codes, names and letter codes are matched on import
+-- together with the aliases below.
+create table if not exists country (
+    code    char(3) primary key,
+    alpha2  char(2) not null unique,
+    alpha3  char(3) not null unique,
+    name_ru text    not null,
+    name_en text
+);
+
+create table if not exists country_alias (
+    alias        text primary key,
+    country_code char(3) not null references country (code) on delete cascade
+);
+
+alter table nomenclature add column if not exists country_code char(3) references country (code) on delete set null;
+create index if not exists nomenclature_country_code_idx on nomenclature (country_code);
+
+insert into country (code, alpha2, alpha3, name_ru, name_en) values
+    ('643', 'RU', 'RUS', 'Россия', 'Russia'),
+    ('112', 'BY', 'BLR', 'Беларусь', 'Belarus'),
+    ('398', 'KZ', 'KAZ', 'Казахстан', 'Kazakhstan'),
+    ('051', 'AM', 'ARM', 'Армения', 'Armenia'),
+    ('417', 'KG', 'KGZ', 'Киргизия', 'Kyrgyzstan'),
+    ('860', 'UZ', 'UZB', 'Узбекистан', 'Uzbekistan'),
+    ('762', 'TJ', 'TJK', 'Таджикистан', 'Tajikistan'),
+    ('795', 'TM', 'TKM', 'Туркменистан', 'Turkmenistan'),
+    ('031', 'AZ', 'AZE', 'Азербайджан', 'Azerbaijan'),
+    ('268', 'GE', 'GEO', 'Грузия', 'Georgia'),
+    ('498', 'MD', 'MDA', 'Молдова', 'Moldova'),
+    ('804', 'UA', 'UKR', 'Украина', 'Ukraine'),
+    ('156', 'CN', 'CHN', 'Китай', 'China'),
+    ('158', 'TW', 'TWN', 'Тайвань (Китай)', 'Taiwan'),
+    ('392', 'JP', 'JPN', 'Япония', 'Japan'),
+    ('410', 'KR', 'KOR', 'Корея, Республика', 'Korea, Republic of'),
+    ('356', 'IN', 'IND', 'Индия', 'India'),
+    ('764', 'TH', 'THA', 'Таиланд', 'Thailand'),
+    ('704', 'VN', 'VNM', 'Вьетнам', 'Viet Nam'),
+    ('458', 'MY', 'MYS', 'Малайзия', 'Malaysia'),
+    ('360', 'ID', 'IDN', 'Индонезия', 'Indonesia'),
+    ('364', 'IR', 'IRN', 'Иран', 'Iran'),
+    ('376', 'IL', 'ISR', 'Израиль', 'Israel'),
+    ('792', 'TR', 'TUR', 'Турция', 'Turkey'),
+    ('276', 'DE', 'DEU', 'Германия', 'Germany'),
+    ('380', 'IT', 'ITA', 'Италия', 'Italy'),
+    ('250', 'FR', 'FRA', 'Франция', 'France'),
+    ('724', 'ES', 'ESP', 'Испания', 'Spain'),
+    ('620', 'PT', 'PRT', 'Португалия', 'Portugal'),
+    ('826', 'GB', 'GBR', 'Соединенное Королевство', 'United Kingdom'),
+    ('372', 'IE', 'IRL', 'Ирландия', 'Ireland'),
+    ('528', 'NL', 'NLD', 'Нидерланды', 'Netherlands'),
+    ('056', 'BE', 'BEL', 'Бельгия', 'Belgium'),
+    ('756', 'CH', 'CHE', 'Швейцария', 'Switzerland'),
+    ('040', 'AT', 'AUT', 'Австрия', 'Austria'),
+    ('752', 'SE', 'SWE', 'Швеция', 'Sweden'),
+    ('578', 'NO', 'NOR', 'Норвегия', 'Norway'),
+    ('246', 'FI', 'FIN', 'Финляндия', 'Finland'),
+    ('208', 'DK', 'DNK', 'Дания', 'Denmark'),
+    ('616', 'PL', 'POL', 'Польша', 'Poland'),
+    ('203', 'CZ', 'CZE', 'Чехия', 'Czechia'),
+    ('703', 'SK', 'SVK', 'Словакия', 'Slovakia'),
+    ('348', 'HU', 'HUN', 'Венгрия', 'Hungary'),
+    ('642', 'RO', 'ROU', 'Румыния', 'Romania'),
+    ('100', 'BG', 'BGR', 'Болгария', 'Bulgaria'),
+    ('688', 'RS', 'SRB', 'Сербия', 'Serbia'),
+    ('840', 'US', 'USA', 'Соединенные Штаты', 'United States'),
+    ('124', 'CA', 'CAN', 'Канада', 'Canada'),
+    ('484', 'MX', 'MEX', 'Мексика', 'Mexico'),
+    ('076', 'BR', 'BRA', 'Бразилия', 'Brazil')
+on conflict (code) do nothing;
+
+-- aliases are stored normalized: lowercase, punctuation replaced by spaces
+insert into country_alias (alias, country_code) values
+    ('рф', '643'),
+    ('российская федерация', '643'),
+    ('russian federation', '643'),
+    ('республика беларусь', '112'),
+    ('белоруссия', '112'),
+    ('республика казахстан', '398'),
+    ('кыргызстан', '417'),
+    ('киргизская республика', '417'),
+    ('кнр', '156'),
+    ('китайская народная республика', '156'),
+    ('тайвань', '158'),
+    ('южная корея', '410'),
+    ('республика корея', '410'),
+    ('корея', '410'),
+    ('south korea', '410'),
+    ('иран исламская республика', '364'),
+    ('türkiye', '792'),
+    ('фрг', '276'),
+    ('великобритания', '826'),
+    ('uk', '826'),
+    ('голландия', '528'),
+    ('чешская республика', '203'),
+    ('czech republic', '203'),
+    ('сша', '840'),
+    ('соединенные штаты америки', '840'),
+    ('united states of america', '840'),
+    ('vietnam', '704')
+on conflict (alias) do nothing;
+
+-- items imported before: the country is kept in the MTR or organizer payload
+update nomenclature n set country_code = c.code
+from country c
+where n.country_code is null
+  and lower(btrim(coalesce(n.payload ->> 'sl_manufacturer_country', n.payload ->> 'manufacturer_country'))) in
+      (lower(c.name_ru), lower(c.name_en), lower(c.alpha2), lower(c.alpha3), c.code);
+update nomenclature n set country_code = a.country_code
+from country_alias a
+where n.country_code is null
+  and lower(btrim(coalesce(n.payload ->> 'sl_manufacturer_country', n.payload ->> 'manufacturer_country'))) = a.alias;
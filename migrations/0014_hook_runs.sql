@@ -0,0 +1,14 @@
+-- One processing run per hook idempotency key (the Idempotency-Key header
+-- or the upload key). Retries of a finished run get the stored response,
+-- failed and abandoned runs are started again.
+create table if not exists hook_run (
+    key         text primary key,
+    upload      uuid        not null references uploads (id) on delete cascade,
+    status      text        not null,
+    attempts    integer     not null default 1,
+    response    jsonb,
+    error       text,
+    error_code  integer,
+    started_at  timestamptz not null default now(),
+    finished_at timestamptz
+);
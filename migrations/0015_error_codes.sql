@@ -0,0 +1,4 @@
+-- Stable error codes (see internal/apperrors) next to the messages, so the
+-- upload report and replayed hook failures carry the same code as the API.
+alter table upload_row_error add column if not exists code text;
+alter table hook_run add column if not exists error_type text;
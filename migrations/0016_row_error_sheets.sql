@@ -0,0 +1,3 @@
+-- Workbooks are imported sheet by sheet, row errors keep the sheet they
+-- came from.
+alter table upload_row_error add column if not exists sheet text;
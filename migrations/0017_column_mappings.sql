@@ -0,0 +1,22 @@
+-- Column mappings for sheets no built-in template fits. A company keeps the
+-- mappings it saved as templates, fields map to header names so a template
+-- still fits when the columns move. An upload is imported with the mapping
+-- the user confirmed for it, fields map to 0-based columns.
+create table if not exists column_template (
+    id         uuid primary key default uuid_generate_v4(),
+    company    uuid        not null references company (id) on delete cascade,
+    name       text        not null,
+    columns    jsonb       not null,
+    created_at timestamptz not null default now(),
+    updated_at timestamptz not null default now(),
+    unique (company, name)
+);
+
+create table if not exists upload_column_mapping (
+    upload     uuid primary key references uploads (id) on delete cascade,
+    sheet      text        not null,
+    data_row   integer     not null,
+    columns    jsonb       not null,
+    template   uuid references column_template (id) on delete set null,
+    updated_at timestamptz not null default now()
+);
@@ -0,0 +1,6 @@
+-- Nomenclature references currency by id looked up by code, a code missing
+-- from the dictionary left prices without a currency that read as rubles.
+-- The currencies suppliers price in are seeded here.
+create unique index if not exists currency_code_key on currency (code);
+
+insert into currency (code) values ('RUB'), ('USD'), ('EUR'), ('CNY') on conflict (code) do nothing;
@@ -0,0 +1,10 @@
+-- Hook runs are serialized per upload: whatever the idempotency key, one
+-- run of an upload runs at a time. Running runs keep a heartbeat, a run
+-- whose heartbeat stopped was abandoned by a stopped process.
+alter table hook_run add column if not exists heartbeat_at timestamptz;
+
+update hook_run h set status = 'failed', error = 'abandoned', error_code = 500, finished_at = now()
+where h.status = 'running'
+  and exists (select 1 from hook_run o where o.upload = h.upload and o.status = 'running' and o.started_at > h.started_at);
+
+create unique index if not exists hook_run_upload_running_idx on hook_run (upload) where status = 'running';
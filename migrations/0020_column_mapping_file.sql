@@ -0,0 +1,4 @@
+-- A column mapping is checked against one file of the upload and applies to
+-- that file only, other files may have a sheet of the same name. Mappings
+-- saved before apply to no file and have to be confirmed again.
+alter table upload_column_mapping add column if not exists file text;
@@ -0,0 +1,11 @@
+-- Rows an upload changed but did not create keep their state from before the
+-- upload, DELETE api/v1/uploads/:id/data restores them. A null row means
+-- the upload created it, so the rollback deletes it.
+create table if not exists upload_pre_image (
+    upload     uuid        not null references uploads (id) on delete cascade,
+    table_name text        not null,
+    row_key    text        not null,
+    row        jsonb,
+    created_at timestamptz not null default now(),
+    primary key (upload, table_name, row_key)
+);
@@ -0,0 +1,9 @@
+-- Category names repeat under different parents, so suggestions keep the id
+-- of the suggested category and are accepted by it. Suggestions whose name
+-- matches one category get its id, the others can only be rejected.
+alter table category_suggestion add column if not exists category_id uuid references category (id) on delete cascade;
+
+update category_suggestion s set category_id = c.id
+from category c
+where s.category_id is null and c.name = s.category_name
+  and (select count(*) from category d where d.name = s.category_name) = 1;
@@ -0,0 +1,29 @@
+-- MTR links point at the organizer reference catalogue (organizer_catalogue)
+-- instead of nomenclature rows. The catalogue is kept in Directus, so
+-- mtr_id holds its id as text without a foreign key, the linker drops links
+-- whose reference is gone.
+alter table mtr_link drop constraint if exists mtr_link_mtr_id_fkey;
+alter table mtr_link alter column mtr_id type text using mtr_id::text;
+
+-- existing links move to the catalogue row with the same 1C reference,
+-- links without one cannot be kept
+update mtr_link l set mtr_id = c.id::text
+from nomenclature n, organizer_catalogue c
+where n.id::text = l.mtr_id and n.link <> '' and c.link = n.link;
+delete from mtr_link l where not exists (select 1 from organizer_catalogue c where c.id::text = l.mtr_id);
+
+create index if not exists organizer_catalogue_name_trgm_idx on organizer_catalogue using gin (name gin_trgm_ops);
+create index if not exists organizer_catalogue_vendor_code_idx on organizer_catalogue (vendor_code);
+create index if not exists organizer_catalogue_code_idx on organizer_catalogue (code);
+create index if not exists organizer_catalogue_sl_amto_idx on organizer_catalogue (sl_amto);
+
+-- 0011 told the sources apart by payload being null, which made organizer
+-- rows supplier items. Items in a price list are supplier items, the
+-- organizer template always writes is_weight to the payload and the MTR
+-- template writes its catalogue fields there.
+update nomenclature n set source = case
+    when exists (select 1 from price_nomenclature p where p.nomenclature_id = n.id) then 'supplier'
+    when n.payload ? 'is_weight' then 'organizer'
+    when n.payload is not null and n.payload <> 'null'::jsonb then 'mtr'
+    else 'supplier'
+end;
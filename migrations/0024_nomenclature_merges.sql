@@ -0,0 +1,13 @@
+-- A merge moves the price list links of the merged row to its master. The
+-- links it moved are kept, so rolling back the upload of either row moves
+-- them back instead of deleting them with the master or leaving them on it.
+create table if not exists nomenclature_merge (
+    id        uuid primary key default uuid_generate_v4(),
+    merged_id uuid        not null references nomenclature (id) on delete cascade,
+    master_id uuid        not null references nomenclature (id) on delete cascade,
+    price_ids text[]      not null default '{}',
+    merged_at timestamptz not null default now()
+);
+
+create index if not exists nomenclature_merge_merged_idx on nomenclature_merge (merged_id);
+create index if not exists nomenclature_merge_master_idx on nomenclature_merge (master_id);